@@ -2,14 +2,17 @@ package main
 
 import (
 	"encoding/json"
+	"flag"
 	"fmt"
 	"log"
 	"os"
 	"os/exec"
 	"runtime"
 	"strings"
-	"time"
 
+	"github.com/simulot/aspiratv/download"
+	"github.com/simulot/aspiratv/metadata/nfo"
+	"github.com/simulot/aspiratv/net/myhttp"
 	"github.com/simulot/aspiratv/providers"
 )
 
@@ -18,8 +21,26 @@ func (a *app) Initialize() {
 
 	// Check ans normalize configuration file
 	a.Config.Check()
+	if a.Config.DetailsTTL > 0 {
+		a.details = providers.NewDetailsCache(a.Config.DetailsTTL)
+	}
+	if a.Config.DedupeContent {
+		idx, err := download.LoadFingerprintIndex(a.Config.FingerprintFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		a.fingerprints = idx
+	}
 
 	// Check ffmpeg presence
+	if len(a.Config.FFMpeg) > 0 {
+		p, err := exec.LookPath(a.Config.FFMpeg)
+		if err != nil {
+			log.Fatalf("Can't find ffmpeg at %q: %s", a.Config.FFMpeg, err)
+		}
+		a.ffmpeg = p
+		return
+	}
 	var cmd *exec.Cmd
 	if runtime.GOOS == "windows" {
 		cmd = exec.Command("where", "ffmpeg")
@@ -28,6 +49,10 @@ func (a *app) Initialize() {
 	}
 	b, err := cmd.Output()
 	if err != nil {
+		if a.Config.rawOnly() {
+			log.Print("Missing ffmpeg on your system, only streams saved without remuxing can be downloaded.")
+			return
+		}
 		log.Fatal("Missing ffmpeg on your system, it's required to download video files.")
 	}
 	a.ffmpeg = strings.Trim(strings.Trim(string(b), "\r\n"), "\n")
@@ -37,27 +62,9 @@ func (a *app) Initialize() {
 }
 
 type ProviderConfig struct {
-	Enabled  bool
-	Settings map[string]string
-}
-
-// Handle Duration as string for JSON configuration
-type textDuration time.Duration
-
-func (t textDuration) MarshalJSON() ([]byte, error) {
-	return []byte(`"` + time.Duration(t).String() + `"`), nil
-}
-
-func (t *textDuration) UnmarshalJSON(b []byte) error {
-	if b[0] == '"' {
-		b = b[1 : len(b)-1]
-	}
-	v, err := time.ParseDuration(string(b))
-	if err != nil {
-		return err
-	}
-	*t = textDuration(v)
-	return nil
+	Enabled   bool
+	Settings  providers.Settings // Quality, time zone, catalog limit and headers of the provider
+	Subfolder string             // Subfolder of the destinations receiving the provider's media, when not empty
 }
 
 // Almost empty configuration for testing purpose
@@ -119,17 +126,117 @@ func (c *config) Check() {
 		c.Destinations[d] = os.ExpandEnv(p)
 	}
 
+	if len(c.SeriesTemplate) > 0 {
+		n, err := nfo.NewFileNamer(c.SeriesTemplate)
+		if err != nil {
+			log.Fatalf("Invalid SeriesTemplate in %q: %s", c.ConfigFile, err)
+		}
+		nfo.DefaultSeriesNamer = n
+	}
+	if len(c.MovieTemplate) > 0 {
+		n, err := nfo.NewFileNamer(c.MovieTemplate)
+		if err != nil {
+			log.Fatalf("Invalid MovieTemplate in %q: %s", c.ConfigFile, err)
+		}
+		nfo.DefaultMovieNamer = n
+	}
+	if c.MaxPathLength != 0 {
+		nfo.MaxPathLength = c.MaxPathLength
+	}
+	if len(c.FileNameRules) > 0 {
+		if err := providers.SetCleanerPreset(c.FileNameRules, c.MaxNameLength, providers.WithTransliteration(c.Transliterate)); err != nil {
+			log.Fatalf("Invalid FileNameRules in %q: %s", c.ConfigFile, err)
+		}
+	} else if c.MaxNameLength > 0 || c.Transliterate {
+		providers.UpdateCleaners(c.MaxNameLength, providers.WithTransliteration(c.Transliterate))
+	}
+
+	if len(c.Proxy) > 0 {
+		u, err := myhttp.ParseProxyURL(c.Proxy)
+		if err != nil {
+			log.Fatalf("Invalid Proxy in %q: %s", c.ConfigFile, err)
+		}
+		myhttp.SetProxy(u)(myhttp.DefaultClient) // Shared by providers and downloads
+		for _, v := range []string{"HTTP_PROXY", "HTTPS_PROXY", "http_proxy", "https_proxy"} {
+			os.Setenv(v, c.Proxy) // For other clients and ffmpeg
+		}
+	}
+
+	if len(c.WatchListFile) > 0 {
+		f, err := os.Open(os.ExpandEnv(c.WatchListFile))
+		if err != nil {
+			log.Fatalf("Can't open WatchListFile: %s", err)
+		}
+		mm, err := providers.LoadMatchRequests(f)
+		f.Close()
+		if err != nil {
+			log.Fatalf("Invalid WatchListFile %q: %s", c.WatchListFile, err)
+		}
+		c.WatchList = append(c.WatchList, mm...)
+	}
+	if len(c.BlockedIDsFile) > 0 {
+		f, err := os.Open(os.ExpandEnv(c.BlockedIDsFile))
+		if err != nil {
+			log.Fatalf("Can't open BlockedIDsFile: %s", err)
+		}
+		ids, err := providers.LoadBlockedIDs(f)
+		f.Close()
+		if err != nil {
+			log.Fatalf("Invalid BlockedIDsFile %q: %s", c.BlockedIDsFile, err)
+		}
+		c.BlockedIDs = append(c.BlockedIDs, ids...)
+	}
+
 	for _, m := range c.WatchList {
+		if len(c.TitleAliases) > 0 {
+			aliases := map[string]string{}
+			for k, v := range c.TitleAliases {
+				aliases[k] = v
+			}
+			for k, v := range m.TitleAliases { // Entry's aliases take precedence
+				aliases[k] = v
+			}
+			m.TitleAliases = aliases
+		}
+		if len(c.BlockedIDs) > 0 {
+			m.BlockedIDs = append(append([]string{}, m.BlockedIDs...), c.BlockedIDs...)
+		}
 		m.Pitch = strings.ToLower(m.Pitch)
 		m.Show = strings.ToLower(m.Show)
 		m.Title = strings.ToLower(m.Title)
 		if _, ok := c.Destinations[m.Destination]; !ok {
 			log.Fatalf("Destination %q is not defined into section Destination of %q", m.Destination, c.ConfigFile)
 		}
+		if err := m.Validate(providerNames()); err != nil {
+			log.Fatalf("Invalid watch list entry for %q in %q: %s", m.Show, c.ConfigFile, err)
+		}
 	}
 
 }
 
+// rawOnly tells if all downloads use the ts container, that doesn't need ffmpeg. DASH streams, that would
+// need it, are skipped with this container, see DownloadShow.
+func (c *config) rawOnly() bool {
+	if flag.Arg(0) == "download" {
+		return strings.ToLower(c.Container) == "ts"
+	}
+	for _, m := range c.WatchList {
+		if strings.ToLower(m.Container) != "ts" {
+			return false
+		}
+	}
+	return len(c.WatchList) > 0
+}
+
+// ProviderConfig returns the configuration given to the provider p
+func (c *config) ProviderConfig(p string) providers.Config {
+	return providers.Config{
+		Debug:     c.Debug,
+		KeepBonus: c.KeepBonus,
+		Settings:  c.Providers[p].Settings,
+	}
+}
+
 func (c *config) IsProviderActive(p string) bool {
 	if pc, ok := c.Providers[p]; ok {
 		return pc.Enabled
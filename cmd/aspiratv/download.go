@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
 	"os"
 	"path/filepath"
@@ -11,6 +13,7 @@ import (
 
 	"github.com/simulot/aspiratv/download"
 	"github.com/simulot/aspiratv/metadata/nfo"
+	"github.com/simulot/aspiratv/playlists/m3u8"
 	"github.com/simulot/aspiratv/providers"
 	"github.com/vbauerster/mpb/v4"
 	"github.com/vbauerster/mpb/v4/decor"
@@ -55,9 +58,11 @@ func (p *progressBar) Update(count int64, size int64) {
 
 var dlID = int32(0)
 
-func (a *app) DownloadShow(ctx context.Context, p providers.Provider, m *providers.Media, pc *mpb.Progress) {
+// DownloadShow downloads the media with its metadata, reporting its progress to r. The error is nil when the media
+// is downloaded, or skipped because it can't be downloaded or its content is already present.
+func (a *app) DownloadShow(ctx context.Context, p providers.Provider, m *providers.Media, pc *mpb.Progress, r providers.ProgressReporter) error {
 	if ctx.Err() != nil {
-		return
+		return ctx.Err()
 	}
 	var itemName string
 	// Collect files beeing downloaded and to be deleted in case of cancellation
@@ -82,24 +87,65 @@ func (a *app) DownloadShow(ctx context.Context, p providers.Provider, m *provide
 
 	}()
 	id := 1000 + atomic.AddInt32(&dlID, 1)
-	itemName = filepath.Base(m.Metadata.GetMediaPath(a.Config.Destinations[m.Match.Destination]))
+	itemName = filepath.Base(m.Metadata.GetMediaPath(a.destination(p, m)))
 
-	err := p.GetMediaDetails(ctx, m) // Side effect: Episode number can be determined at this point.
+	err := a.details.GetMediaDetails(ctx, p, m) // Side effect: Episode number can be determined at this point.
 	url := m.Metadata.GetMediaInfo().URL
+	if errors.Is(err, providers.ErrShowExpired) || errors.Is(err, providers.ErrLiveStream) || errors.Is(err, providers.ErrDRMProtected) {
+		a.engine.Skipped(m)
+	}
+	if errors.Is(err, providers.ErrShowExpired) {
+		if a.Config.Debug {
+			log.Printf("[%s] %s isn't available anymore, skipped.", p.Name(), filepath.Base(m.Metadata.GetMediaPath(a.destination(p, m))))
+		}
+		return nil
+	}
+	if errors.Is(err, providers.ErrLiveStream) {
+		log.Printf("[%s] %s is a live stream, skipped.", p.Name(), filepath.Base(m.Metadata.GetMediaPath(a.destination(p, m))))
+		return nil
+	}
+	if errors.Is(err, providers.ErrDRMProtected) {
+		log.Printf("[%s] %s is DRM protected, skipped.", p.Name(), filepath.Base(m.Metadata.GetMediaPath(a.destination(p, m))))
+		return nil
+	}
 	if err != nil || len(url) == 0 {
-		log.Printf("[%s] Can't get url from %s.", p.Name(), filepath.Base(m.Metadata.GetMediaPath(a.Config.Destinations[m.Match.Destination])))
-		return
+		err = fmt.Errorf("Can't get url: %v", err)
+		r.Done(m, err)
+		log.Printf("[%s] Can't get url from %s.", p.Name(), filepath.Base(m.Metadata.GetMediaPath(a.destination(p, m))))
+		return err
+	}
+	if strings.ToLower(m.Match.Container) == "ts" && m.StreamType == providers.StreamDASH {
+		// Only HLS segments can be saved as they are
+		log.Printf("[%s] %s is a DASH stream that can't be saved with ts container, skipped.", p.Name(), itemName)
+		a.engine.Skipped(m)
+		return nil
+	}
+
+	fingerprint := ""
+	if a.fingerprints != nil && m.StreamType != providers.StreamDASH && m.StreamType != providers.StreamMP4 {
+		fp, err := download.Fingerprint(ctx, url, download.WithGetter(a.getter))
+		if err != nil {
+			log.Printf("[%s] Can't check the content of %s: %s", p.Name(), itemName, err)
+		} else if f, ok := a.fingerprints.Lookup(fp); ok {
+			log.Printf("[%s] %s has the same content as %q, skipped.", p.Name(), itemName, f)
+			a.engine.Skipped(m)
+			if a.state != nil {
+				a.state.MarkSeen(m.ID)
+			}
+			return nil
+		}
+		fingerprint = fp
 	}
 
 	if a.Config.WriteNFO {
-		a.DownloadInfo(ctx, p, a.Config.Destinations[m.Match.Destination], m, pc, id, &files)
+		a.DownloadInfo(ctx, p, a.destination(p, m), m, pc, id, &files)
 		if ctx.Err() != nil {
-			return
+			return ctx.Err()
 		}
 	}
 
 	var pgr *progressBar
-	fn := m.Metadata.GetMediaPath(a.Config.Destinations[m.Match.Destination])
+	fn := m.Metadata.GetMediaPath(a.destination(p, m))
 	itemName = filepath.Base(fn)
 
 	if a.Config.Headless || a.Config.Debug {
@@ -120,24 +166,7 @@ func (a *app) DownloadShow(ctx context.Context, p providers.Provider, m *provide
 	err = os.MkdirAll(filepath.Dir(fn), 0777)
 	if err != nil {
 		log.Println(err)
-		return
-	}
-
-	info := m.Metadata.GetMediaInfo()
-
-	params := []string{
-		"-loglevel", "info", // Give me feedback
-		"-hide_banner", // I don't want banner
-		"-i", url,      // Where is the stream
-		"-metadata", "title=" + info.Title, // Force title
-		"-metadata", "comment=" + info.Plot, // Force comment
-		"-metadata", "show=" + info.Showtitle, //Force show
-		"-metadata", "channel=" + info.Studio, // Force channel
-		"-y",              // Override output file
-		"-vcodec", "copy", // copy video
-		"-acodec", "copy", // copy audio
-		"-bsf:a", "aac_adtstoasc", // I don't know
-		fn, // output file
+		return err
 	}
 
 	if a.Config.Debug {
@@ -145,27 +174,138 @@ func (a *app) DownloadShow(ctx context.Context, p providers.Provider, m *provide
 	}
 
 	files = append(files, fn)
-	err = download.FFMepg(ctx, url, params, download.FFMepgWithProgress(pgr), download.FFMepgWithDebug(a.Config.Debug))
+	r = providers.MultiReporter(a.reporter, r)
+	embedded, err := a.downloadStream(ctx, p, m, url, fn, pgr, r)
+	if err != nil && ctx.Err() == nil && a.details != nil {
+		// The stream url may have expired since it was resolved
+		log.Printf("[%s] Can't download %q, trying again with a new stream url: %s", p.Name(), filepath.Base(fn), err)
+		err = a.details.Refresh(ctx, p, m)
+		if err == nil {
+			embedded, err = a.downloadStream(ctx, p, m, m.Metadata.GetMediaInfo().URL, fn, pgr, r)
+		}
+	}
 
-	if err != nil || ctx.Err() != nil {
-		log.Printf("[%s] FFMEPG exits with error:\n%s", p.Name(), err)
-		return
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		log.Printf("[%s] Can't download %q:\n%s", p.Name(), filepath.Base(fn), err)
+		return err
 	}
 
-	if ctx.Err() != nil {
-		return
+	if len(m.Subtitles) > 0 && !embedded {
+		err = providers.DownloadSubtitles(ctx, a.getter, m, fn)
+		if err != nil {
+			log.Printf("[%s] %s", p.Name(), err)
+		}
 	}
 
+	if a.state != nil {
+		a.state.MarkSeen(m.ID)
+	}
+	if len(fingerprint) > 0 {
+		a.fingerprints.Add(fingerprint, fn)
+		if err := a.fingerprints.Save(a.Config.FingerprintFile); err != nil {
+			log.Printf("[%s] %s", p.Name(), err)
+		}
+	}
 	if a.Config.Headless || a.Config.Debug {
 		log.Printf("[%s] %q downloaded.", p.Name(), filepath.Base(fn))
 	}
-	return
+	if len(a.Config.PostDownload) > 0 {
+		err = providers.RunHook(ctx, a.Config.PostDownload, m, fn)
+		if err != nil {
+			log.Printf("[%s] %s", p.Name(), err)
+		}
+	}
+	return nil
+}
+
+// downloadStream downloads the stream into fn, reporting its progress to r. embedded is true when subtitles are muxed into the file.
+func (a *app) downloadStream(ctx context.Context, p providers.Provider, m *providers.Media, url string, fn string, pgr *progressBar, r providers.ProgressReporter) (embedded bool, err error) {
+	info := m.Metadata.GetMediaInfo()
+	if strings.ToLower(m.Match.Container) == "ts" {
+		// HLS segments are concatenated as they are, without ffmpeg
+		err = download.HLS(ctx, url, fn,
+			download.WithGetter(a.getter),
+			download.WithReporter(r, m),
+			download.WithDebug(a.Config.Debug),
+			download.WithWorkers(a.Config.SegmentWorkers),
+			download.WithTimeout(a.Config.DownloadTimeout.Duration()),
+			download.WithSegmentTimeout(a.Config.SegmentTimeout.Duration(), a.Config.SegmentRetries),
+			download.WithBandwidthLimiter(a.bandwidth),
+			download.WithGracefulStop(10*time.Second),
+		)
+	} else if m.StreamType == providers.StreamMP4 && strings.ToLower(filepath.Ext(fn)) == ".mp4" {
+		// The file is downloaded as it is, without ffmpeg
+		err = download.HTTP(ctx, url, fn,
+			download.WithGetter(a.getter),
+			download.WithReporter(r, m),
+			download.WithDebug(a.Config.Debug),
+			download.WithTimeout(a.Config.DownloadTimeout.Duration()),
+			download.WithBandwidthLimiter(a.bandwidth),
+		)
+	} else {
+		meta := download.Metadata{
+			Title:   info.Title,
+			Show:    info.Showtitle,
+			Comment: info.Plot,
+			Channel: info.Studio,
+			Season:  info.Season,
+			Episode: info.Episode,
+		}
+		if a.Config.Chapters {
+			meta.Chapters = m.Chapters
+		}
+		mux := download.MuxHLS
+		switch m.StreamType {
+		case providers.StreamDASH:
+			mux = download.MuxDASH
+		case providers.StreamMP4:
+			mux = download.MuxFile
+		}
+		if m.Match.EmbedSubtitles && len(m.Subtitles) > 0 && download.CanEmbedSubtitles(fn) {
+			if m.IsHLS() {
+				var variant string
+				var renditions []providers.AudioVersion
+				variant, renditions, err = bestVariant(ctx, a.getter, url, m.Match.Quality)
+				if err == nil {
+					url = variant
+					// The variant may have no audio, it's given by its renditions
+					meta.Audio = providers.SelectAudio(renditions, m.Match.AudioLanguage, false)
+				}
+			}
+			if err == nil {
+				meta.Subtitles = m.Subtitles
+				embedded = true
+			} else if a.Config.Debug {
+				log.Printf("[%s] Subtitles won't be embedded: %s", p.Name(), err)
+			}
+		}
+		if audio := providers.SelectAudio(m.AudioVersions, m.Match.AudioLanguage, m.Match.AudioDescription); len(audio) > 0 && m.IsHLS() {
+			// Audio tracks are given with the video of a variant
+			variant, _, err := bestVariant(ctx, a.getter, url, m.Match.Quality)
+			if err == nil {
+				url = variant
+				meta.Audio = audio
+			} else if a.Config.Debug {
+				log.Printf("[%s] The stream's audio is kept: %s", p.Name(), err)
+			}
+		}
+		err = mux(ctx, url, fn, meta,
+			download.FFMepgWithProgress(pgr),
+			download.FFMepgWithReporter(r, m),
+			download.FFMepgWithDebug(a.Config.Debug),
+			download.FFMepgWithBinary(a.ffmpeg),
+		)
+	}
+	return embedded, err
 }
 
 func (a *app) DownloadInfo(ctx context.Context, p providers.Provider, destination string, m *providers.Media, pc *mpb.Progress, id int32, downloadedFiles *[]string) {
 
 	var metaBar *mpb.Bar
-	itemName := filepath.Base(m.Metadata.GetMediaPath(a.Config.Destinations[m.Match.Destination]))
+	itemName := filepath.Base(m.Metadata.GetMediaPath(a.destination(p, m)))
 
 	defer func() {
 		if metaBar != nil {
@@ -187,7 +327,7 @@ func (a *app) DownloadInfo(ctx context.Context, p providers.Provider, destinatio
 	}
 
 	info := m.Metadata.GetMediaInfo()
-	nfoPath := m.Metadata.GetNFOPath(a.Config.Destinations[m.Match.Destination])
+	nfoPath := m.Metadata.GetNFOPath(a.destination(p, m))
 	nfoExists, err := fileExists(nfoPath)
 	if !nfoExists && err == nil {
 		err = m.Metadata.WriteNFO(nfoPath)
@@ -195,11 +335,14 @@ func (a *app) DownloadInfo(ctx context.Context, p providers.Provider, destinatio
 			log.Println(err)
 		}
 		*downloadedFiles = append(*downloadedFiles, nfoPath)
-		a.DowloadImages(ctx, p, nfoPath, info.Thumb, downloadedFiles)
+		err = providers.DownloadThumbnail(ctx, a.getter, m, m.Metadata.GetMediaPath(destination))
+		if err != nil {
+			log.Printf("[%s] %s", p.Name(), err)
+		}
 	}
 	if m.ShowType == providers.Series {
 		if info.SeasonInfo != nil {
-			nfoPath = m.Metadata.GetSeasonNFOPath(a.Config.Destinations[m.Match.Destination])
+			nfoPath = m.Metadata.GetSeasonNFOPath(a.destination(p, m))
 			nfoExists, err = fileExists(nfoPath)
 			if !nfoExists && err == nil {
 				info.SeasonInfo.WriteNFO(nfoPath)
@@ -211,16 +354,16 @@ func (a *app) DownloadInfo(ctx context.Context, p providers.Provider, destinatio
 				a.DowloadImages(ctx, p, nfoPath, info.SeasonInfo.Thumb, downloadedFiles)
 			}
 		}
-		if info.TVShow != nil {
-			nfoPath = m.Metadata.GetShowNFOPath(a.Config.Destinations[m.Match.Destination])
-			nfoExists, err = fileExists(nfoPath)
-			if !nfoExists && err == nil {
-				info.TVShow.WriteNFO(nfoPath)
-				if err != nil {
-					log.Println(err)
-				}
-				*downloadedFiles = append(*downloadedFiles, nfoPath)
+		nfoPath = m.Metadata.GetShowNFOPath(a.destination(p, m))
+		nfoExists, err = fileExists(nfoPath)
+		if !nfoExists && err == nil {
+			err = providers.WriteSeriesNFO(m, filepath.Dir(nfoPath), false)
+			if err != nil {
+				log.Println(err)
+			}
+			*downloadedFiles = append(*downloadedFiles, nfoPath)
 
+			if info.TVShow != nil {
 				a.DowloadImages(ctx, p, nfoPath, info.TVShow.Thumb, downloadedFiles)
 			}
 		}
@@ -249,12 +392,8 @@ func (a *app) DowloadImages(ctx context.Context, p providers.Provider, destinati
 		switch nfoFile {
 		case "tvshow.nfo", "season.nfo":
 			base = thumb.Aspect + ".png"
-		default: // For episodes
-			if thumb.Aspect == "thumb" {
-				base = strings.TrimSuffix(nfoFile, filepath.Ext(nfoFile)) + ".png"
-			} else {
-				continue
-			}
+		default: // Episodes' thumbnails are handled by providers.DownloadThumbnail
+			continue
 		}
 		thumbName := filepath.Join(destination, base)
 		if thumbExists, _ := fileExists(thumbName); thumbExists {
@@ -271,3 +410,34 @@ func (a *app) DowloadImages(ctx context.Context, p providers.Provider, destinati
 		}
 	}
 }
+
+// bestVariant returns the url of the variant of the requested quality when u is a HLS master playlist, u otherwise.
+// The best variant is selected when quality is empty, as the stream's player does.
+// The audio renditions of the variant are given when they have their own playlist.
+func bestVariant(ctx context.Context, g m3u8.Getter, u string, quality string) (string, []providers.AudioVersion, error) {
+	master, err := m3u8.NewMaster(ctx, u, g)
+	if err != nil {
+		return u, nil, fmt.Errorf("Can't get master playlist: %w", err)
+	}
+	if !master.IsMaster() {
+		return u, nil, nil
+	}
+	if len(quality) == 0 {
+		quality = "best"
+	}
+	variant, err := master.Quality(quality)
+	if err != nil {
+		return u, nil, err
+	}
+	audio := []providers.AudioVersion{}
+	for _, r := range master.AudioRenditions(variant) {
+		audio = append(audio, providers.AudioVersion{
+			Language:         r.Language,
+			Name:             r.Name,
+			Default:          r.Default,
+			AudioDescription: r.IsAudioDescription(),
+			URL:              r.URL,
+		})
+	}
+	return variant, audio, nil
+}
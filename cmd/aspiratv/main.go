@@ -9,22 +9,23 @@ import (
 	"io/ioutil"
 	"log"
 	"os"
-	"os/signal"
 	"path/filepath"
 	"runtime"
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
+	"github.com/simulot/aspiratv/download"
 	"github.com/simulot/aspiratv/net/myhttp"
 	"github.com/simulot/aspiratv/providers"
-	"github.com/simulot/aspiratv/workers"
 	"github.com/vbauerster/mpb/v4"
 	"github.com/vbauerster/mpb/v4/decor"
 
 	_ "github.com/simulot/aspiratv/providers/artetv"
 	_ "github.com/simulot/aspiratv/providers/francetv"
 	_ "github.com/simulot/aspiratv/providers/gulli"
+	_ "github.com/simulot/aspiratv/providers/sixplay"
 )
 
 var (
@@ -37,28 +38,73 @@ var (
 type config struct {
 	Providers       map[string]ProviderConfig // Registered providers
 	Force           bool                      // True to force reload medias
+	OnlyNewer       bool                      // Episodes older than the latest one in the library aren't downloaded
 	Destinations    map[string]string         // Mapping of destination path
 	ConfigFile      string                    // Name of configuration file
 	WatchList       []*providers.MatchRequest // Slice of show matchers
+	WatchListFile   string                    // JSON file of watch list entries added to WatchList
+	BlockedIDs      []string                  // IDs of media never downloaded, added to each watch list entry
+	BlockedIDsFile  string                    // File of blocked IDs, one per line, added to BlockedIDs
 	Headless        bool                      // When true, no progression bar
+	Progress        bool                      // When true, progress lines are printed instead of progression bars
 	ConcurrentTasks int                       // Number of concurrent downloads
+	SegmentWorkers  int                       // Segments downloaded at a time by downloads saved without ffmpeg, 1 when zero
+	DownloadTimeout providers.TextDuration    // Deadline of each download saved without ffmpeg when not zero
+	SegmentTimeout  providers.TextDuration    // Deadline of each segment download saved without ffmpeg when not zero
+	SegmentRetries  int                       // Number of retries of a timed out segment
+	BandwidthLimit  int64                     // Maximum speed in bytes per second of all downloads saved without ffmpeg, unlimited when zero
 	Provider        string                    // Provider for dowload command
 	Destination     string                    // Destination folder for dowload command
 	LogFile         string                    // Log file
 	WriteNFO        bool                      // True when NFO files to be written
 	MaxAgedDays     int                       // Retrieve media younger than MaxAgedDays when non zero
 	RetentionDays   int                       // Delete media from  series older than retention days.
+	Quality         string                    // Preferred stream quality for dowload command
 	KeepBonus       bool                      // True to keep bonus
+	DryRun          bool                      // List media to be downloaded without downloading them
+	SeriesTemplate  string                    // File name template for series, Plex layout when empty
+	MovieTemplate   string                    // File name template for movies, Plex layout when empty
+	FileNameRules   string                    // Sanitization rules of file names: default or windows
+	MaxNameLength   int                       // Maximum length of file and directory names when not zero
+	Transliterate   bool                      // File and directory names are spelled with ASCII characters
+	MaxPathLength   int                       // Maximum length of media paths, see nfo.MaxPathLength. Negative for no limit
+	FFMpeg          string                    // Path of ffmpeg binary, searched into the PATH when empty
+	Container       string                    // Container for dowload command: mp4, mkv or ts
+	EmbedSubtitles  bool                      // Subtitles muxed into the video file for download command
+	Proxy           string                    // URL of the proxy used for all requests, HTTP_PROXY and HTTPS_PROXY when empty
+	DetailsTTL      time.Duration             // How long resolved stream urls are kept, not kept when zero
+	SinceLastRun    bool                      // Skip media seen during previous runs
+	StartAfterID    string                    // Skip the listed media up to this media ID, to resume a scan
+	StateFile       string                    // Name of the file where seen media are recorded
+	DedupeContent   bool                      // Skip media whose content has already been downloaded under another ID
+	Chapters        bool                      // Write the chapters given by providers into the media files
+	ProviderFolders bool                      // Media go into the preferred subfolder of their provider, see providers.Subfolder
+	FingerprintFile string                    // Name of the file where fingerprints of downloaded streams are recorded
+	PostDownload    []string                  // Command and its arguments run after each download
+	TitleAliases    map[string]string         // Show titles replaced by a canonical title for all watch list entries
+	ExplainMatch    bool                      // Log why media are rejected by the watch list
 	Debug           bool                      // Verbose Log output
 }
 
 type app struct {
-	Config config
-	Stop   chan bool
-	ffmpeg string
-	pb     *mpb.Progress // Progress bars
-	worker *workers.WorkerPool
-	getter getter
+	Config       config
+	Stop         chan bool
+	ffmpeg       string
+	pb           *mpb.Progress // Progress bars
+	getter       getter
+	state        *providers.ScanState       // Media seen during previous runs, nil when not used
+	details      *providers.DetailsCache    // Resolved stream details, nil when not kept
+	fingerprints *download.FingerprintIndex // Fingerprints of downloaded streams, nil when content isn't deduplicated
+	stats        *download.Stats            // Counters of the run
+
+	reporter providers.ProgressReporter // Progress of the downloads, nil when not used
+
+	engine    *download.Engine           // Runs the downloads, see startEngine
+	jobs      sync.Map                   // Functions called with the outcome of each submitted job, by job
+	jobsDone  chan struct{}              // Closed once the outcome of all jobs is known
+	bandwidth *download.BandwidthLimiter // Download speed limit shared by the downloads saved without ffmpeg
+
+	scanErrors int32 // Number of providers that failed to list their media
 }
 
 type getter interface {
@@ -66,6 +112,13 @@ type getter interface {
 }
 
 func main() {
+	exitCode := 0
+	defer func() {
+		// Registered first to run after all other deferred calls
+		if exitCode != 0 {
+			os.Exit(exitCode)
+		}
+	}()
 
 	fmt.Printf("%s: %v, commit %v, built at %v\n", filepath.Base(os.Args[0]), version, commit, date)
 	a := &app{
@@ -73,40 +126,52 @@ func main() {
 	}
 
 	// trap Ctrl+C and call cancel on the context
-	ctx, cancel := context.WithCancel(context.Background())
-	breakChannel := make(chan os.Signal, 1)
-	signal.Notify(breakChannel, os.Interrupt)
-
-	defer func() {
-		// Normal end... cleaning up
-		signal.Stop(breakChannel)
-		cancel()
-	}()
-
-	// waiting for interruption
-	go func() {
-		select {
-		case <-breakChannel:
-			cancel()
-		case <-ctx.Done():
-			return
-		}
-	}()
+	ctx, stop := download.CancelOnInterrupt(context.Background())
+	defer stop()
 
 	flag.BoolVar(&a.Config.Debug, "debug", false, "Debug mode.")
+	flag.BoolVar(&a.Config.ExplainMatch, "explain-match", false, "Log the criterion of the watch list rejecting each media.")
 	flag.BoolVar(&a.Config.Force, "force", false, "Force media download.")
+	flag.BoolVar(&a.Config.OnlyNewer, "only-newer", false, "Download only episodes newer than the latest one of the show in the library.")
 	flag.BoolVar(&a.Config.Headless, "headless", false, "Headless mode. Progression bars are not displayed.")
 	flag.StringVar(&a.Config.ConfigFile, "config", "config.json", "Configuration file name.")
 	flag.IntVar(&a.Config.ConcurrentTasks, "max-tasks", runtime.NumCPU(), "Maximum concurrent downloads at a time.")
-	flag.StringVar(&a.Config.Provider, "provider", "", "Provider to be used with download command. Possible values : artetv,francetv,gulli")
+	flag.IntVar(&a.Config.SegmentWorkers, "segment-workers", 1, "Number of segments downloaded at a time for each download with ts container.")
+	flag.DurationVar((*time.Duration)(&a.Config.DownloadTimeout), "download-timeout", 0, "Deadline of each download saved without ffmpeg, like 2h. 0 for no deadline.")
+	flag.DurationVar((*time.Duration)(&a.Config.SegmentTimeout), "segment-timeout", 0, "Deadline of each segment download with ts container, like 30s. 0 for no deadline.")
+	flag.IntVar(&a.Config.SegmentRetries, "segment-retries", 3, "Number of retries of a segment download exceeding -segment-timeout.")
+	flag.Int64Var(&a.Config.BandwidthLimit, "bandwidth-limit", 0, "Maximum download speed in bytes per second, shared by all downloads saved without ffmpeg. 0 for no limit.")
+	flag.StringVar(&a.Config.Provider, "provider", "", "Provider to be used with download command. Possible values : "+strings.Join(providerNames(), ","))
 	flag.StringVar(&a.Config.Destination, "destination", "", "Provider to be used with download command. Possible values : artetv,francetv,gulli")
 	flag.StringVar(&a.Config.LogFile, "log", "", "Give the log file name. When empty, no log.")
 	// flag.IntVar(&a.Config.RetentionDays, "retention", 0, "Delete media older than retention days for the downloaded show.")
 	flag.BoolVar(&a.Config.WriteNFO, "write-nfo", true, "Write NFO file for KODI,Emby,Plex...")
 	flag.BoolVar(&a.Config.KeepBonus, "keep-bonuses", true, "Download bonuses when true")
 	flag.IntVar(&a.Config.MaxAgedDays, "max-aged", 0, "Retrieve media younger than MaxAgedDays.")
+	flag.StringVar(&a.Config.Quality, "quality", "", "Preferred stream quality for download command. Possible values: best, worst, 720p...")
+	flag.StringVar(&a.Config.Container, "container", "mp4", "Container of downloaded files for download command. Possible values: mp4, mkv, ts (segments saved without ffmpeg)")
+	flag.BoolVar(&a.Config.EmbedSubtitles, "embed-subtitles", false, "Subtitles are muxed into the video file for download command, instead of .srt files. Not possible with ts container.")
+	flag.StringVar(&a.Config.Proxy, "proxy", "", "URL of the proxy used for all requests, like http://proxy.example.com:3128. HTTP_PROXY and HTTPS_PROXY environment variables are used when empty.")
+	flag.DurationVar(&a.Config.DetailsTTL, "details-ttl", 30*time.Minute, "How long resolved stream urls are kept during the run. 0 to resolve them each time.")
+	flag.BoolVar(&a.Config.DryRun, "dry-run", false, "List media that would be downloaded, without downloading them. Implies -headless.")
+	flag.BoolVar(&a.Config.SinceLastRun, "since-last-run", false, "Skip media seen during previous runs.")
+	flag.StringVar(&a.Config.StartAfterID, "start-after", "", "Skip the media listed up to this media ID, to resume an interrupted scan. With -since-last-run, interrupted scans are resumed automatically.")
+	flag.StringVar(&a.Config.StateFile, "state", "aspiratv-state.json", "File name where seen media are recorded for -since-last-run.")
+	flag.BoolVar(&a.Config.ProviderFolders, "provider-folders", false, "Put the media of each provider into its own subfolder of the destinations, like FranceTV.")
+	flag.BoolVar(&a.Config.Chapters, "chapters", false, "Write the chapters given by the provider, like the opening and the credits, into the media files.")
+	flag.BoolVar(&a.Config.DedupeContent, "dedupe-content", false, "Skip media whose stream has already been downloaded, like re-broadcasts under another ID. Costs an extra request per media.")
+	flag.StringVar(&a.Config.FingerprintFile, "fingerprints", "aspiratv-fingerprints.json", "File name where fingerprints of downloaded streams are recorded for -dedupe-content.")
+	flag.BoolVar(&a.Config.Progress, "progress", false, "Print progress lines for each download and for the whole batch. Implies -headless.")
 	flag.Parse()
 
+	if a.Config.DryRun {
+		a.Config.Headless = true
+	}
+	if a.Config.Progress {
+		a.Config.Headless = true
+		a.reporter = providers.NewStdoutReporter()
+	}
+
 	if a.Config.Debug {
 		fmt.Print("PID: ", os.Getpid(), ", press enter to continue")
 		var input string
@@ -133,6 +198,9 @@ func main() {
 	}
 
 	a.Initialize()
+	if a.Config.ExplainMatch {
+		providers.SetMatchLogger(providers.NewStdLogger("match", true))
+	}
 	if len(os.Args) < 1 {
 		flag.Usage()
 		os.Exit(1)
@@ -141,9 +209,19 @@ func main() {
 	switch flag.Arg(0) {
 	case "download":
 		a.Download(ctx)
+	case "doctor":
+		if !a.Doctor(ctx) {
+			exitCode = 1
+		}
+	case "export":
+		a.Export(ctx)
 	default:
 		a.Run(ctx)
 	}
+	if atomic.LoadInt32(&a.scanErrors) > 0 {
+		log.Println("Some catalogs can't be scanned")
+		exitCode = 1
+	}
 }
 
 func (a *app) CheckPaths() {
@@ -193,7 +271,7 @@ func (a *app) Download(ctx context.Context) {
 	a.CheckPaths()
 	a.Config.WatchList = []*providers.MatchRequest{}
 
-	for dl := 1; dl <= flag.NArg(); dl++ {
+	for dl := 1; dl < flag.NArg(); dl++ {
 		a.Config.WatchList = append(a.Config.WatchList,
 			&providers.MatchRequest{
 				Destination:   "DL",
@@ -201,10 +279,13 @@ func (a *app) Download(ctx context.Context) {
 				Provider:      a.Config.Provider,
 				MaxAgedDays:   a.Config.MaxAgedDays,
 				RetentionDays: a.Config.RetentionDays,
+				Quality:       a.Config.Quality,
+				Container:     a.Config.Container,
+
+				EmbedSubtitles: a.Config.EmbedSubtitles,
 			},
 		)
 	}
-	a.worker = workers.New(ctx, a.Config.ConcurrentTasks, a.Config.Debug)
 	a.getter = myhttp.DefaultClient
 
 	if a.Config.Provider == "" {
@@ -212,22 +293,70 @@ func (a *app) Download(ctx context.Context) {
 		os.Exit(1)
 	}
 
-	p, ok := providers.List()[a.Config.Provider]
+	p, ok := providers.Get(a.Config.Provider)
 	if !ok {
 		log.Printf("Unknown provider %q", a.Config.Provider)
 		os.Exit(1)
 	}
-	p.Configure(providers.Config{
-		Debug:     a.Config.Debug,
-		KeepBonus: a.Config.KeepBonus,
-	})
+	for _, m := range a.Config.WatchList {
+		if err := m.Validate(providerNames()); err != nil {
+			log.Printf("Invalid request %q: %s", m.Show, err)
+			os.Exit(1)
+		}
+	}
+	p.Configure(a.Config.ProviderConfig(p.Name()))
+
+	if a.Config.DryRun {
+		a.PlanShows(ctx, p)
+		return
+	}
 
+	a.startEngine(ctx)
 	pc := a.getProgres(ctx)
 
 	a.PullShows(ctx, p, pc)
 	if !a.Config.Headless {
 		pc.Wait()
 	}
+	a.stopEngine()
+	a.logStats()
+}
+
+// startEngine starts the engine running at most ConcurrentTasks downloads at once, the media expiring first
+// being downloaded first. Its statistics are the run's ones. The BandwidthLimit is shared by all downloads.
+func (a *app) startEngine(ctx context.Context) {
+	concurrency := a.Config.ConcurrentTasks
+	if concurrency < 1 {
+		concurrency = runtime.NumCPU()
+	}
+	a.engine = download.NewEngine(ctx, concurrency)
+	a.stats = a.engine.Stats()
+	a.bandwidth = download.NewBandwidthLimiter(a.Config.BandwidthLimit)
+	a.jobsDone = make(chan struct{})
+	go func() {
+		defer close(a.jobsDone)
+		for r := range a.engine.Results() {
+			if done, ok := a.jobs.Load(r.Job); ok {
+				a.jobs.Delete(r.Job)
+				done.(func(error))(r.Err)
+			}
+		}
+	}()
+}
+
+// stopEngine waits for the end of the submitted downloads
+func (a *app) stopEngine() {
+	a.engine.Close()
+	<-a.jobsDone
+}
+
+// logStats logs the counters of the run by provider
+func (a *app) logStats() {
+	b := strings.Builder{}
+	a.stats.WriteSummary(&b)
+	for _, l := range strings.Split(strings.TrimSpace(b.String()), "\n") {
+		log.Print(l)
+	}
 }
 
 func (a *app) getProgres(ctx context.Context) *mpb.Progress {
@@ -249,19 +378,27 @@ func (a *app) getProgres(ctx context.Context) *mpb.Progress {
 
 func (a *app) Run(ctx context.Context) {
 	a.CheckPaths()
-	a.worker = workers.New(ctx, a.Config.ConcurrentTasks, a.Config.Debug)
+	a.startEngine(ctx)
 	a.getter = myhttp.DefaultClient
 
+	if a.Config.SinceLastRun {
+		s, err := providers.LoadScanState(a.Config.StateFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		a.state = s
+		if a.Config.Debug {
+			log.Printf("Last run at %s, %d media already seen", s.LastRun, len(s.Seen))
+		}
+	}
+
 	pc := a.getProgres(ctx)
 
 	activeProviders := int64(0)
 	for _, p := range providers.List() {
 		if a.Config.IsProviderActive(p.Name()) {
 			activeProviders++
-			p.Configure(providers.Config{
-				Debug:     a.Config.Debug,
-				KeepBonus: a.Config.KeepBonus,
-			})
+			p.Configure(a.Config.ProviderConfig(p.Name()))
 		}
 	}
 
@@ -281,7 +418,11 @@ providerLoop:
 					if a.Config.Headless {
 						log.Printf("[%s] Pulling shows", p.Name())
 					}
-					a.PullShows(ctx, p, pc)
+					if a.Config.DryRun {
+						a.PlanShows(ctx, p)
+					} else {
+						a.PullShows(ctx, p, pc)
+					}
 					wg.Done()
 					log.Printf("[%s] Pulling completed", p.Name())
 				}(p)
@@ -298,15 +439,88 @@ providerLoop:
 	if a.Config.Debug {
 		log.Println("End of providerLoop")
 	}
-	a.worker.Stop()
+	a.stopEngine()
 	if a.Config.Debug {
-		log.Println("Workers stop confirmed")
+		log.Println("Downloads stop confirmed")
+	}
+	a.logStats()
+	if a.state != nil && !a.Config.DryRun && ctx.Err() == nil {
+		// An interrupted run keeps the state of its last checkpoint
+		a.state.LastRun = time.Now()
+		if err := a.state.Save(a.Config.StateFile); err != nil {
+			log.Println(err)
+		}
 	}
 	if a.Config.Debug {
 		log.Println("End of Run")
 	}
 }
 
+// Doctor checks providers' backends and reports which are reachable. Providers without health check
+// are reported as not checked. It returns false when one of them is down.
+func (a *app) Doctor(ctx context.Context) bool {
+	ok := true
+	for _, p := range providers.List() {
+		p.Configure(a.Config.ProviderConfig(p.Name()))
+		features := strings.Join(p.Capabilities().Features(), ", ")
+		if !p.Capabilities().HealthCheck {
+			fmt.Printf("%-10s not checked (%s)\n", p.Name(), features)
+			continue
+		}
+		ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		err := p.HealthCheck(ctx)
+		cancel()
+		if err != nil {
+			ok = false
+			fmt.Printf("%-10s DOWN: %s\n", p.Name(), err)
+			continue
+		}
+		fmt.Printf("%-10s UP (%s)\n", p.Name(), features)
+	}
+	return ok
+}
+
+// Export writes the media matching the watch list of active providers as a JSON catalog,
+// into the file given as argument, or on the standard output.
+func (a *app) Export(ctx context.Context) {
+	medias := []*providers.Media{}
+	for _, p := range providers.List() {
+		if !a.Config.IsProviderActive(p.Name()) {
+			continue
+		}
+		p.Configure(a.Config.ProviderConfig(p.Name()))
+		plans, err := providers.PlanDownloads(ctx, p, a.Config.WatchList, a.destinations(p), true)
+		if err != nil {
+			a.scanError(p, err)
+		}
+		for _, pl := range plans {
+			medias = append(medias, pl.Media)
+		}
+	}
+
+	w := os.Stdout
+	if flag.NArg() > 1 {
+		f, err := os.Create(flag.Arg(1))
+		if err != nil {
+			log.Fatalf("Can't create catalog file: %s", err)
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := providers.ExportJSON(w, medias); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// providerNames gives the list of registered providers
+func providerNames() []string {
+	names := []string{}
+	for _, p := range providers.List() {
+		names = append(names, p.Name())
+	}
+	return names
+}
+
 type debugger interface {
 	SetDebug(bool)
 }
@@ -348,39 +562,81 @@ func (a *app) PullShows(ctx context.Context, p providers.Provider, pc *mpb.Progr
 	wg := sync.WaitGroup{}
 
 	showCount := int64(0)
-showLoop:
+	cursor := a.Config.StartAfterID
+	if len(cursor) == 0 && a.state != nil {
+		cursor = a.state.Cursor(p.Name())
+	}
+	mediaList, errc := p.MediaList(ctx, a.Config.WatchList)
+	if len(cursor) > 0 {
+		if a.Config.Headless || a.Config.Debug {
+			log.Printf("[%s] Resuming the scan after media %q", p.Name(), cursor)
+		}
+		mediaList = providers.StartAfter(ctx, mediaList, cursor)
+	}
+	if a.state != nil {
+		mediaList = a.state.Filter(ctx, mediaList)
+	}
 
-	for m := range p.MediaList(ctx, a.Config.WatchList) {
+	// The cursor moves past media once they are downloaded or skipped, never past a pending download
+	progress := &providers.ScanProgress{}
+	processed := int64(0)
+	advance := func(seq int) {
+		id, n := progress.Done(seq)
+		if n > 0 {
+			total := atomic.AddInt64(&processed, int64(n))
+			a.checkpoint(ctx, p, id, total-int64(n), total)
+		}
+	}
+showLoop:
+	for m := range mediaList {
+		seq := progress.Add(m.ID)
 		if _, ok := seen[m.ID]; ok {
+			advance(seq)
 			continue
 		}
-		seen[m.ID] = true
 
 		select {
 		case <-ctx.Done():
 			if a.Config.Debug {
-				log.Println("[%s] Context done, received %s", ctx.Err())
+				log.Printf("[%s] Context done, received %s", p.Name(), ctx.Err())
 			}
 			break showLoop
 		default:
 
+			// Requests are matched against the canonical show title
+			m.ApplyMatch()
+			if !providers.MatchAny(m, a.Config.WatchList) {
+				advance(seq)
+				continue
+			}
+			seen[m.ID] = true
+			a.stats.Matched(m)
 			if a.Config.Force || a.MustDownload(ctx, p, m) {
 				if a.Config.Headless {
-					log.Printf("[%s] Download of %q submitted", p.Name(), filepath.Base(m.Metadata.GetMediaPath(a.Config.Destinations[m.Match.Destination])))
+					log.Printf("[%s] Download of %q submitted", p.Name(), filepath.Base(m.Metadata.GetMediaPath(a.destination(p, m))))
 				}
 				showCount++
 				if !a.Config.Headless {
 					providerBar.SetTotal(showCount, false)
 				}
-				a.SubmitDownload(ctx, &wg, p, m, pc, providerBar)
+				a.SubmitDownload(ctx, &wg, p, m, pc, providerBar, func(err error) {
+					if err == nil {
+						advance(seq)
+					}
+				})
 			} else {
+				a.engine.Skipped(m)
+				if a.state != nil {
+					a.state.MarkSeen(m.ID)
+				}
+				advance(seq)
 				if a.Config.Headless {
-					log.Printf("[%s] %s already downloaded.", p.Name(), filepath.Base(m.Metadata.GetMediaPath(a.Config.Destinations[m.Match.Destination])))
+					log.Printf("[%s] %s already downloaded.", p.Name(), filepath.Base(m.Metadata.GetMediaPath(a.destination(p, m))))
 				}
 			}
 			if ctx.Err() != nil {
 				if a.Config.Debug {
-					log.Println("[%s] PullShows received %s", ctx.Err())
+					log.Printf("[%s] PullShows received %s", p.Name(), ctx.Err())
 				}
 				break showLoop
 			}
@@ -389,6 +645,14 @@ showLoop:
 	if !a.Config.Headless {
 		providerBar.SetTotal(showCount, showCount == 0)
 	}
+	complete := false
+	if ctx.Err() == nil {
+		if err := <-errc; err != nil {
+			a.scanError(p, err)
+		} else {
+			complete = true
+		}
+	}
 	if a.Config.Debug {
 		log.Println("Waiting end of PullShows loop")
 	}
@@ -396,6 +660,11 @@ showLoop:
 	// Wait for submitted jobs to be terminated
 	wg.Wait()
 
+	if complete && ctx.Err() == nil && a.state != nil {
+		// The scan is complete, the next one starts from the beginning
+		a.state.ClearCursor(p.Name())
+	}
+
 	if !a.Config.Headless {
 		providerBar.SetTotal(showCount, true)
 	}
@@ -404,20 +673,82 @@ showLoop:
 	}
 }
 
+// checkpointInterval is the number of media processed between two saves of the scan state
+const checkpointInterval = 50
+
+// checkpoint records id as the last media processed by the provider's scan, when the count of processed
+// media goes from before to after, and saves the state regularly, so a crashed scan resumes from there.
+// Nothing is recorded once the context is cancelled.
+func (a *app) checkpoint(ctx context.Context, p providers.Provider, id string, before, after int64) {
+	if a.state == nil || ctx.Err() != nil {
+		return
+	}
+	a.state.SetCursor(p.Name(), id)
+	if before/checkpointInterval == after/checkpointInterval {
+		return
+	}
+	if err := a.state.Save(a.Config.StateFile); err != nil {
+		log.Printf("[%s] %s", p.Name(), err)
+	}
+}
+
+// PlanShows prints media that would be downloaded by PullShows
+func (a *app) PlanShows(ctx context.Context, p providers.Provider) {
+	plans, err := providers.PlanDownloads(ctx, p, a.Config.WatchList, a.destinations(p), true)
+	if err != nil {
+		log.Printf("[%s] %s", p.Name(), err)
+	}
+	for _, pl := range plans {
+		if !a.Config.Force && !a.MustDownload(ctx, p, pl.Media) {
+			continue
+		}
+		fmt.Printf("[%s] %s\n", p.Name(), pl.Path)
+		fmt.Printf("[%s]     %s\n", p.Name(), pl.URL)
+	}
+}
+
+// destination gives the directory receiving the media, within its destination
+func (a *app) destination(p providers.Provider, m *providers.Media) string {
+	return a.destinations(p)[m.Match.Destination]
+}
+
+// destinations gives the destinations of the provider's media, with the provider's subfolder
+func (a *app) destinations(p providers.Provider) map[string]string {
+	subfolder := providers.Subfolder(p, a.Config.Providers[p.Name()].Subfolder, a.Config.ProviderFolders)
+	if len(subfolder) == 0 {
+		return a.Config.Destinations
+	}
+	ds := make(map[string]string, len(a.Config.Destinations))
+	for k, d := range a.Config.Destinations {
+		ds[k] = providers.MediaRoot(d, subfolder)
+	}
+	return ds
+}
+
+// scanError reports an error met while listing provider's media
+func (a *app) scanError(p providers.Provider, err error) {
+	log.Printf("[%s] %s", p.Name(), err)
+	atomic.AddInt32(&a.scanErrors, 1)
+}
+
 // MustDownload check if the show isn't yet downloaded.
 func (a *app) MustDownload(ctx context.Context, p providers.Provider, m *providers.Media) bool {
-	mediaPath := m.Metadata.GetMediaPath(a.Config.Destinations[m.Match.Destination])
-	mediaExists, err := fileExists(mediaPath)
-	if mediaExists {
-		return false
+	downloaded, err := providers.AlreadyDownloaded(m, a.destination(p, m))
+	if err != nil {
+		log.Fatalf("[%s] %s", p.Name(), err)
 	}
-
-	mediaPath = m.Metadata.GetMediaPathMatcher(a.Config.Destinations[m.Match.Destination])
-	files, err := filepath.Glob(mediaPath)
+	if downloaded || !a.Config.OnlyNewer {
+		return !downloaded
+	}
+	newer, err := providers.NewerThanLibrary(m, a.destination(p, m))
 	if err != nil {
-		log.Fatalf("Can't glob %s: %v", mediaPath, err)
+		log.Printf("[%s] %s", p.Name(), err)
+		return true
 	}
-	return len(files) == 0
+	if !newer && a.Config.Debug {
+		log.Printf("[%s] %s is older than the library, skipped.", p.Name(), filepath.Base(m.Metadata.GetMediaPath(a.destination(p, m))))
+	}
+	return newer
 }
 
 func fileExists(p string) (bool, error) {
@@ -431,12 +762,25 @@ func fileExists(p string) (bool, error) {
 	return true, nil
 }
 
-func (a *app) SubmitDownload(ctx context.Context, wg *sync.WaitGroup, p providers.Provider, m *providers.Media, pc *mpb.Progress, bar *mpb.Bar) {
+// SubmitDownload queues the download of the media, done is called with its outcome once it's ended
+func (a *app) SubmitDownload(ctx context.Context, wg *sync.WaitGroup, p providers.Provider, m *providers.Media, pc *mpb.Progress, bar *mpb.Bar, done func(error)) {
 	wg.Add(1)
-	go a.worker.Submit(func() {
-		a.DownloadShow(ctx, p, m, pc)
+	job := &download.Job{
+		Media: m,
+		Run: func(ctx context.Context, r providers.ProgressReporter) error {
+			return a.DownloadShow(ctx, p, m, pc, r)
+		},
+	}
+	a.jobs.Store(job, func(err error) {
+		done(err)
 		if bar != nil {
 			bar.Increment()
 		}
-	}, wg)
+		wg.Done()
+	})
+	if err := a.engine.Submit(job); err != nil {
+		a.jobs.Delete(job)
+		done(err)
+		wg.Done()
+	}
 }
@@ -0,0 +1,89 @@
+package download
+
+import (
+	"context"
+	"io"
+
+	"golang.org/x/time/rate"
+)
+
+// BandwidthLimiter caps the download speed of the readers sharing it
+type BandwidthLimiter struct {
+	limiter *rate.Limiter
+}
+
+// NewBandwidthLimiter create a limiter of bytesPerSec bytes per second. Zero means unlimited.
+func NewBandwidthLimiter(bytesPerSec int64) *BandwidthLimiter {
+	if bytesPerSec <= 0 {
+		return &BandwidthLimiter{limiter: rate.NewLimiter(rate.Inf, 0)}
+	}
+	// Let pass at most a tenth of second of data at once
+	burst := int(bytesPerSec / 10)
+	if burst < 512 {
+		burst = 512
+	}
+	if burst > 64*1024 {
+		burst = 64 * 1024
+	}
+	if int64(burst) > bytesPerSec {
+		burst = int(bytesPerSec)
+	}
+	return &BandwidthLimiter{limiter: rate.NewLimiter(rate.Limit(bytesPerSec), burst)}
+}
+
+// Reader gives a reader that waits for the limiter before returning the data read from r
+func (l *BandwidthLimiter) Reader(ctx context.Context, r io.Reader) io.Reader {
+	return &limitedReader{ctx: ctx, r: r, limiter: l.limiter}
+}
+
+// WithBandwidthLimit caps the download speed to bytesPerSec bytes per second, shared by all segments
+// of the download. Zero means unlimited.
+func WithBandwidthLimit(bytesPerSec int64) configurator {
+	return WithBandwidthLimiter(NewBandwidthLimiter(bytesPerSec))
+}
+
+// WithBandwidthLimiter caps the download speed with a limiter that can be shared between downloads
+func WithBandwidthLimiter(l *BandwidthLimiter) configurator {
+	return func(c *config) {
+		c.bandwidth = l
+	}
+}
+
+type limitedReader struct {
+	ctx     context.Context
+	r       io.Reader
+	limiter *rate.Limiter
+}
+
+func (r *limitedReader) Read(b []byte) (int, error) {
+	if r.limiter.Limit() == rate.Inf {
+		return r.r.Read(b)
+	}
+	if burst := r.limiter.Burst(); len(b) > burst {
+		b = b[:burst]
+	}
+	n, err := r.r.Read(b)
+	if n > 0 {
+		if werr := r.limiter.WaitN(r.ctx, n); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
+
+// limitedGetter applies the bandwidth limiter on the responses of the getter
+type limitedGetter struct {
+	g Getter
+	l *BandwidthLimiter
+}
+
+func (g limitedGetter) Get(ctx context.Context, uri string) (io.ReadCloser, error) {
+	r, err := g.g.Get(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{g.l.Reader(ctx, r), r}, nil
+}
@@ -0,0 +1,79 @@
+package download
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHTTPBandwidthLimit(t *testing.T) {
+	body := bytes.Repeat([]byte("A"), 20000)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(body)
+	}))
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "aspiratv-bandwidth-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name    string
+		limit   int64
+		minTime time.Duration
+	}{
+		{"unlimited", 0, 0},
+		{"40KBps", 40000, 400 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := filepath.Join(dir, tt.name+".mp4")
+			start := time.Now()
+			err := HTTP(context.TODO(), ts.URL, dest, WithBandwidthLimit(tt.limit))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d := time.Since(start); d < tt.minTime {
+				t.Errorf("Expecting download to last at least %s, got %s", tt.minTime, d)
+			}
+			b, _ := ioutil.ReadFile(dest)
+			if !bytes.Equal(b, body) {
+				t.Errorf("Downloaded content differs from expected")
+			}
+		})
+	}
+}
+
+func TestHLSBandwidthShared(t *testing.T) {
+	ts, expected := newHLSServer(30) // 3000 bytes
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "aspiratv-bandwidth-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	dest := filepath.Join(dir, "media.ts")
+
+	// Segments and playlists weight about 3500 bytes, they need at least 500ms at 5000 bytes/s
+	start := time.Now()
+	err = HLS(context.TODO(), ts.URL+"/master.m3u8", dest, WithWorkers(4), WithBandwidthLimit(5000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d < 500*time.Millisecond {
+		t.Errorf("Expecting the limit to be shared by workers, download lasted %s", d)
+	}
+	b, _ := ioutil.ReadFile(dest)
+	if string(b) != expected {
+		t.Errorf("Downloaded content differs from expected")
+	}
+}
@@ -0,0 +1,222 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/simulot/aspiratv/providers"
+)
+
+// ErrEngineClosed is returned when submitting a job to a closed engine
+var ErrEngineClosed = errors.New("download engine is closed")
+
+// Job types
+const (
+	JobHLS  = "hls"  // HLS stream, segments are concatenated
+	JobHTTP = "http" // File served as a whole
+)
+
+// Job is a download run by the Engine
+type Job struct {
+	Media     *providers.Media // Downloaded media, optional
+	Type      string           // JobHLS or JobHTTP, JobHLS when empty
+	URL       string
+	Dest      string         // Downloaded file, see Destination
+	Root      string         // Destination directory of the media, used when Dest is empty
+	Subfolder string         // Subfolder of Root, see providers.Subfolder
+	Options   []configurator // Options of this job, applied after the engine's ones
+
+	// Run is the download done by the caller instead of Type and URL, like a stream muxed by ffmpeg.
+	// It reports the progress of the media to r, that gives it to the engine's statistics and events.
+	Run func(ctx context.Context, r providers.ProgressReporter) error
+}
+
+// Destination gives the downloaded file. It's Dest when set, otherwise the media's path
+// within Root joined with Subfolder: root/subfolder/show/season/episode.mp4
+func (j *Job) Destination() string {
+	if len(j.Dest) > 0 || j.Media == nil || j.Media.Metadata == nil {
+		return j.Dest
+	}
+	return j.Media.Metadata.GetMediaPath(providers.MediaRoot(j.Root, j.Subfolder))
+}
+
+// Result is the outcome of a job
+type Result struct {
+	Job      *Job
+	Err      error
+	Duration time.Duration
+}
+
+// Engine runs the submitted jobs with at most concurrency jobs at once. Waiting jobs are run by ascending
+// expiry of their media, so media about to disappear are downloaded first. Jobs without expiry come last,
+// and jobs with the same expiry run in submission order.
+// Options given to the engine are shared by all jobs: a bandwidth limit set with WithBandwidthLimit
+// is shared by all running downloads, and WithEvents gives the events of all jobs to user interfaces.
+// The outcome and the size of the jobs are counted in Stats, media not downloaded because already
+// present are told with Skipped. Jobs waiting when the context is cancelled are ended with the context's error.
+type Engine struct {
+	ctx     context.Context
+	options []configurator
+	results chan Result
+	stats   *Stats
+
+	mu     sync.Mutex
+	cond   *sync.Cond
+	queue  []*Job
+	closed bool
+}
+
+// NewEngine starts an engine running at most concurrency jobs at once
+func NewEngine(ctx context.Context, concurrency int, options ...configurator) *Engine {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	e := &Engine{
+		ctx:     ctx,
+		options: options,
+		results: make(chan Result),
+		stats:   NewStats(),
+	}
+	e.cond = sync.NewCond(&e.mu)
+
+	wg := sync.WaitGroup{}
+	done := make(chan struct{})
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			e.run()
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(done)
+		close(e.results)
+	}()
+	go func() {
+		// Wake up waiting workers on cancellation
+		select {
+		case <-ctx.Done():
+			e.mu.Lock()
+			e.cond.Broadcast()
+			e.mu.Unlock()
+		case <-done:
+		}
+	}()
+	return e
+}
+
+// Submit queues the job
+func (e *Engine) Submit(job *Job) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.closed {
+		return ErrEngineClosed
+	}
+	if err := e.ctx.Err(); err != nil {
+		return err
+	}
+	i := sort.Search(len(e.queue), func(i int) bool { return expiresBefore(job, e.queue[i]) })
+	e.queue = append(e.queue, nil)
+	copy(e.queue[i+1:], e.queue[i:])
+	e.queue[i] = job
+	e.cond.Signal()
+	return nil
+}
+
+// expiresBefore tells if the media of a expires before the one of b. Unknown expiries are the latest.
+func expiresBefore(a, b *Job) bool {
+	ea, eb := a.expires(), b.expires()
+	if ea.IsZero() {
+		return false
+	}
+	return eb.IsZero() || ea.Before(eb)
+}
+
+func (j *Job) expires() time.Time {
+	if j.Media == nil {
+		return time.Time{}
+	}
+	return j.Media.Expires
+}
+
+// Close tells that no more jobs will be submitted. The results channel is closed
+// when all queued jobs are done.
+func (e *Engine) Close() {
+	e.mu.Lock()
+	e.closed = true
+	e.cond.Broadcast()
+	e.mu.Unlock()
+}
+
+// Stats gives the statistics of the jobs run by the engine
+func (e *Engine) Stats() *Stats {
+	return e.stats
+}
+
+// Results gives the result of each job, as they end. It must be read until it's closed.
+func (e *Engine) Results() <-chan Result {
+	return e.results
+}
+
+func (e *Engine) next() (*Job, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for len(e.queue) == 0 && !e.closed && e.ctx.Err() == nil {
+		e.cond.Wait()
+	}
+	if len(e.queue) == 0 {
+		return nil, false
+	}
+	job := e.queue[0]
+	e.queue = e.queue[1:]
+	return job, true
+}
+
+func (e *Engine) run() {
+	for {
+		job, ok := e.next()
+		if !ok {
+			return
+		}
+		start := time.Now()
+		err := e.ctx.Err()
+		if err == nil {
+			err = e.download(job)
+		}
+		e.results <- Result{Job: job, Err: err, Duration: time.Since(start)}
+	}
+}
+
+// Skipped tells the statistics and the events of the engine that the media isn't downloaded, because it's already present
+func (e *Engine) Skipped(m *providers.Media) {
+	cfg := e.config(m, e.options)
+	if r, ok := cfg.reporter.(providers.SkipReporter); ok {
+		r.Skipped(m)
+	}
+}
+
+// config gives the configuration of a download of the media with the options, counted in the engine's statistics
+func (e *Engine) config(m *providers.Media, options []configurator) config {
+	cfg := config{}
+	for _, c := range options {
+		c(&cfg)
+	}
+	withStats(e.stats, m)(&cfg)
+	return cfg
+}
+
+func (e *Engine) download(job *Job) error {
+	options := append(append([]configurator{}, e.options...), job.Options...)
+	if job.Run != nil {
+		return job.Run(e.ctx, e.config(job.Media, options).reporter)
+	}
+	options = append(options, withStats(e.stats, job.Media))
+	if job.Type == JobHTTP {
+		return HTTP(e.ctx, job.URL, job.Destination(), options...)
+	}
+	return HLS(e.ctx, job.URL, job.Destination(), options...)
+}
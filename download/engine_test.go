@@ -0,0 +1,193 @@
+package download
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/simulot/aspiratv/metadata/nfo"
+	"github.com/simulot/aspiratv/providers"
+)
+
+func TestEngineConcurrency(t *testing.T) {
+	inFlight, maxInFlight := int32(0), int32(0)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			m := atomic.LoadInt32(&maxInFlight)
+			if n <= m || atomic.CompareAndSwapInt32(&maxInFlight, m, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		fmt.Fprint(w, r.URL.Path)
+	}))
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "aspiratv-engine-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	e := NewEngine(context.Background(), 3)
+	const jobs = 10
+	go func() {
+		for i := 0; i < jobs; i++ {
+			err := e.Submit(&Job{Type: JobHTTP, URL: fmt.Sprintf("%s/%d", ts.URL, i), Dest: filepath.Join(dir, fmt.Sprintf("%d.mp4", i))})
+			if err != nil {
+				t.Error(err)
+			}
+		}
+		e.Close()
+	}()
+
+	n := 0
+	for r := range e.Results() {
+		n++
+		if r.Err != nil {
+			t.Errorf("Job %q failed: %s", r.Job.URL, r.Err)
+		}
+	}
+	if n != jobs {
+		t.Errorf("Expecting %d results, got %d", jobs, n)
+	}
+	if maxInFlight > 3 {
+		t.Errorf("Expecting at most 3 downloads at once, got %d", maxInFlight)
+	}
+	if err := e.Submit(&Job{}); err != ErrEngineClosed {
+		t.Errorf("Expecting ErrEngineClosed, got %v", err)
+	}
+}
+
+func TestEngineCancel(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(2 * time.Second):
+		}
+	}))
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "aspiratv-engine-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	e := NewEngine(ctx, 1)
+	for i := 0; i < 3; i++ {
+		if err := e.Submit(&Job{Type: JobHTTP, URL: ts.URL, Dest: filepath.Join(dir, fmt.Sprintf("%d.mp4", i))}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	n := 0
+	for r := range e.Results() {
+		n++
+		if r.Err == nil {
+			t.Errorf("Expecting job %d to be cancelled", n)
+		}
+	}
+	if n != 3 {
+		t.Errorf("Expecting 3 results, got %d", n)
+	}
+}
+
+func TestJobDestination(t *testing.T) {
+	m := &providers.Media{Metadata: &nfo.Movie{MediaInfo: nfo.MediaInfo{Title: "Cyrano"}}}
+	tests := []struct {
+		name string
+		job  Job
+		want string
+	}{
+		{"dest", Job{Media: m, Dest: "out.mp4", Root: "/videos"}, "out.mp4"},
+		{"root", Job{Media: m, Root: "/videos"}, m.Metadata.GetMediaPath("/videos")},
+		{"subfolder", Job{Media: m, Root: "/videos", Subfolder: "FranceTV"}, m.Metadata.GetMediaPath(filepath.Join("/videos", "FranceTV"))},
+		{"no media", Job{Root: "/videos"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.job.Destination(); got != tt.want {
+				t.Errorf("Destination() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEngineExpiryOrder(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	mu := sync.Mutex{}
+	order := []string{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/blocker" {
+			close(started)
+			<-release
+		}
+		mu.Lock()
+		order = append(order, strings.TrimPrefix(r.URL.Path, "/"))
+		mu.Unlock()
+	}))
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "aspiratv-engine-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	day := func(d int) time.Time { return time.Date(2020, 6, d, 0, 0, 0, 0, time.UTC) }
+	job := func(name string, expires time.Time) *Job {
+		return &Job{
+			Type:  JobHTTP,
+			URL:   ts.URL + "/" + name,
+			Dest:  filepath.Join(dir, name+".mp4"),
+			Media: &providers.Media{ID: name, Expires: expires},
+		}
+	}
+
+	e := NewEngine(context.Background(), 1)
+	if err := e.Submit(job("blocker", time.Time{})); err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	for _, j := range []*Job{
+		job("none1", time.Time{}),
+		job("day20", day(20)),
+		job("day5", day(5)),
+		{Type: JobHTTP, URL: ts.URL + "/nomedia", Dest: filepath.Join(dir, "nomedia.mp4")},
+		job("day10a", day(10)),
+		job("day10b", day(10)),
+		job("none2", time.Time{}),
+	} {
+		if err := e.Submit(j); err != nil {
+			t.Fatal(err)
+		}
+	}
+	close(release)
+	e.Close()
+	for r := range e.Results() {
+		if r.Err != nil {
+			t.Errorf("Job %q failed: %s", r.Job.URL, r.Err)
+		}
+	}
+
+	want := []string{"blocker", "day5", "day10a", "day10b", "day20", "none1", "nomedia", "none2"}
+	if !reflect.DeepEqual(order, want) {
+		t.Errorf("Expecting order %v, got %v", want, order)
+	}
+}
@@ -0,0 +1,176 @@
+package download
+
+import (
+	"sync"
+	"time"
+
+	"github.com/simulot/aspiratv/providers"
+)
+
+// EventType tells what happened to a download
+type EventType int
+
+// Event types
+const (
+	EventStarted   EventType = iota // The download starts
+	EventProgress                   // More bytes have been downloaded
+	EventCompleted                  // The download is successful
+	EventFailed                     // The download failed, see Event.Err
+	EventSkipped                    // The media isn't downloaded, because it's already present
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventStarted:
+		return "started"
+	case EventProgress:
+		return "progress"
+	case EventCompleted:
+		return "completed"
+	case EventFailed:
+		return "failed"
+	case EventSkipped:
+		return "skipped"
+	}
+	return "unknown"
+}
+
+// Event is a step of the download of a media
+type Event struct {
+	Type  EventType
+	Media *providers.Media // Downloaded media, nil for jobs without media
+	Bytes int64            // Bytes of the media downloaded so far
+	Err   error            // Cause of EventFailed
+	Time  time.Time
+}
+
+// EventBus is a ProgressReporter delivering download events on a channel, for user interfaces.
+// Downloads are never blocked by a slow consumer: events are queued, and progress events of a media
+// waiting in the queue are merged into the latest one. The channel must be read until it's closed by Close.
+// Its methods can be called concurrently.
+type EventBus struct {
+	c chan Event
+
+	mu       sync.Mutex
+	cond     *sync.Cond
+	queue    []*Event
+	progress map[*providers.Media]*Event // Queued progress event of each media
+	bytes    map[*providers.Media]int64
+	closed   bool
+}
+
+// NewEventBus creates a bus delivering the events on a channel buffered with size events
+func NewEventBus(size int) *EventBus {
+	if size < 0 {
+		size = 0
+	}
+	b := &EventBus{
+		c:        make(chan Event, size),
+		progress: map[*providers.Media]*Event{},
+		bytes:    map[*providers.Media]int64{},
+	}
+	b.cond = sync.NewCond(&b.mu)
+	go b.pump()
+	return b
+}
+
+// Events gives the events, in the order they happened
+func (b *EventBus) Events() <-chan Event {
+	return b.c
+}
+
+// Close ends the bus: the channel is closed once queued events are delivered. Later events are ignored.
+func (b *EventBus) Close() {
+	b.mu.Lock()
+	b.closed = true
+	b.cond.Broadcast()
+	b.mu.Unlock()
+}
+
+// Start implements providers.ProgressReporter
+func (b *EventBus) Start(m *providers.Media) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.bytes[m] = 0
+	delete(b.progress, m)
+	b.push(&Event{Type: EventStarted, Media: m})
+}
+
+// Bytes implements providers.ProgressReporter
+func (b *EventBus) Bytes(m *providers.Media, n int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return
+	}
+	b.bytes[m] += n
+	if e, ok := b.progress[m]; ok {
+		e.Bytes, e.Time = b.bytes[m], time.Now()
+		return
+	}
+	e := &Event{Type: EventProgress, Media: m, Bytes: b.bytes[m]}
+	if b.push(e) {
+		b.progress[m] = e
+	}
+}
+
+// Done implements providers.ProgressReporter
+func (b *EventBus) Done(m *providers.Media, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	e := &Event{Type: EventCompleted, Media: m, Bytes: b.bytes[m], Err: err}
+	if err != nil {
+		e.Type = EventFailed
+	}
+	delete(b.bytes, m)
+	delete(b.progress, m) // Later bytes are for another download
+	b.push(e)
+}
+
+// Skipped tells that the media isn't downloaded because it's already present
+func (b *EventBus) Skipped(m *providers.Media) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.push(&Event{Type: EventSkipped, Media: m})
+}
+
+// push queues the event, it's false when the bus is closed. b.mu must be held.
+func (b *EventBus) push(e *Event) bool {
+	if b.closed {
+		return false
+	}
+	e.Time = time.Now()
+	b.queue = append(b.queue, e)
+	b.cond.Signal()
+	return true
+}
+
+// pump sends the queued events on the channel
+func (b *EventBus) pump() {
+	for {
+		b.mu.Lock()
+		for len(b.queue) == 0 && !b.closed {
+			b.cond.Wait()
+		}
+		if len(b.queue) == 0 {
+			b.mu.Unlock()
+			close(b.c)
+			return
+		}
+		e := b.queue[0]
+		b.queue = b.queue[1:]
+		if b.progress[e.Media] == e {
+			delete(b.progress, e.Media)
+		}
+		ev := *e
+		b.mu.Unlock()
+		b.c <- ev
+	}
+}
+
+// WithEvents delivers the events of the download on the bus. Given to NewEngine, it gives the events of all jobs.
+func WithEvents(b *EventBus) configurator {
+	return func(c *config) {
+		c.reporter = providers.MultiReporter(c.reporter, b)
+	}
+}
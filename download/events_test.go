@@ -0,0 +1,137 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/simulot/aspiratv/providers"
+)
+
+func TestEventBus(t *testing.T) {
+	b := NewEventBus(0)
+	m1, m2 := &providers.Media{ID: "1"}, &providers.Media{ID: "2"}
+
+	// Nobody reads the events yet: progress events are merged
+	b.Start(m1)
+	b.Bytes(m1, 10)
+	b.Bytes(m1, 20)
+	b.Start(m2)
+	b.Bytes(m1, 30)
+	b.Done(m1, nil)
+	b.Done(m2, errors.New("boom"))
+	b.Skipped(m2)
+	b.Close()
+	b.Bytes(m1, 1)
+
+	type ev struct {
+		t     EventType
+		m     *providers.Media
+		bytes int64
+	}
+	want := []ev{
+		{EventStarted, m1, 0},
+		{EventProgress, m1, 60},
+		{EventStarted, m2, 0},
+		{EventCompleted, m1, 60},
+		{EventFailed, m2, 0},
+		{EventSkipped, m2, 0},
+	}
+	got := []ev{}
+	for e := range b.Events() {
+		got = append(got, ev{e.Type, e.Media, e.Bytes})
+		if e.Type == EventFailed && e.Err == nil {
+			t.Errorf("Expecting the failure cause")
+		}
+		if e.Time.IsZero() {
+			t.Errorf("Expecting event time")
+		}
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Expecting %v, got %v", want, got)
+	}
+}
+
+func TestEngineEvents(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, "0123456789")
+	}))
+	defer ts.Close()
+	dir, err := ioutil.TempDir("", "aspiratv-events-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	b := NewEventBus(10)
+	e := NewEngine(context.Background(), 1, WithEvents(b))
+	ok, missing := &providers.Media{ID: "ok"}, &providers.Media{ID: "missing"}
+	e.Submit(&Job{Media: ok, Type: JobHTTP, URL: ts.URL + "/ok", Dest: filepath.Join(dir, "ok.mp4")})
+	e.Submit(&Job{Media: missing, Type: JobHTTP, URL: ts.URL + "/missing", Dest: filepath.Join(dir, "missing.mp4")})
+	e.Close()
+	for range e.Results() {
+	}
+	b.Close()
+
+	last := map[*providers.Media]Event{}
+	started := map[*providers.Media]bool{}
+	for ev := range b.Events() {
+		if ev.Type == EventStarted {
+			started[ev.Media] = true
+		}
+		last[ev.Media] = ev
+	}
+	if !started[ok] || !started[missing] {
+		t.Errorf("Expecting started events, got %v", started)
+	}
+	if ev := last[ok]; ev.Type != EventCompleted || ev.Bytes != 10 {
+		t.Errorf("Expecting completed event with 10 bytes, got %s with %d bytes", ev.Type, ev.Bytes)
+	}
+	if ev := last[missing]; ev.Type != EventFailed || ev.Err == nil {
+		t.Errorf("Expecting failed event, got %s, %v", ev.Type, ev.Err)
+	}
+}
+
+func TestEngineRunEvents(t *testing.T) {
+	b := NewEventBus(10)
+	e := NewEngine(context.Background(), 1, WithEvents(b))
+	m, skipped := &providers.Media{ID: "muxed"}, &providers.Media{ID: "present"}
+	e.Submit(&Job{Media: m, Run: func(ctx context.Context, r providers.ProgressReporter) error {
+		r.Start(m)
+		r.Bytes(m, 42)
+		r.Done(m, nil)
+		return nil
+	}})
+	e.Skipped(skipped)
+	e.Close()
+	for r := range e.Results() {
+		if r.Err != nil {
+			t.Errorf("Job failed: %s", r.Err)
+		}
+	}
+	b.Close()
+
+	last := map[*providers.Media]Event{}
+	for ev := range b.Events() {
+		last[ev.Media] = ev
+	}
+	if ev := last[m]; ev.Type != EventCompleted || ev.Bytes != 42 {
+		t.Errorf("Expecting completed event with 42 bytes, got %s with %d bytes", ev.Type, ev.Bytes)
+	}
+	if ev := last[skipped]; ev.Type != EventSkipped {
+		t.Errorf("Expecting skipped event, got %s", ev.Type)
+	}
+	if s := e.Stats().Total(); s.Downloaded != 1 || s.Skipped != 1 || s.Bytes != 42 {
+		t.Errorf("Expecting 1 downloaded and 1 skipped media with 42 bytes, got %s", s)
+	}
+}
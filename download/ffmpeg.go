@@ -9,6 +9,8 @@ import (
 	"log"
 	"os/exec"
 	"time"
+
+	"github.com/simulot/aspiratv/providers"
 )
 
 func dropCR(data []byte) []byte {
@@ -123,34 +125,51 @@ func watchProgress(r io.ReadCloser, prg Progresser) {
 }
 
 type ffmpegConfig struct {
-	debug bool
-	pgr   Progresser
+	debug  bool
+	pgr    Progresser
+	binary string
+
+	reporter providers.ProgressReporter // Notified of the download progress when not nil
+	media    *providers.Media
 }
 
 type ffmpegConfigurator func(c *ffmpegConfig)
 
 func FFMepg(ctx context.Context, u string, params []string, configurators ...ffmpegConfigurator) error {
-	cfg := ffmpegConfig{}
+	cfg := ffmpegConfig{
+		binary: "ffmpeg",
+	}
 
 	for _, c := range configurators {
 		c(&cfg)
 	}
 
+	binary, err := lookFFMpeg(cfg.binary)
+	if err != nil {
+		return err
+	}
+
 	if cfg.debug {
-		log.Printf("[FFMPEG] runing ffmpeg %v", params)
+		log.Printf("[FFMPEG] runing %s %v", binary, params)
+	}
+
+	if cfg.reporter != nil {
+		cfg.pgr = &reporterProgresser{r: cfg.reporter, m: cfg.media, pgr: cfg.pgr}
+		cfg.reporter.Start(cfg.media)
 	}
 
-	cmd := exec.CommandContext(ctx, "ffmpeg", params...)
+	cmd := exec.CommandContext(ctx, binary, params...)
 	out, err := cmd.StderrPipe()
 	if cfg.pgr != nil {
 		watchProgress(out, cfg.pgr)
 	}
 	err = cmd.Start()
-	if err != nil {
-		return err
+	if err == nil {
+		err = cmd.Wait()
+	}
+	if cfg.reporter != nil {
+		cfg.reporter.Done(cfg.media, err)
 	}
-	err = cmd.Wait()
-
 	return err
 }
 
@@ -165,3 +184,10 @@ func FFMepgWithDebug(debug bool) ffmpegConfigurator {
 		c.debug = debug
 	}
 }
+
+// FFMepgWithBinary set the ffmpeg binary to be used
+func FFMepgWithBinary(binary string) ffmpegConfigurator {
+	return func(c *ffmpegConfig) {
+		c.binary = binary
+	}
+}
@@ -0,0 +1,144 @@
+package download
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/simulot/aspiratv/net/myhttp"
+	"github.com/simulot/aspiratv/playlists/m3u8"
+)
+
+// fingerprintSamples is the number of segments hashed by Fingerprint, spread along the stream
+const fingerprintSamples = 5
+
+// Fingerprint identifies the content of the HLS stream at url u, whatever its url.
+// It's the SHA-256 hash of segments spread along the lowest quality variant, followed by the number
+// of segments and the duration of the stream. Re-broadcasts of the same video get the same fingerprint,
+// while episodes of a series sharing their opening credits and their length get different ones.
+func Fingerprint(ctx context.Context, u string, configurators ...configurator) (string, error) {
+	cfg := config{getter: myhttp.DefaultClient}
+	for _, c := range configurators {
+		c(&cfg)
+	}
+	master, err := m3u8.NewMaster(ctx, u, cfg.getter)
+	if err != nil {
+		return "", fmt.Errorf("Can't get master playlist: %w", err)
+	}
+	if master.IsMaster() {
+		u, err = master.Quality("worst")
+		if err != nil {
+			return "", fmt.Errorf("Can't fingerprint %q: %w", u, err)
+		}
+	}
+	pl, err := m3u8.NewPlayList(ctx, u, cfg.getter)
+	if err != nil {
+		return "", fmt.Errorf("Can't get playlist: %w", err)
+	}
+	segments := pl.Segments()
+	if len(segments) == 0 {
+		return "", fmt.Errorf("Can't fingerprint %q: no segment", u)
+	}
+	h := sha256.New()
+	for _, i := range sampleSegments(len(segments), fingerprintSamples) {
+		err = copySegment(ctx, checkedGetter{g: cfg.getter}, segments[i], h)
+		if err != nil {
+			return "", fmt.Errorf("Can't fingerprint %q: %w", u, err)
+		}
+	}
+	return fmt.Sprintf("%s/%d/%.0f", hex.EncodeToString(h.Sum(nil)), len(segments), pl.Duration.Seconds()), nil
+}
+
+// sampleSegments gives the indexes of at most k segments out of n, evenly spread from the first to the last one
+func sampleSegments(n, k int) []int {
+	if n <= k {
+		k = n
+	}
+	if k <= 1 {
+		return []int{0}
+	}
+	l := make([]int, 0, k)
+	for i := 0; i < k; i++ {
+		l = append(l, i*(n-1)/(k-1))
+	}
+	return l
+}
+
+// FingerprintIndex records the fingerprints of downloaded streams with the file they were written to
+type FingerprintIndex struct {
+	Files map[string]string `json:"files"` // File names by fingerprint
+
+	mu sync.Mutex
+}
+
+// NewFingerprintIndex creates an empty index
+func NewFingerprintIndex() *FingerprintIndex {
+	return &FingerprintIndex{Files: map[string]string{}}
+}
+
+// LoadFingerprintIndex reads the index file. An empty index is returned when the file doesn't exist yet.
+func LoadFingerprintIndex(indexPath string) (*FingerprintIndex, error) {
+	idx := NewFingerprintIndex()
+	b, err := ioutil.ReadFile(indexPath)
+	if os.IsNotExist(err) {
+		return idx, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Can't read fingerprint index: %w", err)
+	}
+	err = json.Unmarshal(b, idx)
+	if err != nil {
+		return nil, fmt.Errorf("Can't decode fingerprint index %q: %w", indexPath, err)
+	}
+	if idx.Files == nil {
+		idx.Files = map[string]string{}
+	}
+	return idx, nil
+}
+
+// Lookup gives the file already holding the content with the fingerprint
+func (idx *FingerprintIndex) Lookup(fingerprint string) (string, bool) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	f, ok := idx.Files[fingerprint]
+	return f, ok
+}
+
+// Add records the file holding the content with the fingerprint
+func (idx *FingerprintIndex) Add(fingerprint, file string) {
+	idx.mu.Lock()
+	idx.Files[fingerprint] = file
+	idx.mu.Unlock()
+}
+
+// Save writes the index file. The file is replaced only when completely written.
+func (idx *FingerprintIndex) Save(indexPath string) error {
+	idx.mu.Lock()
+	b, err := json.MarshalIndent(idx, "", "  ")
+	idx.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("Can't encode fingerprint index: %w", err)
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(indexPath), filepath.Base(indexPath)+".*")
+	if err != nil {
+		return fmt.Errorf("Can't save fingerprint index: %w", err)
+	}
+	_, err = tmp.Write(b)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), indexPath)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("Can't save fingerprint index: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package download
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFingerprint(t *testing.T) {
+	ts1, _ := newHLSServer(5)
+	defer ts1.Close()
+	ts2, _ := newHLSServer(5)
+	defer ts2.Close()
+	ts3, _ := newHLSServer(6)
+	defer ts3.Close()
+	ctx := context.Background()
+
+	fp1, err := Fingerprint(ctx, ts1.URL+"/master.m3u8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fp2, err := Fingerprint(ctx, ts2.URL+"/playlist.m3u8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fp1 != fp2 {
+		t.Errorf("Expecting the same fingerprint for the same content, got %q and %q", fp1, fp2)
+	}
+	fp3, err := Fingerprint(ctx, ts3.URL+"/master.m3u8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fp1 == fp3 {
+		t.Errorf("Expecting different fingerprints for different contents")
+	}
+	if _, err := Fingerprint(ctx, ts1.URL+"/missing.m3u8"); err == nil {
+		t.Errorf("Expecting an error for a missing playlist")
+	}
+}
+
+// newSegmentsServer serves a media playlist of 10 seconds segments with the given contents
+func newSegmentsServer(bodies ...string) *httptest.Server {
+	mux := http.NewServeMux()
+	playlist := strings.Builder{}
+	playlist.WriteString("#EXTM3U\n#EXT-X-TARGETDURATION:10\n")
+	for i, body := range bodies {
+		body := body
+		name := fmt.Sprintf("seg-%d.ts", i)
+		fmt.Fprintf(&playlist, "#EXTINF:10.0,\n%s\n", name)
+		mux.HandleFunc("/"+name, func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, body)
+		})
+	}
+	playlist.WriteString("#EXT-X-ENDLIST\n")
+	mux.HandleFunc("/playlist.m3u8", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, playlist.String())
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestFingerprintEpisodes(t *testing.T) {
+	// Episodes of a series: the same opening credits, the same number of segments
+	ts1 := newSegmentsServer("credits", "a1", "a2", "a3", "a4", "a5", "a6", "a7")
+	defer ts1.Close()
+	ts2 := newSegmentsServer("credits", "b1", "b2", "b3", "b4", "b5", "b6", "b7")
+	defer ts2.Close()
+	ctx := context.Background()
+
+	fp1, err := Fingerprint(ctx, ts1.URL+"/playlist.m3u8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fp2, err := Fingerprint(ctx, ts2.URL+"/playlist.m3u8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fp1 == fp2 {
+		t.Errorf("Expecting different fingerprints for episodes sharing their opening credits, got %q", fp1)
+	}
+	if !strings.HasSuffix(fp1, "/8/80") {
+		t.Errorf("Expecting the number of segments and the duration in %q", fp1)
+	}
+}
+
+func TestSampleSegments(t *testing.T) {
+	tests := []struct {
+		n, k int
+		want []int
+	}{
+		{1, 5, []int{0}},
+		{3, 5, []int{0, 1, 2}},
+		{5, 5, []int{0, 1, 2, 3, 4}},
+		{9, 5, []int{0, 2, 4, 6, 8}},
+		{100, 5, []int{0, 24, 49, 74, 99}},
+	}
+	for _, tt := range tests {
+		if got := sampleSegments(tt.n, tt.k); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("sampleSegments(%d, %d) = %v, want %v", tt.n, tt.k, got, tt.want)
+		}
+	}
+}
+
+func TestFingerprintIndex(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aspiratv-fingerprints-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	indexPath := filepath.Join(dir, "fingerprints.json")
+
+	idx, err := LoadFingerprintIndex(indexPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := idx.Lookup("abc/5"); ok {
+		t.Errorf("Expecting an empty index")
+	}
+	idx.Add("abc/5", "Show/Season 01/Show - s01e01.mp4")
+	if err := idx.Save(indexPath); err != nil {
+		t.Fatal(err)
+	}
+
+	idx, err = LoadFingerprintIndex(indexPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f, ok := idx.Lookup("abc/5"); !ok || f != "Show/Season 01/Show - s01e01.mp4" {
+		t.Errorf("Lookup() = %q, %v", f, ok)
+	}
+}
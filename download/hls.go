@@ -0,0 +1,179 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/simulot/aspiratv/net/myhttp"
+	"github.com/simulot/aspiratv/playlists/m3u8"
+	"github.com/simulot/aspiratv/providers"
+)
+
+// ErrTimeout is returned when a download or a segment isn't completed in time
+var ErrTimeout = errors.New("download timed out")
+
+// Getter is the interface used to fetch playlists and segments
+type Getter interface {
+	Get(ctx context.Context, uri string) (io.ReadCloser, error)
+}
+
+type config struct {
+	debug    bool
+	getter   Getter
+	progress func(current, total int64)
+	workers  int // Number of segments downloaded at a time
+
+	timeout        time.Duration // Deadline of the whole download when not zero
+	segmentTimeout time.Duration // Deadline of each segment download when not zero
+	segmentRetries int           // Number of retries of a timed out segment
+	grace          time.Duration // Time left to segments being downloaded when the context is cancelled
+
+	bandwidth *BandwidthLimiter // Download speed limiter, unlimited when nil
+	storage   Storage           // Receives the downloaded file
+
+	reporter providers.ProgressReporter // Notified of the download progress when not nil
+	media    *providers.Media
+}
+
+type configurator func(c *config)
+
+// WithGetter set the getter used to download the playlists and segments
+func WithGetter(g Getter) configurator {
+	return func(c *config) {
+		c.getter = g
+	}
+}
+
+// WithProgress set the function called as the download progresses
+func WithProgress(fn func(current, total int64)) configurator {
+	return func(c *config) {
+		c.progress = fn
+	}
+}
+
+// WithWorkers set the number of segments downloaded at a time, 1 by default.
+func WithWorkers(n int) configurator {
+	return func(c *config) {
+		c.workers = n
+	}
+}
+
+// WithTimeout set the deadline of the whole download
+func WithTimeout(d time.Duration) configurator {
+	return func(c *config) {
+		c.timeout = d
+	}
+}
+
+// WithSegmentTimeout set the deadline of each segment download. A timed out
+// segment is downloaded again at most retries times before failing the download.
+func WithSegmentTimeout(d time.Duration, retries int) configurator {
+	return func(c *config) {
+		c.segmentTimeout = d
+		c.segmentRetries = retries
+	}
+}
+
+// WithDebug enable logs
+func WithDebug(debug bool) configurator {
+	return func(c *config) {
+		c.debug = debug
+	}
+}
+
+// HLS downloads the HLS stream at url u and writes the concatenated segments into dest.
+// When u is a master playlist, the best quality variant is downloaded.
+// Segments are written into the directory dest.parts, and concatenated in playlist order
+// when all of them are downloaded. An interrupted download is resumed from the segments
+// already present in dest.parts, see WithGracefulStop.
+func HLS(ctx context.Context, u string, dest string, configurators ...configurator) error {
+	cfg := config{
+		getter:  myhttp.DefaultClient,
+		storage: LocalStorage{},
+	}
+	for _, c := range configurators {
+		c(&cfg)
+	}
+	cfg.getter = checkedGetter{g: cfg.getter}
+	if cfg.bandwidth != nil {
+		cfg.getter = limitedGetter{g: cfg.getter, l: cfg.bandwidth}
+	}
+	if cfg.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.timeout)
+		defer cancel()
+	}
+	if cfg.reporter != nil {
+		cfg.getter = countingGetter{g: cfg.getter, r: cfg.reporter, m: cfg.media}
+		cfg.reporter.Start(cfg.media)
+	}
+	err := hls(ctx, cfg, u, dest)
+	if cfg.timeout > 0 && errors.Is(err, context.DeadlineExceeded) {
+		err = fmt.Errorf("%w: download not completed after %s", ErrTimeout, cfg.timeout)
+	}
+	if cfg.reporter != nil {
+		cfg.reporter.Done(cfg.media, err)
+	}
+	return err
+}
+
+func hls(ctx context.Context, cfg config, u string, dest string) error {
+	master, err := m3u8.NewMaster(ctx, u, cfg.getter)
+	if err != nil {
+		return fmt.Errorf("Can't get master playlist: %w", err)
+	}
+	if master.IsMaster() {
+		u = master.BestQuality()
+	}
+	if cfg.debug {
+		log.Printf("[HLS] Playlist url %q", u)
+	}
+
+	pl, err := m3u8.NewPlayList(ctx, u, cfg.getter)
+	if err != nil {
+		return fmt.Errorf("Can't get playlist: %w", err)
+	}
+	segments := pl.Segments()
+	if !pl.Ended() {
+		log.Printf("[HLS] Playlist %q has no end tag, the media may be incomplete", u)
+	}
+
+	return segmentedHLS(ctx, cfg, segments, dest)
+}
+
+func copySegment(ctx context.Context, g Getter, u string, w io.Writer) error {
+	r, err := g.Get(ctx, u)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	_, err = io.Copy(w, r)
+	return err
+}
+
+// writePart calls fn with a writer on the storage's dest.part file. When fn succeeds, the
+// part file is flushed to the disk when possible and renamed into dest, otherwise it is removed.
+func writePart(s Storage, dest string, fn func(w io.Writer) error) error {
+	part := dest + ".part"
+	f, err := s.Create(part)
+	if err != nil {
+		return fmt.Errorf("Can't create file: %w", err)
+	}
+	err = fn(f)
+	if syncer, ok := f.(interface{ Sync() error }); ok && err == nil {
+		// A crash after the rename mustn't leave an empty file in place of the media
+		err = syncer.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		s.Remove(part)
+		return err
+	}
+	return s.Rename(part, dest)
+}
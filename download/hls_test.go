@@ -0,0 +1,335 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/simulot/aspiratv/net/myhttp"
+)
+
+func newHLSServer(segments int) (*httptest.Server, string) {
+	expected := strings.Builder{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/master.m3u8", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "#EXTM3U\n#EXT-X-STREAM-INF:BANDWIDTH=258157,RESOLUTION=422x180\nplaylist.m3u8\n")
+	})
+	playlist := strings.Builder{}
+	playlist.WriteString("#EXTM3U\n#EXT-X-TARGETDURATION:10\n")
+	for i := 0; i < segments; i++ {
+		body := strings.Repeat(string(rune('A'+i%26)), 100)
+		expected.WriteString(body)
+		name := fmt.Sprintf("seg-%d.ts", i)
+		fmt.Fprintf(&playlist, "#EXTINF:10.0,\n%s\n", name)
+		mux.HandleFunc("/"+name, func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, body)
+		})
+	}
+	playlist.WriteString("#EXT-X-ENDLIST\n")
+	mux.HandleFunc("/playlist.m3u8", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, playlist.String())
+	})
+	mux.HandleFunc("/broken.m3u8", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "#EXTM3U\n#EXTINF:10.0,\nseg-0.ts\n#EXTINF:10.0,\nmissing.ts\n")
+	})
+	return httptest.NewServer(mux), expected.String()
+}
+
+func TestHLS(t *testing.T) {
+	ts, expected := newHLSServer(30)
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "aspiratv-hls-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	dest := filepath.Join(dir, "show", "media.ts")
+
+	calls := 0
+	err = HLS(context.TODO(), ts.URL+"/master.m3u8", dest, WithProgress(func(current, total int64) {
+		calls++
+		if total != 30 {
+			t.Errorf("Expecting total to be %d, got %d", 30, total)
+		}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 30 {
+		t.Errorf("Expecting progress to be called %d times, got %d", 30, calls)
+	}
+
+	b, err := ioutil.ReadFile(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != expected {
+		t.Errorf("Downloaded content differs from expected")
+	}
+	if _, err := os.Stat(dest + ".part"); !os.IsNotExist(err) {
+		t.Errorf("Expecting part file to be renamed")
+	}
+}
+
+func TestHLSFailure(t *testing.T) {
+	ts, _ := newHLSServer(3)
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "aspiratv-hls-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	dest := filepath.Join(dir, "media.ts")
+
+	err = HLS(context.TODO(), ts.URL+"/broken.m3u8", dest)
+	if err == nil {
+		t.Fatal("Expecting an error")
+	}
+	if _, err := os.Stat(dest + ".part"); !os.IsNotExist(err) {
+		t.Errorf("Expecting part file to be removed")
+	}
+}
+
+// slowGetter delays first segments, so they complete after the following ones
+type slowGetter struct {
+	mu       sync.Mutex
+	inFlight int
+	max      int
+}
+
+func (g *slowGetter) Get(ctx context.Context, uri string) (io.ReadCloser, error) {
+	g.mu.Lock()
+	g.inFlight++
+	if g.inFlight > g.max {
+		g.max = g.inFlight
+	}
+	g.mu.Unlock()
+	defer func() {
+		g.mu.Lock()
+		g.inFlight--
+		g.mu.Unlock()
+	}()
+
+	var i int
+	if _, err := fmt.Sscanf(uri[strings.LastIndex(uri, "/")+1:], "seg-%d.ts", &i); err == nil {
+		time.Sleep(time.Duration(10-i%10) * time.Millisecond)
+	}
+	return myhttp.DefaultClient.Get(ctx, uri)
+}
+
+func TestHLSParallel(t *testing.T) {
+	ts, expected := newHLSServer(30)
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "aspiratv-hls-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	dest := filepath.Join(dir, "media.ts")
+
+	g := &slowGetter{}
+	last := int64(0)
+	err = HLS(context.TODO(), ts.URL+"/master.m3u8", dest, WithGetter(g), WithWorkers(4), WithProgress(func(current, total int64) {
+		last = current
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last != 30 {
+		t.Errorf("Expecting progress to reach %d, got %d", 30, last)
+	}
+	if g.max < 2 || g.max > 4 {
+		t.Errorf("Expecting between 2 and 4 concurrent requests, got %d", g.max)
+	}
+	b, err := ioutil.ReadFile(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != expected {
+		t.Errorf("Segments aren't in playlist order")
+	}
+	if _, err := os.Stat(dest + ".parts"); !os.IsNotExist(err) {
+		t.Errorf("Expecting segments directory to be removed")
+	}
+}
+
+func TestHLSParallelFailure(t *testing.T) {
+	ts, _ := newHLSServer(3)
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "aspiratv-hls-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	dest := filepath.Join(dir, "media.ts")
+
+	err = HLS(context.TODO(), ts.URL+"/broken.m3u8", dest, WithWorkers(4))
+	if err == nil {
+		t.Fatal("Expecting an error")
+	}
+	if _, err := os.Stat(dest); !os.IsNotExist(err) {
+		t.Errorf("Expecting no media file")
+	}
+}
+
+func TestHLSResume(t *testing.T) {
+	ts, expected := newHLSServer(5)
+	defer ts.Close()
+
+	mu := sync.Mutex{}
+	calls := map[string]int{}
+	failing := true
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls[r.URL.Path]++
+		fail := failing && r.URL.Path == "/seg-3.ts"
+		mu.Unlock()
+		if fail {
+			http.Error(w, "boom", http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, ts.URL+r.URL.Path, http.StatusFound)
+	}))
+	defer proxy.Close()
+
+	dir, err := ioutil.TempDir("", "aspiratv-hls-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	dest := filepath.Join(dir, "media.ts")
+
+	err = HLS(context.TODO(), proxy.URL+"/playlist.m3u8", dest)
+	if err == nil {
+		t.Fatal("Expecting an error")
+	}
+	if _, err := os.Stat(filepath.Join(dest+".parts", manifestName)); err != nil {
+		t.Fatalf("Expecting the manifest to be kept: %s", err)
+	}
+	// Damage segment 1, it must be downloaded again
+	err = ioutil.WriteFile(segmentPath(dest+".parts", 1), []byte("AA"), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	failing = false
+	mu.Unlock()
+	err = HLS(context.TODO(), proxy.URL+"/playlist.m3u8", dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != expected {
+		t.Errorf("Resumed content differs from expected")
+	}
+	want := map[string]int{"/seg-0.ts": 1, "/seg-1.ts": 2, "/seg-2.ts": 1, "/seg-3.ts": 2, "/seg-4.ts": 1}
+	for p, n := range want {
+		if calls[p] != n {
+			t.Errorf("Expecting %s to be requested %d times, got %d", p, n, calls[p])
+		}
+	}
+	if _, err := os.Stat(dest + ".parts"); !os.IsNotExist(err) {
+		t.Errorf("Expecting segments directory to be removed")
+	}
+}
+
+func TestHLSSegmentTimeout(t *testing.T) {
+	ts, expected := newHLSServer(3)
+	defer ts.Close()
+
+	tests := []struct {
+		name    string
+		stalls  int // Number of times segment 1 stalls
+		wantErr bool
+	}{
+		{"recover after a stall", 1, false},
+		{"too many stalls", 3, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mu := sync.Mutex{}
+			stalls := tt.stalls
+			proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				stall := r.URL.Path == "/seg-1.ts" && stalls > 0
+				if stall {
+					stalls--
+				}
+				mu.Unlock()
+				if stall {
+					select {
+					case <-r.Context().Done():
+					case <-time.After(time.Second):
+					}
+					return
+				}
+				http.Redirect(w, r, ts.URL+r.URL.Path, http.StatusFound)
+			}))
+			defer proxy.Close()
+
+			dir, err := ioutil.TempDir("", "aspiratv-hls-")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			dest := filepath.Join(dir, "media.ts")
+
+			err = HLS(context.TODO(), proxy.URL+"/playlist.m3u8", dest, WithSegmentTimeout(50*time.Millisecond, 1))
+			if tt.wantErr {
+				if !errors.Is(err, ErrTimeout) || !strings.Contains(err.Error(), "segment 1") {
+					t.Errorf("Expecting a timeout error on segment 1, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, _ := ioutil.ReadFile(dest)
+			if string(b) != expected {
+				t.Errorf("Downloaded content differs from expected")
+			}
+		})
+	}
+}
+
+func TestHLSTimeout(t *testing.T) {
+	ts, _ := newHLSServer(3)
+	defer ts.Close()
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/seg-2.ts" {
+			<-r.Context().Done()
+			return
+		}
+		http.Redirect(w, r, ts.URL+r.URL.Path, http.StatusFound)
+	}))
+	defer proxy.Close()
+
+	dir, err := ioutil.TempDir("", "aspiratv-hls-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	err = HLS(context.TODO(), proxy.URL+"/playlist.m3u8", filepath.Join(dir, "media.ts"), WithTimeout(100*time.Millisecond))
+	if !errors.Is(err, ErrTimeout) {
+		t.Errorf("Expecting %v, got %v", ErrTimeout, err)
+	}
+}
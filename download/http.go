@@ -0,0 +1,83 @@
+package download
+
+import (
+	"context"
+	"crypto/sha1"
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+
+	"github.com/simulot/aspiratv/net/myhttp"
+)
+
+// HTTP downloads a media file served as a whole, like mp4 files.
+// The progress function gives the number of bytes downloaded so far.
+func HTTP(ctx context.Context, u string, dest string, configurators ...configurator) error {
+	cfg := config{
+		getter:  myhttp.DefaultClient,
+		storage: LocalStorage{},
+	}
+	for _, c := range configurators {
+		c(&cfg)
+	}
+	cfg.getter = checkedGetter{g: cfg.getter}
+	if cfg.bandwidth != nil {
+		cfg.getter = limitedGetter{g: cfg.getter, l: cfg.bandwidth}
+	}
+	if cfg.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.timeout)
+		defer cancel()
+	}
+
+	if cfg.reporter != nil {
+		cfg.getter = countingGetter{g: cfg.getter, r: cfg.reporter, m: cfg.media}
+		cfg.reporter.Start(cfg.media)
+	}
+	err := httpDownload(ctx, cfg, u, dest)
+	if cfg.reporter != nil {
+		cfg.reporter.Done(cfg.media, err)
+	}
+	return err
+}
+
+func httpDownload(ctx context.Context, cfg config, u string, dest string) error {
+	r, err := cfg.getter.Get(ctx, u)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	h := sha1.New()
+	size := int64(0)
+	err = writePart(cfg.storage, dest, func(w io.Writer) error {
+		w = io.MultiWriter(w, h)
+		if cfg.progress != nil {
+			w = &progressWriter{w: w, fn: cfg.progress}
+		}
+		size, err = io.Copy(w, r)
+		return err
+	})
+	if cfg.timeout > 0 && ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("%w: download not completed after %s", ErrTimeout, cfg.timeout)
+	}
+	if err != nil {
+		return err
+	}
+	log.Printf("[HTTP] %q completed: %d bytes, sha1 %x", filepath.Base(dest), size, h.Sum(nil))
+	return nil
+}
+
+type progressWriter struct {
+	w     io.Writer
+	count int64
+	fn    func(current, total int64)
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	p.count += int64(n)
+	p.fn(p.count, 0)
+	return n, err
+}
@@ -0,0 +1,56 @@
+package download
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// CancelOnInterrupt returns a context cancelled when the process is interrupted by Ctrl+C or SIGTERM.
+// A second interruption kills the process. Call stop to release the signal handler.
+func CancelOnInterrupt(parent context.Context) (ctx context.Context, stop func()) {
+	ctx, cancel := context.WithCancel(parent)
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case <-signals:
+			cancel()
+		case <-ctx.Done():
+		}
+		signal.Stop(signals) // Back to the default behavior
+	}()
+	return ctx, func() {
+		signal.Stop(signals)
+		cancel()
+	}
+}
+
+// WithGracefulStop lets segments being downloaded when the context is cancelled complete within grace.
+// No new segment is started, and the completed ones are kept to resume the download later.
+func WithGracefulStop(grace time.Duration) configurator {
+	return func(c *config) {
+		c.grace = grace
+	}
+}
+
+// gracefulContext returns a context cancelled grace after ctx is done, or when cancel is called
+func gracefulContext(ctx context.Context, grace time.Duration) (context.Context, context.CancelFunc) {
+	g, cancel := context.WithCancel(context.Background())
+	go func() {
+		select {
+		case <-ctx.Done():
+			t := time.NewTimer(grace)
+			defer t.Stop()
+			select {
+			case <-t.C:
+			case <-g.Done():
+			}
+			cancel()
+		case <-g.Done():
+		}
+	}()
+	return g, cancel
+}
@@ -0,0 +1,74 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/simulot/aspiratv/net/myhttp"
+)
+
+// cancellingGetter cancels the download when the given segment is requested
+type cancellingGetter struct {
+	segment string
+	cancel  context.CancelFunc
+}
+
+func (g cancellingGetter) Get(ctx context.Context, uri string) (io.ReadCloser, error) {
+	if strings.HasSuffix(uri, g.segment) {
+		g.cancel()
+		time.Sleep(20 * time.Millisecond) // The segment is still being downloaded
+	}
+	return myhttp.DefaultClient.Get(ctx, uri)
+}
+
+func TestGracefulStop(t *testing.T) {
+	ts, expected := newHLSServer(5)
+	defer ts.Close()
+
+	tests := []struct {
+		name      string
+		grace     time.Duration
+		completed int
+	}{
+		{"immediate", 0, 1},
+		{"graceful", time.Second, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "aspiratv-graceful-")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			dest := filepath.Join(dir, "media.ts")
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			err = HLS(ctx, ts.URL+"/playlist.m3u8", dest, WithGetter(cancellingGetter{segment: "seg-1.ts", cancel: cancel}), WithGracefulStop(tt.grace))
+			if !errors.Is(err, context.Canceled) {
+				t.Fatalf("Expecting %v, got %v", context.Canceled, err)
+			}
+			m := loadManifest(dest + ".parts")
+			if len(m.Segments) != tt.completed {
+				t.Errorf("Expecting %d completed segments, got %d", tt.completed, len(m.Segments))
+			}
+
+			// The download is resumed from the completed segments
+			err = HLS(context.Background(), ts.URL+"/playlist.m3u8", dest)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, _ := ioutil.ReadFile(dest)
+			if string(b) != expected {
+				t.Errorf("Unexpected resumed file content")
+			}
+		})
+	}
+}
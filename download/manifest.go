@@ -0,0 +1,118 @@
+package download
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// ManifestEntry describes a file of the library
+type ManifestEntry struct {
+	Path   string `json:"path"` // Path relative to the library root, with slashes
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Manifest problems
+const (
+	ManifestMissing = "missing" // The file isn't in the library anymore
+	ManifestChanged = "changed" // The size or the content of the file differ
+)
+
+// ManifestProblem is a file of the manifest that doesn't match the library
+type ManifestProblem struct {
+	Path    string
+	Problem string // ManifestMissing or ManifestChanged
+}
+
+func (p ManifestProblem) String() string {
+	return p.Path + ": " + p.Problem
+}
+
+// WriteManifest walks the library and writes the path, size and SHA-256 hash of each file as a JSON array, sorted by path
+func WriteManifest(libraryRoot string, w io.Writer) error {
+	entries := []ManifestEntry{}
+	err := filepath.Walk(libraryRoot, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !fi.Mode().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(libraryRoot, path)
+		if err != nil {
+			return err
+		}
+		sum, err := fileSHA256(path)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, ManifestEntry{Path: filepath.ToSlash(rel), Size: fi.Size(), SHA256: sum})
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("Can't walk library: %w", err)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	err = enc.Encode(entries)
+	if err != nil {
+		return fmt.Errorf("Can't write manifest: %w", err)
+	}
+	return nil
+}
+
+// VerifyManifest checks the files of the manifest read from r against the library.
+// It returns the files that are missing or have changed. Files not in the manifest are ignored.
+func VerifyManifest(libraryRoot string, r io.Reader) ([]ManifestProblem, error) {
+	entries := []ManifestEntry{}
+	err := json.NewDecoder(r).Decode(&entries)
+	if err != nil {
+		return nil, fmt.Errorf("Can't read manifest: %w", err)
+	}
+	problems := []ManifestProblem{}
+	for _, e := range entries {
+		path := filepath.Join(libraryRoot, filepath.FromSlash(e.Path))
+		fi, err := os.Stat(path)
+		if os.IsNotExist(err) {
+			problems = append(problems, ManifestProblem{Path: e.Path, Problem: ManifestMissing})
+			continue
+		}
+		if err != nil {
+			return problems, fmt.Errorf("Can't verify %q: %w", e.Path, err)
+		}
+		if fi.Size() != e.Size {
+			problems = append(problems, ManifestProblem{Path: e.Path, Problem: ManifestChanged})
+			continue
+		}
+		sum, err := fileSHA256(path)
+		if err != nil {
+			return problems, fmt.Errorf("Can't verify %q: %w", e.Path, err)
+		}
+		if sum != e.SHA256 {
+			problems = append(problems, ManifestProblem{Path: e.Path, Problem: ManifestChanged})
+		}
+	}
+	return problems, nil
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	_, err = io.Copy(h, f)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,64 @@
+package download
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestManifest(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aspiratv-manifest-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"Show/Season 01/Show - s01e01.mp4": "episode 1",
+		"Show/Season 01/Show - s01e02.mp4": "episode 2",
+		"Show/tvshow.nfo":                  "<tvshow/>",
+		"Movie (2020)/Movie (2020).mp4":    "movie",
+	}
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(p), 0777)
+		if err := ioutil.WriteFile(p, []byte(content), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	b := bytes.NewBuffer(nil)
+	if err := WriteManifest(dir, b); err != nil {
+		t.Fatal(err)
+	}
+	manifest := b.Bytes()
+
+	problems, err := VerifyManifest(dir, bytes.NewReader(manifest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) != 0 {
+		t.Errorf("Expecting no problem, got %v", problems)
+	}
+
+	os.Remove(filepath.Join(dir, "Show", "tvshow.nfo"))
+	ioutil.WriteFile(filepath.Join(dir, "Show", "Season 01", "Show - s01e01.mp4"), []byte("episode 0"), 0666)
+	ioutil.WriteFile(filepath.Join(dir, "Show", "Season 01", "Show - s01e02.mp4"), []byte("truncated"[:5]), 0666)
+	ioutil.WriteFile(filepath.Join(dir, "new.mp4"), []byte("new"), 0666)
+
+	problems, err = VerifyManifest(dir, bytes.NewReader(manifest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ManifestProblem{
+		{"Show/Season 01/Show - s01e01.mp4", ManifestChanged},
+		{"Show/Season 01/Show - s01e02.mp4", ManifestChanged},
+		{"Show/tvshow.nfo", ManifestMissing},
+	}
+	if !reflect.DeepEqual(problems, want) {
+		t.Errorf("Expecting %v, got %v", want, problems)
+	}
+}
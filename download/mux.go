@@ -0,0 +1,219 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/simulot/aspiratv/providers"
+)
+
+// ErrFFMpegNotFound is returned when the ffmpeg binary can't be found
+var ErrFFMpegNotFound = errors.New("ffmpeg not found")
+
+// Metadata holds tags to be written into the media file
+type Metadata struct {
+	Title   string
+	Show    string
+	Comment string
+	Channel string
+	Season  int
+	Episode int
+
+	Subtitles []providers.SubtitleTrack // Subtitles embedded into the file, see CanEmbedSubtitles
+	Chapters  []providers.Chapter       // Chapters written into the file, when not empty
+	Audio     []providers.AudioVersion  // Audio tracks replacing the ones of the stream, when not empty, see providers.SelectAudio
+
+	chaptersFile string // ffmpeg metadata file giving the chapters
+}
+
+// CanEmbedSubtitles tells if the file at outPath can hold subtitle tracks
+func CanEmbedSubtitles(outPath string) bool {
+	return len(subtitleCodec(outPath)) > 0
+}
+
+// subtitleCodec gives the codec of embedded subtitles for the container of outPath, empty when not supported
+func subtitleCodec(outPath string) string {
+	switch strings.ToLower(filepath.Ext(outPath)) {
+	case ".mp4", ".m4v":
+		return "mov_text"
+	case ".mkv":
+		return "srt"
+	}
+	return ""
+}
+
+// languages gives ISO 639-2 codes, used by containers, for usual ISO 639-1 codes
+var languages = map[string]string{
+	"fr": "fre",
+	"en": "eng",
+	"de": "ger",
+	"es": "spa",
+	"it": "ita",
+	"nl": "dut",
+	"pt": "por",
+	"ar": "ara",
+}
+
+// subtitleLanguage returns the ISO 639-2 code of the language
+func subtitleLanguage(lang string) string {
+	lang = strings.ToLower(lang)
+	if l, ok := languages[lang]; ok {
+		return l
+	}
+	if len(lang) == 0 {
+		return "und"
+	}
+	return lang
+}
+
+// MuxHLS remuxes the HLS stream at url u into an MP4 file, or a Matroska file when outPath
+// ends with .mkv, and writes metadata tags.
+// When meta has subtitles, all video and audio streams of u are kept: u mustn't be a master playlist.
+// When meta has audio tracks, only the video of u is kept, followed by the given audio tracks.
+// Use FFMepgWithBinary to give ffmpeg location when it isn't on the PATH.
+func MuxHLS(ctx context.Context, u string, outPath string, meta Metadata, configurators ...ffmpegConfigurator) error {
+	return mux(ctx, u, outPath, meta, true, configurators...)
+}
+
+// MuxDASH remuxes the MPEG-DASH stream described by the manifest at url u, like MuxHLS does.
+func MuxDASH(ctx context.Context, u string, outPath string, meta Metadata, configurators ...ffmpegConfigurator) error {
+	return mux(ctx, u, outPath, meta, false, configurators...)
+}
+
+// MuxFile remuxes the media file at url u, like a progressive MP4, like MuxHLS does.
+func MuxFile(ctx context.Context, u string, outPath string, meta Metadata, configurators ...ffmpegConfigurator) error {
+	return mux(ctx, u, outPath, meta, false, configurators...)
+}
+
+func mux(ctx context.Context, u string, outPath string, meta Metadata, adts bool, configurators ...ffmpegConfigurator) error {
+	if len(meta.Chapters) > 0 {
+		f, err := writeChapters(outPath, meta.Chapters)
+		if err != nil {
+			return err
+		}
+		defer os.Remove(f)
+		meta.chaptersFile = f
+	}
+	return FFMepg(ctx, u, muxParams(u, outPath, meta, adts), configurators...)
+}
+
+// writeChapters writes the chapters into an ffmpeg metadata file next to outPath, and returns its name
+func writeChapters(outPath string, chapters []providers.Chapter) (string, error) {
+	b := strings.Builder{}
+	b.WriteString(";FFMETADATA1\n")
+	for _, c := range chapters {
+		fmt.Fprintf(&b, "[CHAPTER]\nTIMEBASE=1/1000\nSTART=%d\nEND=%d\ntitle=%s\n",
+			c.Start.Milliseconds(), c.End.Milliseconds(), metadataEscaper.Replace(c.Title))
+	}
+	name := outPath + ".chapters.txt"
+	err := os.MkdirAll(filepath.Dir(name), 0777)
+	if err == nil {
+		err = ioutil.WriteFile(name, []byte(b.String()), 0666)
+	}
+	if err != nil {
+		return "", fmt.Errorf("Can't write chapters: %w", err)
+	}
+	return name, nil
+}
+
+// metadataEscaper escapes the special characters of ffmpeg metadata files
+var metadataEscaper = strings.NewReplacer("\\", "\\\\", "=", "\\=", ";", "\\;", "#", "\\#", "\n", "\\\n")
+
+// muxParams gives ffmpeg parameters. ADTS audio streams, found into MPEG-TS segments, need a conversion for MP4.
+func muxParams(u string, outPath string, meta Metadata, adts bool) []string {
+	params := []string{
+		"-loglevel", "info", // Give me feedback
+		"-hide_banner", // I don't want banner
+		"-i", u,        // Where is the stream
+	}
+	codec := subtitleCodec(outPath)
+	subtitles := meta.Subtitles
+	if len(codec) == 0 {
+		subtitles = nil
+	}
+	for _, a := range meta.Audio {
+		params = append(params, "-i", a.URL)
+	}
+	for _, s := range subtitles {
+		params = append(params, "-i", s.URL)
+	}
+	if len(meta.chaptersFile) > 0 {
+		params = append(params, "-i", meta.chaptersFile, "-map_chapters", strconv.Itoa(len(meta.Audio)+len(subtitles)+1))
+	}
+	if len(meta.Audio) > 0 || len(subtitles) > 0 {
+		// Explicit mapping, otherwise ffmpeg keeps only one audio and one subtitle stream
+		params = append(params, "-map", "0:v?")
+		if len(meta.Audio) == 0 {
+			params = append(params, "-map", "0:a?")
+		}
+		for i := range meta.Audio {
+			params = append(params, "-map", strconv.Itoa(i+1)+":a")
+		}
+		for i := range subtitles {
+			params = append(params, "-map", strconv.Itoa(len(meta.Audio)+i+1))
+		}
+	}
+	params = append(params,
+		"-metadata", "title="+meta.Title, // Force title
+		"-metadata", "comment="+meta.Comment, // Force comment
+		"-metadata", "show="+meta.Show, //Force show
+		"-metadata", "channel="+meta.Channel, // Force channel
+	)
+	for i, a := range meta.Audio {
+		params = append(params, "-metadata:s:a:"+strconv.Itoa(i), "language="+subtitleLanguage(a.Language))
+		if a.AudioDescription {
+			params = append(params, "-metadata:s:a:"+strconv.Itoa(i), "title="+a.Name, "-disposition:a:"+strconv.Itoa(i), "visual_impaired")
+		} else if i == 0 {
+			params = append(params, "-disposition:a:0", "default")
+		}
+	}
+	for i, s := range subtitles {
+		params = append(params, "-metadata:s:s:"+strconv.Itoa(i), "language="+subtitleLanguage(s.Language))
+	}
+	if meta.Season > 0 {
+		params = append(params, "-metadata", "season_number="+strconv.Itoa(meta.Season))
+	}
+	if meta.Episode > 0 {
+		params = append(params, "-metadata", "episode_sort="+strconv.Itoa(meta.Episode))
+	}
+	params = append(params,
+		"-y",              // Override output file
+		"-vcodec", "copy", // copy video
+		"-acodec", "copy", // copy audio
+	)
+	if adts {
+		params = append(params, "-bsf:a", "aac_adtstoasc") // Turn ADTS AAC from MPEG-TS into MP4 AAC
+	}
+	if len(subtitles) > 0 {
+		params = append(params, "-scodec", codec) // Convert subtitles for the container
+	}
+	if strings.ToLower(filepath.Ext(outPath)) == ".mkv" {
+		if len(subtitles) == 0 {
+			params = append(params, "-scodec", "copy") // Keep embedded subtitles
+		}
+		params = append(params, "-f", "matroska")
+	} else {
+		params = append(params,
+			"-movflags", "+faststart", // Index at the beginning of the file
+			"-f", "mp4",
+		)
+	}
+	params = append(params, outPath) // output file
+	return params
+}
+
+// lookFFMpeg checks ffmpeg's presence
+func lookFFMpeg(binary string) (string, error) {
+	p, err := exec.LookPath(binary)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrFFMpegNotFound, err)
+	}
+	return p, nil
+}
@@ -0,0 +1,148 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/simulot/aspiratv/providers"
+)
+
+func TestMuxHLSMissingFFMpeg(t *testing.T) {
+	err := MuxHLS(context.TODO(), "http://example.com/master.m3u8", "out.mp4", Metadata{}, FFMepgWithBinary("ffmpeg-not-installed-here"))
+	if !errors.Is(err, ErrFFMpegNotFound) {
+		t.Errorf("Expecting error %v, got %v", ErrFFMpegNotFound, err)
+	}
+}
+
+func TestMuxParams(t *testing.T) {
+	tests := []struct {
+		name    string
+		out     string
+		adts    bool
+		wantBSF bool
+		format  string
+	}{
+		{"hls mp4", "out.mp4", true, true, "mp4"},
+		{"hls mkv", "out.mkv", true, true, "matroska"},
+		{"dash mp4", "out.mp4", false, false, "mp4"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := strings.Join(muxParams("http://example.com/stream", tt.out, Metadata{}, tt.adts), " ")
+			if got := strings.Contains(params, "-bsf:a aac_adtstoasc"); got != tt.wantBSF {
+				t.Errorf("Expecting ADTS filter to be %v, got %q", tt.wantBSF, params)
+			}
+			if !strings.Contains(params, "-f "+tt.format+" "+tt.out) {
+				t.Errorf("Expecting format %s, got %q", tt.format, params)
+			}
+		})
+	}
+}
+
+func TestMuxParamsSubtitles(t *testing.T) {
+	subs := []providers.SubtitleTrack{
+		{Language: "fr", URL: "http://example.com/fr.vtt", Format: "vtt"},
+		{Language: "en", URL: "http://example.com/en.vtt", Format: "vtt"},
+	}
+	tests := []struct {
+		name  string
+		out   string
+		codec string
+	}{
+		{"mp4", "out.mp4", "mov_text"},
+		{"mkv", "out.mkv", "srt"},
+		{"unsupported", "out.avi", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := strings.Join(muxParams("http://example.com/stream", tt.out, Metadata{Subtitles: subs}, true), " ")
+			if CanEmbedSubtitles(tt.out) != (len(tt.codec) > 0) {
+				t.Errorf("Unexpected CanEmbedSubtitles for %s", tt.out)
+			}
+			if len(tt.codec) == 0 {
+				if strings.Contains(params, "fr.vtt") {
+					t.Errorf("Expecting no subtitles, got %q", params)
+				}
+				return
+			}
+			for _, want := range []string{
+				"-i http://example.com/stream -i http://example.com/fr.vtt -i http://example.com/en.vtt",
+				"-map 0:v? -map 0:a? -map 1 -map 2",
+				"-metadata:s:s:0 language=fre -metadata:s:s:1 language=eng",
+				"-scodec " + tt.codec,
+			} {
+				if !strings.Contains(params, want) {
+					t.Errorf("Expecting %q, got %q", want, params)
+				}
+			}
+		})
+	}
+}
+
+func TestMuxParamsChapters(t *testing.T) {
+	subs := []providers.SubtitleTrack{{Language: "fr", URL: "http://example.com/fr.vtt", Format: "vtt"}}
+	params := strings.Join(muxParams("http://example.com/stream", "out.mp4", Metadata{Subtitles: subs, chaptersFile: "out.mp4.chapters.txt"}, true), " ")
+	want := "-i http://example.com/fr.vtt -i out.mp4.chapters.txt -map_chapters 2"
+	if !strings.Contains(params, want) {
+		t.Errorf("Expecting %q, got %q", want, params)
+	}
+	params = strings.Join(muxParams("http://example.com/stream", "out.mp4", Metadata{}, true), " ")
+	if strings.Contains(params, "-map_chapters") {
+		t.Errorf("Expecting no chapters, got %q", params)
+	}
+}
+
+func TestMuxParamsAudio(t *testing.T) {
+	audio := []providers.AudioVersion{
+		{Language: "fr", Name: "Français", Default: true, URL: "http://example.com/fr.m3u8"},
+		{Language: "fr", Name: "Audiodescription", AudioDescription: true, URL: "http://example.com/ad.m3u8"},
+	}
+	subs := []providers.SubtitleTrack{{Language: "fr", URL: "http://example.com/fr.vtt", Format: "vtt"}}
+	params := strings.Join(muxParams("http://example.com/video.m3u8", "out.mkv", Metadata{Audio: audio, Subtitles: subs, chaptersFile: "out.mkv.chapters.txt"}, true), " ")
+	for _, want := range []string{
+		"-i http://example.com/video.m3u8 -i http://example.com/fr.m3u8 -i http://example.com/ad.m3u8 -i http://example.com/fr.vtt",
+		"-map_chapters 4",
+		"-map 0:v? -map 1:a -map 2:a -map 3 ",
+		"-metadata:s:a:0 language=fre -disposition:a:0 default",
+		"-metadata:s:a:1 language=fre -metadata:s:a:1 title=Audiodescription -disposition:a:1 visual_impaired",
+	} {
+		if !strings.Contains(params, want) {
+			t.Errorf("Expecting %q, got %q", want, params)
+		}
+	}
+	if strings.Contains(params, "0:a?") {
+		t.Errorf("Expecting the stream's audio to be dropped, got %q", params)
+	}
+}
+
+func TestWriteChapters(t *testing.T) {
+	d, err := ioutil.TempDir("", "aspiratv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(d)
+
+	name, err := writeChapters(filepath.Join(d, "out.mp4"), []providers.Chapter{
+		{Title: "Générique de début", Start: 0, End: 45 * time.Second},
+		{Title: "Partie 1; suite = fin", Start: 45 * time.Second, End: 150 * time.Second},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := ";FFMETADATA1\n" +
+		"[CHAPTER]\nTIMEBASE=1/1000\nSTART=0\nEND=45000\ntitle=Générique de début\n" +
+		"[CHAPTER]\nTIMEBASE=1/1000\nSTART=45000\nEND=150000\ntitle=Partie 1\\; suite \\= fin\n"
+	if string(b) != expected {
+		t.Errorf("Expecting:\n%s\ngot:\n%s", expected, b)
+	}
+}
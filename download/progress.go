@@ -0,0 +1,79 @@
+package download
+
+import (
+	"context"
+	"io"
+
+	"github.com/simulot/aspiratv/providers"
+)
+
+// WithReporter notifies the reporter of the start, the bytes received and the end of the download of the media
+func WithReporter(r providers.ProgressReporter, m *providers.Media) configurator {
+	return func(c *config) {
+		c.reporter = r
+		c.media = m
+	}
+}
+
+// FFMepgWithReporter notifies the reporter of the start, the size and the end of the download of the media
+func FFMepgWithReporter(r providers.ProgressReporter, m *providers.Media) ffmpegConfigurator {
+	return func(c *ffmpegConfig) {
+		c.reporter = r
+		c.media = m
+	}
+}
+
+// countingGetter reports the bytes read from the getter's responses
+type countingGetter struct {
+	g Getter
+	r providers.ProgressReporter
+	m *providers.Media
+}
+
+func (g countingGetter) Get(ctx context.Context, uri string) (io.ReadCloser, error) {
+	rc, err := g.g.Get(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{&countingReader{r: rc, g: g}, rc}, nil
+}
+
+type countingReader struct {
+	r io.Reader
+	g countingGetter
+}
+
+func (r *countingReader) Read(b []byte) (int, error) {
+	n, err := r.r.Read(b)
+	if n > 0 {
+		r.g.r.Bytes(r.g.m, int64(n))
+	}
+	return n, err
+}
+
+// reporterProgresser turns ffmpeg's output size into the bytes reported, and forwards the progress to pgr
+type reporterProgresser struct {
+	r    providers.ProgressReporter
+	m    *providers.Media
+	last int64
+	pgr  Progresser
+}
+
+func (p *reporterProgresser) Init(size int64) {
+	if p.pgr != nil {
+		p.pgr.Init(size)
+	}
+}
+
+func (p *reporterProgresser) Update(count int64, size int64) {
+	if count > p.last {
+		p.r.Bytes(p.m, count-p.last)
+		p.last = count
+	}
+	if p.pgr != nil {
+		p.pgr.Update(count, size)
+	}
+}
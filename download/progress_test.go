@@ -0,0 +1,103 @@
+package download
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/simulot/aspiratv/providers"
+)
+
+type testReporter struct {
+	mu      sync.Mutex
+	started int
+	bytes   int64
+	errs    []error
+}
+
+func (r *testReporter) Start(m *providers.Media) {
+	r.mu.Lock()
+	r.started++
+	r.mu.Unlock()
+}
+
+func (r *testReporter) Bytes(m *providers.Media, n int64) {
+	r.mu.Lock()
+	r.bytes += n
+	r.mu.Unlock()
+}
+
+func (r *testReporter) Done(m *providers.Media, err error) {
+	r.mu.Lock()
+	r.errs = append(r.errs, err)
+	r.mu.Unlock()
+}
+
+func TestHTTPReporter(t *testing.T) {
+	body := bytes.Repeat([]byte("A"), 20000)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(body)
+	}))
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "aspiratv-progress-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	r := &testReporter{}
+	m := &providers.Media{ID: "1"}
+	err = HTTP(context.TODO(), ts.URL, filepath.Join(dir, "media.mp4"), WithReporter(r, m))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.started != 1 || len(r.errs) != 1 || r.errs[0] != nil {
+		t.Errorf("Expecting one successful download, got %d started, ends %v", r.started, r.errs)
+	}
+	if r.bytes != int64(len(body)) {
+		t.Errorf("Expecting %d bytes reported, got %d", len(body), r.bytes)
+	}
+
+	err = HTTP(context.TODO(), ts.URL+"/missing", filepath.Join(dir, "missing.mp4"), WithReporter(r, m))
+	if err == nil {
+		t.Fatal("Expecting an error")
+	}
+	if len(r.errs) != 2 || r.errs[1] == nil {
+		t.Errorf("Expecting the error to be reported, got %v", r.errs)
+	}
+}
+
+func TestHLSReporter(t *testing.T) {
+	ts, expected := newHLSServer(10)
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "aspiratv-progress-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	r := &testReporter{}
+	err = HLS(context.TODO(), ts.URL+"/master.m3u8", filepath.Join(dir, "media.ts"), WithWorkers(4), WithReporter(r, &providers.Media{ID: "1"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.started != 1 || len(r.errs) != 1 || r.errs[0] != nil {
+		t.Errorf("Expecting one successful download, got %d started, ends %v", r.started, r.errs)
+	}
+	// Playlists are counted too
+	if r.bytes < int64(len(expected)) {
+		t.Errorf("Expecting at least %d bytes reported, got %d", len(expected), r.bytes)
+	}
+}
@@ -0,0 +1,279 @@
+package download
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// partManifest records segments already downloaded into the parts directory
+type partManifest struct {
+	Segments map[int]segmentEntry `json:"segments"` // Completed segments by playlist index
+}
+
+type segmentEntry struct {
+	URL  string `json:"url"`  // Segment URL, without query
+	Size int64  `json:"size"` // Size of the segment file
+}
+
+const manifestName = "manifest.json"
+
+// segmentedHLS downloads segments with cfg.workers workers. Each segment is written into
+// its own file in the directory dest.parts, then segments are concatenated in playlist order.
+// The directory is kept when the download fails, to be resumed later.
+func segmentedHLS(ctx context.Context, cfg config, segments []string, dest string) error {
+	partDir := partsDir(cfg.storage, dest)
+	err := os.MkdirAll(partDir, 0777)
+	if err != nil {
+		return fmt.Errorf("Can't create segments directory: %w", err)
+	}
+
+	err = downloadSegments(ctx, cfg, segments, partDir)
+	if err != nil {
+		return err
+	}
+	h := sha1.New()
+	size := int64(0)
+	err = writePart(cfg.storage, dest, func(w io.Writer) error {
+		w = io.MultiWriter(w, h)
+		for i := range segments {
+			n, err := appendFile(w, segmentPath(partDir, i))
+			if err != nil {
+				return fmt.Errorf("Can't assemble segment %d: %w", i, err)
+			}
+			size += n
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	log.Printf("[HLS] %q completed: %d segments, %d bytes, sha1 %x", filepath.Base(dest), len(segments), size, h.Sum(nil))
+	return os.RemoveAll(partDir)
+}
+
+// downloadSegments fetches the segments missing from partDir concurrently.
+// The first error cancels the remaining downloads. When ctx is cancelled, segments being downloaded
+// are given cfg.grace to complete.
+func downloadSegments(ctx context.Context, cfg config, segments []string, partDir string) error {
+	workCtx, stopFeed := context.WithCancel(ctx)
+	fetchCtx, stopFetch := workCtx, stopFeed
+	if cfg.grace > 0 {
+		fetchCtx, stopFetch = gracefulContext(ctx, cfg.grace)
+	}
+	cancel := func() {
+		stopFeed()
+		stopFetch()
+	}
+	defer cancel()
+
+	workers := cfg.workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	manifest := loadManifest(partDir)
+	jobs := make(chan int)
+	errc := make(chan error, 1)
+	wg := sync.WaitGroup{}
+	mu := sync.Mutex{}
+	completed := int64(0)
+
+	done := func(i int, size int64) error {
+		mu.Lock()
+		defer mu.Unlock()
+		completed++
+		if cfg.progress != nil {
+			cfg.progress(completed, int64(len(segments)))
+		}
+		if size < 0 {
+			return nil
+		}
+		manifest.Segments[i] = segmentEntry{URL: segmentKey(segments[i]), Size: size}
+		return manifest.save(partDir)
+	}
+
+	// Segments to be downloaded, checked before workers start updating the manifest
+	pending := []int{}
+	for i := range segments {
+		if manifest.isComplete(partDir, i, segments[i]) {
+			done(i, -1)
+			continue
+		}
+		pending = append(pending, i)
+	}
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if workCtx.Err() != nil {
+					continue // Stopping, the segment is left for later
+				}
+				size, err := fetchSegmentWithRetry(fetchCtx, cfg, i, segments[i], segmentPath(partDir, i))
+				if err == nil {
+					err = done(i, size)
+				}
+				if err != nil {
+					select {
+					case errc <- fmt.Errorf("Can't download segment %d: %w", i, err):
+					default:
+					}
+					cancel()
+					return
+				}
+			}
+		}()
+	}
+
+feedLoop:
+	for _, i := range pending {
+		select {
+		case jobs <- i:
+		case <-workCtx.Done():
+			break feedLoop
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	select {
+	case err := <-errc:
+		return err
+	default:
+	}
+	return nil
+}
+
+func loadManifest(partDir string) *partManifest {
+	m := &partManifest{}
+	b, err := ioutil.ReadFile(filepath.Join(partDir, manifestName))
+	if err == nil {
+		json.Unmarshal(b, m)
+	}
+	if m.Segments == nil {
+		m.Segments = map[int]segmentEntry{}
+	}
+	return m
+}
+
+func (m *partManifest) save(partDir string) error {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(partDir, manifestName+".tmp")
+	err = ioutil.WriteFile(tmp, b, 0666)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(partDir, manifestName))
+}
+
+// isComplete tells if the segment is recorded into the manifest, and its file has the recorded size
+func (m *partManifest) isComplete(partDir string, i int, u string) bool {
+	e, ok := m.Segments[i]
+	if !ok || e.URL != segmentKey(u) {
+		return false
+	}
+	fi, err := os.Stat(segmentPath(partDir, i))
+	return err == nil && fi.Size() == e.Size
+}
+
+// segmentKey removes the query from the segment URL, as it often carries a token renewed at each playlist download
+func segmentKey(u string) string {
+	pu, err := url.Parse(u)
+	if err != nil {
+		return u
+	}
+	pu.RawQuery = ""
+	return pu.String()
+}
+
+// partsDir gives the local directory of segments. It's next to the destination file on the local storage,
+// and into the temporary directory for other storages.
+func partsDir(s Storage, dest string) string {
+	if l, ok := s.(LocalStorage); ok {
+		return l.path(dest) + ".parts"
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("aspiratv-%x.parts", sha1.Sum([]byte(dest))))
+}
+
+func segmentPath(partDir string, i int) string {
+	return filepath.Join(partDir, fmt.Sprintf("segment-%06d.ts", i))
+}
+
+// fetchSegmentWithRetry fetches the segment within cfg.segmentTimeout, and tries again when it times out.
+// An incomplete segment is tried again at least once.
+func fetchSegmentWithRetry(ctx context.Context, cfg config, i int, u string, segPath string) (int64, error) {
+	for attempt := 0; ; attempt++ {
+		segCtx, cancel := ctx, context.CancelFunc(func() {})
+		if cfg.segmentTimeout > 0 {
+			segCtx, cancel = context.WithTimeout(ctx, cfg.segmentTimeout)
+		}
+		size, err := fetchSegment(segCtx, cfg.getter, u, segPath)
+		timedOut := cfg.segmentTimeout > 0 && err != nil && segCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil
+		cancel()
+		incomplete := errors.Is(err, ErrIncomplete) && ctx.Err() == nil
+		if !timedOut && !incomplete {
+			return size, err
+		}
+		retries := cfg.segmentRetries
+		if incomplete && retries < 1 {
+			retries = 1
+		}
+		if attempt >= retries {
+			if incomplete {
+				return 0, fmt.Errorf("segment %d not completed after %d attempts: %w", i, attempt+1, err)
+			}
+			return 0, fmt.Errorf("%w: segment %d %q not completed after %d attempts of %s", ErrTimeout, i, u, attempt+1, cfg.segmentTimeout)
+		}
+		if cfg.debug {
+			log.Printf("[HLS] Segment %d will be downloaded again: %s", i, err)
+		}
+	}
+}
+
+// fetchSegment writes the segment into a temporary file renamed when complete, and returns its size
+func fetchSegment(ctx context.Context, g Getter, u string, segPath string) (int64, error) {
+	tmp := segPath + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return 0, err
+	}
+	err = copySegment(ctx, g, u, f)
+	var size int64
+	if err == nil {
+		size, err = f.Seek(0, io.SeekCurrent)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return 0, err
+	}
+	return size, os.Rename(tmp, segPath)
+}
+
+func appendFile(w io.Writer, name string) (int64, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	return io.Copy(w, f)
+}
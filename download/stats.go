@@ -0,0 +1,160 @@
+package download
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/simulot/aspiratv/providers"
+)
+
+// ProviderStats are the counters of a batch of downloads
+type ProviderStats struct {
+	Matched    int   // Media matching the watch list
+	Downloaded int   // Media successfully downloaded
+	Skipped    int   // Media not downloaded because already present
+	Failed     int   // Media whose download failed
+	Bytes      int64 // Downloaded bytes, including failed downloads
+}
+
+func (p *ProviderStats) add(o ProviderStats) {
+	p.Matched += o.Matched
+	p.Downloaded += o.Downloaded
+	p.Skipped += o.Skipped
+	p.Failed += o.Failed
+	p.Bytes += o.Bytes
+}
+
+// Stats accumulates the counters of a batch of downloads by provider. It's a ProgressReporter
+// counting the bytes and the outcome of downloads: a media downloaded again after a failure
+// is counted once, with its last outcome. Its methods can be called concurrently.
+type Stats struct {
+	mu       sync.Mutex
+	counters map[string]*ProviderStats
+	outcomes map[*providers.Media]error
+}
+
+// NewStats creates empty statistics
+func NewStats() *Stats {
+	return &Stats{
+		counters: map[string]*ProviderStats{},
+		outcomes: map[*providers.Media]error{},
+	}
+}
+
+func providerOf(m *providers.Media) string {
+	if m == nil || m.Match == nil {
+		return ""
+	}
+	return m.Match.Provider
+}
+
+func (s *Stats) counter(m *providers.Media) *ProviderStats {
+	p := providerOf(m)
+	c, ok := s.counters[p]
+	if !ok {
+		c = &ProviderStats{}
+		s.counters[p] = c
+	}
+	return c
+}
+
+// Matched counts a media matching the watch list
+func (s *Stats) Matched(m *providers.Media) {
+	s.mu.Lock()
+	s.counter(m).Matched++
+	s.mu.Unlock()
+}
+
+// Skipped counts a media that isn't downloaded because already present
+func (s *Stats) Skipped(m *providers.Media) {
+	s.mu.Lock()
+	s.counter(m).Skipped++
+	s.mu.Unlock()
+}
+
+// Start implements providers.ProgressReporter
+func (s *Stats) Start(m *providers.Media) {}
+
+// Bytes implements providers.ProgressReporter
+func (s *Stats) Bytes(m *providers.Media, n int64) {
+	s.mu.Lock()
+	s.counter(m).Bytes += n
+	s.mu.Unlock()
+}
+
+// Done implements providers.ProgressReporter
+func (s *Stats) Done(m *providers.Media, err error) {
+	s.mu.Lock()
+	s.counter(m)
+	s.outcomes[m] = err
+	s.mu.Unlock()
+}
+
+// Providers gives the counters of each provider. Media without matched request are counted under the empty name.
+func (s *Stats) Providers() map[string]ProviderStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	l := make(map[string]ProviderStats, len(s.counters))
+	for p, c := range s.counters {
+		l[p] = *c
+	}
+	for m, err := range s.outcomes {
+		c := l[providerOf(m)]
+		if err != nil {
+			c.Failed++
+		} else {
+			c.Downloaded++
+		}
+		l[providerOf(m)] = c
+	}
+	return l
+}
+
+// Total gives the counters of all providers
+func (s *Stats) Total() ProviderStats {
+	t := ProviderStats{}
+	for _, c := range s.Providers() {
+		t.add(c)
+	}
+	return t
+}
+
+// WriteSummary writes a line for each provider, sorted by name, followed by the total
+func (s *Stats) WriteSummary(w io.Writer) error {
+	l := s.Providers()
+	names := make([]string, 0, len(l))
+	for p := range l {
+		names = append(names, p)
+	}
+	sort.Strings(names)
+	t := ProviderStats{}
+	for _, p := range names {
+		c := l[p]
+		t.add(c)
+		if len(p) == 0 {
+			p = "other"
+		}
+		if _, err := fmt.Fprintf(w, "%s: %s\n", p, c); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "total: %s\n", t)
+	return err
+}
+
+func (p ProviderStats) String() string {
+	return fmt.Sprintf("%d matched, %d downloaded, %d skipped, %d failed, %.1f MB",
+		p.Matched, p.Downloaded, p.Skipped, p.Failed, float64(p.Bytes)/(1024*1024))
+}
+
+// withStats adds the statistics to the reporter of the download
+func withStats(s *Stats, m *providers.Media) configurator {
+	return func(c *config) {
+		c.reporter = providers.MultiReporter(c.reporter, s)
+		if c.media == nil {
+			c.media = m
+		}
+	}
+}
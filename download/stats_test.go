@@ -0,0 +1,91 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/simulot/aspiratv/providers"
+)
+
+func TestStats(t *testing.T) {
+	media := func(provider, id string) *providers.Media {
+		return &providers.Media{ID: id, Match: &providers.MatchRequest{Provider: provider}}
+	}
+	s := NewStats()
+	ok, retried, failed, present := media("francetv", "1"), media("francetv", "2"), media("artetv", "3"), media("artetv", "4")
+	for _, m := range []*providers.Media{ok, retried, failed, present} {
+		s.Matched(m)
+	}
+	s.Skipped(present)
+
+	s.Bytes(ok, 1024*1024)
+	s.Done(ok, nil)
+	s.Bytes(retried, 100)
+	s.Done(retried, errors.New("url expired"))
+	s.Bytes(retried, 200)
+	s.Done(retried, nil)
+	s.Done(failed, errors.New("network error"))
+
+	l := s.Providers()
+	if got, want := l["francetv"], (ProviderStats{Matched: 2, Downloaded: 2, Bytes: 1024*1024 + 300}); got != want {
+		t.Errorf("francetv stats = %+v, want %+v", got, want)
+	}
+	if got, want := l["artetv"], (ProviderStats{Matched: 2, Skipped: 1, Failed: 1}); got != want {
+		t.Errorf("artetv stats = %+v, want %+v", got, want)
+	}
+	if got, want := s.Total(), (ProviderStats{Matched: 4, Downloaded: 2, Skipped: 1, Failed: 1, Bytes: 1024*1024 + 300}); got != want {
+		t.Errorf("Total() = %+v, want %+v", got, want)
+	}
+
+	b := strings.Builder{}
+	if err := s.WriteSummary(&b); err != nil {
+		t.Fatal(err)
+	}
+	want := "artetv: 2 matched, 0 downloaded, 1 skipped, 1 failed, 0.0 MB\n" +
+		"francetv: 2 matched, 2 downloaded, 0 skipped, 0 failed, 1.0 MB\n" +
+		"total: 4 matched, 2 downloaded, 1 skipped, 1 failed, 1.0 MB\n"
+	if b.String() != want {
+		t.Errorf("WriteSummary() =\n%s\nwant\n%s", b.String(), want)
+	}
+}
+
+func TestEngineStats(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, strings.Repeat("x", 100))
+	}))
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "aspiratv-engine-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	e := NewEngine(context.Background(), 2)
+	for i, path := range []string{"/1", "/2", "/missing"} {
+		m := &providers.Media{ID: path, Match: &providers.MatchRequest{Provider: "gulli"}}
+		err := e.Submit(&Job{Media: m, Type: JobHTTP, URL: ts.URL + path, Dest: filepath.Join(dir, fmt.Sprintf("%d.mp4", i))})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	e.Close()
+	for range e.Results() {
+	}
+
+	if got, want := e.Stats().Providers()["gulli"], (ProviderStats{Downloaded: 2, Failed: 1, Bytes: 200}); got != want {
+		t.Errorf("Engine stats = %+v, want %+v", got, want)
+	}
+}
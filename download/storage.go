@@ -0,0 +1,121 @@
+package download
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"syscall"
+)
+
+// Storage is where downloaded files are written. Paths are logical paths, like those given by GetMediaPath;
+// the storage decides where the files physically live.
+// Segments being downloaded are still kept on the local disk, only complete files go to the storage.
+type Storage interface {
+	Create(path string) (io.WriteCloser, error) // Create or truncate the file
+	Exists(path string) (bool, error)
+	Rename(oldPath, newPath string) error
+	Remove(path string) error
+}
+
+// LocalStorage writes the files on the local file system. It's the default storage.
+type LocalStorage struct {
+	Root string // Directory prepended to relative paths, current directory when empty
+}
+
+func (s LocalStorage) path(p string) string {
+	if len(s.Root) == 0 || filepath.IsAbs(p) {
+		return p
+	}
+	return filepath.Join(s.Root, p)
+}
+
+// Create creates the file and its directory
+func (s LocalStorage) Create(path string) (io.WriteCloser, error) {
+	path = s.path(path)
+	err := os.MkdirAll(filepath.Dir(path), 0777)
+	if err != nil {
+		return nil, err
+	}
+	return os.Create(path)
+}
+
+// Exists tells if the file exists
+func (s LocalStorage) Exists(path string) (bool, error) {
+	_, err := os.Stat(s.path(path))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Rename renames the file. When both paths aren't on the same file system, the file is copied then removed.
+func (s LocalStorage) Rename(oldPath, newPath string) error {
+	oldPath, newPath = s.path(oldPath), s.path(newPath)
+	err := os.Rename(oldPath, newPath)
+	if isCrossDevice(err) {
+		return moveFile(oldPath, newPath)
+	}
+	return err
+}
+
+// isCrossDevice tells if the error is given by a rename between two file systems
+func isCrossDevice(err error) bool {
+	var le *os.LinkError
+	if !errors.As(err, &le) {
+		return false
+	}
+	errno, ok := le.Err.(syscall.Errno)
+	if !ok {
+		return false
+	}
+	return errno == syscall.EXDEV || (runtime.GOOS == "windows" && errno == errorNotSameDevice)
+}
+
+// errorNotSameDevice is the windows ERROR_NOT_SAME_DEVICE error
+const errorNotSameDevice = 17
+
+// moveFile copies oldPath into a temporary file next to newPath, synced then renamed into newPath,
+// so newPath never holds a partial file. oldPath is removed once newPath is complete.
+func moveFile(oldPath, newPath string) error {
+	src, err := os.Open(oldPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	tmp, err := ioutil.TempFile(filepath.Dir(newPath), filepath.Base(newPath)+".*")
+	if err != nil {
+		return fmt.Errorf("Can't move file: %w", err)
+	}
+	_, err = io.Copy(tmp, src)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), newPath)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("Can't move file: %w", err)
+	}
+	src.Close()
+	return os.Remove(oldPath)
+}
+
+// Remove removes the file
+func (s LocalStorage) Remove(path string) error {
+	return os.Remove(s.path(path))
+}
+
+// WithStorage set the storage receiving the downloaded file, the local file system by default
+func WithStorage(s Storage) configurator {
+	return func(c *config) {
+		c.storage = s
+	}
+}
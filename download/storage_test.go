@@ -0,0 +1,162 @@
+package download
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"testing"
+)
+
+// memStorage keeps files in memory
+type memStorage struct {
+	mu    sync.Mutex
+	files map[string][]byte
+}
+
+type memFile struct {
+	bytes.Buffer
+	s    *memStorage
+	path string
+}
+
+func (f *memFile) Close() error {
+	f.s.mu.Lock()
+	defer f.s.mu.Unlock()
+	f.s.files[f.path] = f.Bytes()
+	return nil
+}
+
+func (s *memStorage) Create(path string) (io.WriteCloser, error) {
+	return &memFile{s: s, path: path}, nil
+}
+
+func (s *memStorage) Exists(path string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.files[path]
+	return ok, nil
+}
+
+func (s *memStorage) Rename(oldPath, newPath string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.files[oldPath]
+	if !ok {
+		return os.ErrNotExist
+	}
+	delete(s.files, oldPath)
+	s.files[newPath] = b
+	return nil
+}
+
+func (s *memStorage) Remove(path string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.files, path)
+	return nil
+}
+
+func TestStorage(t *testing.T) {
+	ts, expected := newHLSServer(10)
+	defer ts.Close()
+	body := bytes.Repeat([]byte("A"), 2000)
+	tsHTTP := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(body)
+	}))
+	defer tsHTTP.Close()
+
+	s := &memStorage{files: map[string][]byte{}}
+	err := HLS(context.TODO(), ts.URL+"/master.m3u8", "Show/Season 01/episode.ts", WithStorage(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = HTTP(context.TODO(), tsHTTP.URL, "Movie/movie.mp4", WithStorage(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.files) != 2 {
+		t.Errorf("Expecting 2 files in the storage, got %d", len(s.files))
+	}
+	if string(s.files["Show/Season 01/episode.ts"]) != expected {
+		t.Errorf("HLS content differs from expected")
+	}
+	if !bytes.Equal(s.files["Movie/movie.mp4"], body) {
+		t.Errorf("HTTP content differs from expected")
+	}
+	if _, err := os.Stat(partsDir(s, "Show/Season 01/episode.ts")); !os.IsNotExist(err) {
+		t.Errorf("Expecting segments to be removed, got %v", err)
+	}
+}
+
+func TestLocalStorage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aspiratv-storage-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := LocalStorage{Root: dir}
+	w, err := s.Create("show/media.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("media"))
+	w.Close()
+	if ok, err := s.Exists("show/media.mp4"); !ok || err != nil {
+		t.Errorf("Expecting file to exist, got %v, %v", ok, err)
+	}
+	if ok, err := s.Exists("show/other.mp4"); ok || err != nil {
+		t.Errorf("Expecting file not to exist, got %v, %v", ok, err)
+	}
+	if err := s.Rename("show/media.mp4", "show/renamed.mp4"); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := ioutil.ReadFile(filepath.Join(dir, "show", "renamed.mp4")); string(b) != "media" {
+		t.Errorf("Expecting the file under the root directory, got %q", b)
+	}
+}
+
+func TestMoveFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aspiratv-storage-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "media.mp4.part")
+	dst := filepath.Join(dir, "media.mp4")
+	if err := ioutil.WriteFile(src, []byte("media"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if err := moveFile(src, dst); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := ioutil.ReadFile(dst); string(b) != "media" {
+		t.Errorf("Expecting the moved content, got %q", b)
+	}
+	if _, err := os.Stat(src); !os.IsNotExist(err) {
+		t.Errorf("Expecting the source to be removed, got %v", err)
+	}
+	if files, _ := filepath.Glob(filepath.Join(dir, "*")); len(files) != 1 {
+		t.Errorf("Expecting no temporary file left, got %v", files)
+	}
+}
+
+func TestIsCrossDevice(t *testing.T) {
+	if !isCrossDevice(&os.LinkError{Op: "rename", Old: "a", New: "b", Err: syscall.EXDEV}) {
+		t.Errorf("Expecting EXDEV to be a cross device error")
+	}
+	if isCrossDevice(&os.LinkError{Op: "rename", Old: "a", New: "b", Err: syscall.ENOENT}) {
+		t.Errorf("Expecting ENOENT not to be a cross device error")
+	}
+	if isCrossDevice(nil) {
+		t.Errorf("Expecting nil not to be a cross device error")
+	}
+}
@@ -0,0 +1,52 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/simulot/aspiratv/net/myhttp"
+)
+
+// ErrIncomplete is returned when a response is shorter than its announced length
+var ErrIncomplete = errors.New("incomplete download")
+
+// checkedGetter verifies the size of the responses against their Content-Length, when known
+type checkedGetter struct {
+	g Getter
+}
+
+func (g checkedGetter) Get(ctx context.Context, uri string) (io.ReadCloser, error) {
+	r, err := g.g.Get(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+	expected := int64(-1)
+	if cl, ok := r.(myhttp.ContentLengther); ok {
+		expected = cl.ContentLength()
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{&checkedReader{r: r, uri: uri, expected: expected}, r}, nil
+}
+
+type checkedReader struct {
+	r        io.Reader
+	uri      string
+	expected int64 // -1 when unknown
+	read     int64
+}
+
+func (r *checkedReader) Read(b []byte) (int, error) {
+	n, err := r.r.Read(b)
+	r.read += int64(n)
+	switch {
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		err = fmt.Errorf("%w: %q interrupted after %d bytes", ErrIncomplete, r.uri, r.read)
+	case err == io.EOF && r.expected >= 0 && r.read != r.expected:
+		err = fmt.Errorf("%w: %q got %d bytes, expecting %d", ErrIncomplete, r.uri, r.read, r.expected)
+	}
+	return n, err
+}
@@ -0,0 +1,100 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// sizedGetter gives a body with a wrong announced length
+type sizedGetter struct {
+	body   string
+	length int64
+}
+
+type sizedBody struct {
+	io.ReadCloser
+	length int64
+}
+
+func (b sizedBody) ContentLength() int64 { return b.length }
+
+func (g sizedGetter) Get(ctx context.Context, uri string) (io.ReadCloser, error) {
+	return sizedBody{ioutil.NopCloser(strings.NewReader(g.body)), g.length}, nil
+}
+
+func TestCheckedGetter(t *testing.T) {
+	tests := []struct {
+		name   string
+		length int64
+		want   error
+	}{
+		{"expected length", 5, nil},
+		{"unknown length", -1, nil},
+		{"short body", 10, ErrIncomplete},
+		{"long body", 2, ErrIncomplete},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := checkedGetter{g: sizedGetter{body: "hello", length: tt.length}}.Get(context.TODO(), "seg.ts")
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = ioutil.ReadAll(r)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Expecting error %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestHLSTruncatedSegment(t *testing.T) {
+	segments := []string{strings.Repeat("A", 100), strings.Repeat("B", 100)}
+	calls := int32(0)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/playlist.m3u8", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "#EXTM3U\n#EXTINF:10.0,\nseg-0.ts\n#EXTINF:10.0,\nseg-1.ts\n#EXT-X-ENDLIST\n")
+	})
+	mux.HandleFunc("/seg-0.ts", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, segments[0])
+	})
+	mux.HandleFunc("/seg-1.ts", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "100")
+		if atomic.AddInt32(&calls, 1) == 1 {
+			// The server announces the whole segment but sends only half of it
+			fmt.Fprint(w, segments[1][:50])
+			return
+		}
+		fmt.Fprint(w, segments[1])
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "aspiratv-hls-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	dest := filepath.Join(dir, "media.ts")
+
+	err = HLS(context.TODO(), ts.URL+"/playlist.m3u8", dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 {
+		t.Errorf("Expecting the truncated segment to be downloaded again, got %d calls", calls)
+	}
+	b, _ := ioutil.ReadFile(dest)
+	if string(b) != segments[0]+segments[1] {
+		t.Errorf("Downloaded content differs from expected")
+	}
+}
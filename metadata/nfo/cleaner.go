@@ -0,0 +1,153 @@
+package nfo
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// CleanerConfig gives the rules used to make a name safe for the file system
+type CleanerConfig struct {
+	Replacements map[string]string // Strings replaced first, like ":" by "-"
+	Forbidden    string            // Other forbidden characters. Control characters are always forbidden
+	Replacement  string            // Character replacing forbidden characters, they are removed when empty
+	MaxLength    int               // Maximum length in bytes of a path component, the extension is kept. No limit when 0
+	TrimDots     bool              // Remove trailing dots of path components
+	ASCII        bool              // Names are transliterated, see Transliterate. Other non ASCII characters are forbidden
+}
+
+// Cleaners rules matching the historical behavior
+var (
+	DefaultFileCleanerConfig = CleanerConfig{
+		Replacements: map[string]string{"/": "-", "\\": "-", "!": "", "?": "", ":": "", "*": "-", "|": "-", "\"": "", ">": "", "<": ""},
+	}
+	DefaultPathCleanerConfig = CleanerConfig{
+		Replacements: map[string]string{"!": "", "?": "", ":": " ", ",": "", "*": "", "|": " ", "\"": "", ">": "", "<": ""},
+	}
+)
+
+// Cleaners rules for file systems used by Windows (NTFS, exFAT, FAT32)
+var (
+	WindowsFileCleanerConfig = CleanerConfig{
+		Replacements: map[string]string{"/": "-", "\\": "-", "?": "", ":": " -", "\"": "'"},
+		Forbidden:    "*|<>",
+		Replacement:  "_",
+		MaxLength:    255,
+		TrimDots:     true,
+	}
+	WindowsPathCleanerConfig = CleanerConfig{
+		Replacements: map[string]string{"?": "", ":": " -", "\"": "'"},
+		Forbidden:    "*|<>",
+		Replacement:  "_",
+		MaxLength:    255,
+		TrimDots:     true,
+	}
+)
+
+// Cleaner makes names safe for the file system
+type Cleaner struct {
+	cfg      CleanerConfig
+	replacer *strings.Replacer
+}
+
+// NewCleaner creates a cleaner applying the rules of cfg
+func NewCleaner(cfg CleanerConfig) *Cleaner {
+	// The longest strings are replaced first, and the order must not depend on the map
+	olds := make([]string, 0, len(cfg.Replacements))
+	for o := range cfg.Replacements {
+		olds = append(olds, o)
+	}
+	sort.Slice(olds, func(i, j int) bool {
+		if len(olds[i]) != len(olds[j]) {
+			return len(olds[i]) > len(olds[j])
+		}
+		return olds[i] < olds[j]
+	})
+	pairs := []string{}
+	for _, o := range olds {
+		pairs = append(pairs, o, cfg.Replacements[o])
+	}
+	return &Cleaner{
+		cfg:      cfg,
+		replacer: strings.NewReplacer(pairs...),
+	}
+}
+
+// Cleaners used by FileNameCleaner and PathNameCleaner
+var (
+	FileCleaner = NewCleaner(DefaultFileCleanerConfig)
+	PathCleaner = NewCleaner(DefaultPathCleanerConfig)
+)
+
+// Clean returns a safe file name
+func (c *Cleaner) Clean(s string) string {
+	return c.component(c.replace(s))
+}
+
+// CleanPath returns a safe path. Path separators and the volume name are kept.
+func (c *Cleaner) CleanPath(s string) string {
+	volume := ""
+	if i := strings.Index(s, ":"); i >= 0 && i < 2 {
+		volume, s = s[:i+1], s[i+1:]
+	}
+	s = c.replace(s)
+	parts := strings.FieldsFunc(s, isSeparator)
+	separators := strings.FieldsFunc(s, func(r rune) bool { return !isSeparator(r) })
+	b := strings.Builder{}
+	b.WriteString(volume)
+	if len(s) > 0 && isSeparator(rune(s[0])) {
+		b.WriteString(separators[0])
+		separators = separators[1:]
+	}
+	for i, p := range parts {
+		b.WriteString(c.component(p))
+		if i < len(separators) {
+			b.WriteString(separators[i])
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+func isSeparator(r rune) bool {
+	return r == '/' || r == '\\'
+}
+
+func (c *Cleaner) replace(s string) string {
+	if c.cfg.ASCII {
+		s = Transliterate(s)
+	}
+	s = c.replacer.Replace(s)
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || strings.ContainsRune(c.cfg.Forbidden, r) || (c.cfg.ASCII && r > unicode.MaxASCII) {
+			if len(c.cfg.Replacement) == 0 {
+				return -1
+			}
+			r, _ = utf8.DecodeRuneInString(c.cfg.Replacement)
+		}
+		return r
+	}, s)
+}
+
+// component trims and truncates a path component
+func (c *Cleaner) component(s string) string {
+	s = strings.TrimSpace(s)
+	if c.cfg.MaxLength > 0 && len(s) > c.cfg.MaxLength {
+		ext := filepath.Ext(s)
+		if len(ext) > 10 || strings.ContainsRune(ext, ' ') || len(ext) >= c.cfg.MaxLength {
+			ext = ""
+		}
+		base := s[:len(s)-len(ext)]
+		max := c.cfg.MaxLength - len(ext)
+		for len(base) > max {
+			_, size := utf8.DecodeLastRuneInString(base)
+			base = base[:len(base)-size]
+		}
+		s = strings.TrimSpace(base) + ext
+	}
+	if c.cfg.TrimDots {
+		s = strings.TrimRight(s, ". ")
+	}
+	return s
+}
@@ -0,0 +1,44 @@
+package nfo
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCleaner(t *testing.T) {
+	long := strings.Repeat("é", 200) + ".mp4"
+	tests := []struct {
+		name string
+		cfg  CleanerConfig
+		path bool
+		in   string
+		want string
+	}{
+		{"default file", DefaultFileCleanerConfig, false, "Star Wars: Qui est-ce? 1/2", "Star Wars Qui est-ce 1-2"},
+		{"default path", DefaultPathCleanerConfig, true, "/videos/Star Wars: l'épisode?/", "/videos/Star Wars  l'épisode/"},
+		{"default path volume", DefaultPathCleanerConfig, true, `C:\videos\a|b`, `C:\videos\a b`},
+		{"control characters", DefaultFileCleanerConfig, false, "Titre\navec\tsaut", "Titreavecsaut"},
+		{"windows file", WindowsFileCleanerConfig, false, `Star Wars: "Qui" est-ce? a*b<c>.`, "Star Wars - 'Qui' est-ce a_b_c_"},
+		{"windows trailing dots", WindowsFileCleanerConfig, false, "A suivre...", "A suivre"},
+		{"windows path", WindowsPathCleanerConfig, true, `D:\séries\Star Wars: a|b...\ep.mp4`, `D:\séries\Star Wars - a_b\ep.mp4`},
+		{"truncated, extension kept", CleanerConfig{MaxLength: 100}, false, long, strings.Repeat("é", 48) + ".mp4"},
+		{"truncated path component", CleanerConfig{MaxLength: 10}, true, "/videos/A very long name/ep.mp4", "/videos/A very lon/ep.mp4"},
+		{"accents kept", DefaultFileCleanerConfig, false, "Cœur brisé", "Cœur brisé"},
+		{"ascii file", CleanerConfig{ASCII: true, Replacement: "_"}, false, "Cœur brisé – l’été 日本", "Coeur brise - l'ete __"},
+		{"ascii path", CleanerConfig{ASCII: true}, true, "/séries/Œdipe/ép.mp4", "/series/OEdipe/ep.mp4"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCleaner(tt.cfg)
+			got := ""
+			if tt.path {
+				got = c.CleanPath(tt.in)
+			} else {
+				got = c.Clean(tt.in)
+			}
+			if got != tt.want {
+				t.Errorf("Expecting %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
@@ -38,28 +38,43 @@ func (n *EpisodeDetails) GetSeasonPath(destination string) string {
 
 // GetMediaPath gives the full filename of given media
 func (n EpisodeDetails) GetMediaPath(destination string) string {
-	cleanTitle := FileNameCleaner(n.Title)
-	cleanShow := FileNameCleaner(n.Showtitle)
-	var episode string
-	if n.Episode > 0 {
-		episode = fmt.Sprintf("s%02de%02d", n.Season, n.Episode)
-	} else {
-		episode = n.Aired.Time().Format("2006-01-02")
-	}
-	if cleanTitle == "" {
-		return filepath.Join(n.GetSeasonPath(destination), cleanShow+" - "+episode+".mp4")
-
+	if n.Namer != nil {
+		if p, err := n.Namer.Path(destination, &n.MediaInfo); err == nil {
+			return p
+		}
 	}
-
-	return filepath.Join(n.GetSeasonPath(destination), cleanShow+" - "+episode+" - "+cleanTitle+".mp4")
+	p, _ := DefaultSeriesNamer.Path(destination, &n.MediaInfo)
+	return p
 }
 
-// GetMediaPathMatcher gives a name matcher for mis numbered episodes
+// GetMediaPathMatcher gives a name matcher for mis numbered episodes.
+// Episodes without number are matched by their aired date, with or without the time and the id
+// of the broadcast, so files named before the aired tag had them are recognised.
 func (n EpisodeDetails) GetMediaPathMatcher(destination string) string {
-	cleanTitle := FileNameCleaner(n.Title)
+	namer := n.Namer
+	if namer == nil {
+		namer = DefaultSeriesNamer
+	}
+	if namer != plexSeriesNamer {
+		// Custom layouts can't be matched
+		return n.GetMediaPath(destination)
+	}
 	cleanShow := FileNameCleaner(n.Showtitle)
-	return filepath.Join(n.GetSeriesPath(destination), "*", cleanShow+" - * - "+cleanTitle+".mp4")
-
+	suffix := ""
+	if title := FileNameCleaner(n.Title); len(title) > 0 {
+		suffix = " - " + title
+	}
+	if sub := FileNameCleaner(n.SubChannel); len(sub) > 0 {
+		suffix += " (" + sub + ")"
+	}
+	if n.Episode == 0 && !n.Aired.Time().IsZero() {
+		return filepath.Join(n.GetSeriesPath(destination), "*", cleanShow+" - "+n.Aired.Time().Format("2006-01-02")+"*"+suffix+n.Ext())
+	}
+	if len(suffix) == 0 {
+		// Nothing tells the episode apart from the others
+		return n.GetMediaPath(destination)
+	}
+	return filepath.Join(n.GetSeriesPath(destination), "*", cleanShow+" - *"+suffix+n.Ext())
 }
 
 // GetNFOPath give the path where the episode's NFO should be
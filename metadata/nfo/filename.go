@@ -1,31 +1,27 @@
 package nfo
 
 import (
-	"strings"
+	"fmt"
+	"strconv"
 )
 
-var (
-	fileNameReplacer = strings.NewReplacer("/", "-", "\\", "-", "!", "", "?", "", ":", "", "*", "-", "|", "-", "\"", "", ">", "", "<", "")
-	pathNameReplacer = strings.NewReplacer("!", "", "?", "", ":", " ", ",", "", "*", "", "|", " ", "\"", "", ">", "", "<", "")
-)
-
-// FileNameCleaner return a safe file name from a given show name.
+// FileNameCleaner return a safe file name from a given show name, using FileCleaner.
 func FileNameCleaner(s string) string {
-	return strings.TrimSpace(fileNameReplacer.Replace(s))
+	return FileCleaner.Clean(s)
 }
 
-// PathNameCleaner return a safe path name from a given show name.
+// PathNameCleaner return a safe path name from a given show name, using PathCleaner.
 func PathNameCleaner(s string) string {
-	if i := strings.Index(s, ":"); i >= 0 && i < 2 {
-		return s[:i] + strings.TrimSpace(pathNameReplacer.Replace(s[i:]))
-	}
-	return strings.TrimSpace(pathNameReplacer.Replace(s))
+	return PathCleaner.CleanPath(s)
 }
 
-// Format2Digits return a number with 2 digits when there is only one digit
+// Format2Digits return a number with at least 2 digits. Extra leading zeros are removed.
+// Non numeric values are returned cleaned.
 func Format2Digits(d string) string {
-	if len(d) < 2 {
-		return "0" + d
+	d = FileNameCleaner(d)
+	n, err := strconv.Atoi(d)
+	if err != nil || n < 0 {
+		return d
 	}
-	return d
+	return fmt.Sprintf("%02d", n)
 }
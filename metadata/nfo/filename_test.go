@@ -0,0 +1,26 @@
+package nfo
+
+import "testing"
+
+func TestFormat2Digits(t *testing.T) {
+	testCases := []struct {
+		in   string
+		want string
+	}{
+		{"1", "01"},
+		{"01", "01"},
+		{"10", "10"},
+		{"003", "03"},
+		{"100", "100"},
+		{"", ""},
+		{"Final", "Final"},
+		{"Final?", "Final"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.in, func(t *testing.T) {
+			if got := Format2Digits(tc.in); got != tc.want {
+				t.Errorf("Format2Digits(%q) = %q, want %q", tc.in, got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package nfo
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"text/template"
+	"unicode/utf8"
+)
+
+// FileNamer computes media file names from a text/template.
+// The template receives the MediaInfo and gives the path of the media relative to the destination,
+// using / as path separator. Following functions are available in the template:
+//   - clean: make a string safe for a file name (see FileNameCleaner)
+//   - cleanPath: make a string safe for a path (see PathNameCleaner)
+//   - twoDigits: left pad a number with 0 (see Format2Digits)
+//
+// The file extension matching the container is given by {{.Ext}}.
+type FileNamer struct {
+	t *template.Template
+}
+
+// Default templates, reproducing the Plex layout
+const (
+	DefaultSeriesTemplate = `{{clean .Showtitle}}/Season {{if gt .Season 0}}{{printf "%02d" .Season}}{{else}}00{{end}}/` +
+		`{{clean .Showtitle}} - {{if gt .Episode 0}}{{printf "s%02de%02d" .Season .Episode}}{{else}}{{.AiredTag}}{{end}}` +
+		`{{with clean .Title}} - {{.}}{{end}}{{with clean .SubChannel}} ({{.}}){{end}}{{.Ext}}`
+	DefaultMovieTemplate = `{{clean .Title}}{{if gt .Year 0}} ({{.Year}}){{end}}/{{clean .Title}}{{if gt .Year 0}} ({{.Year}}){{end}}{{.Ext}}`
+)
+
+var plexSeriesNamer = MustFileNamer(DefaultSeriesTemplate)
+
+// Namers used when the media has no specific namer
+var (
+	DefaultSeriesNamer = plexSeriesNamer
+	DefaultMovieNamer  = MustFileNamer(DefaultMovieTemplate)
+)
+
+var namerFuncs = template.FuncMap{
+	"clean":     FileNameCleaner,
+	"cleanPath": PathNameCleaner,
+	"twoDigits": func(d interface{}) string { return Format2Digits(fmt.Sprint(d)) },
+}
+
+// NewFileNamer parses the template
+func NewFileNamer(tmpl string) (*FileNamer, error) {
+	t, err := template.New("filename").Funcs(namerFuncs).Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return nil, fmt.Errorf("Can't parse file name template: %w", err)
+	}
+	return &FileNamer{t: t}, nil
+}
+
+// MustFileNamer parses the template or panics
+func MustFileNamer(tmpl string) *FileNamer {
+	n, err := NewFileNamer(tmpl)
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
+
+// MaxPathLength is the maximum length in characters of media paths, no limit when not positive.
+// It's the Windows limit by default on Windows, see FileNamer.Path.
+var MaxPathLength = defaultMaxPathLength()
+
+// WindowsMaxPathLength is the longest path accepted by Windows without long path support (MAX_PATH)
+const WindowsMaxPathLength = 259
+
+func defaultMaxPathLength() int {
+	if runtime.GOOS == "windows" {
+		return WindowsMaxPathLength
+	}
+	return 0
+}
+
+// sideFileSuffix is the longest suffix of the files written next to the media, it's counted in the path length
+const sideFileSuffix = "-thumb.jpg"
+
+// ellipsis ends truncated titles
+const ellipsis = "…"
+
+// Path gives the media path into the destination.
+// When the path is longer than MaxPathLength, the title is truncated and ends with an ellipsis:
+// the show name, the episode number or the date, and the extension are kept.
+func (n *FileNamer) Path(destination string, info *MediaInfo) (string, error) {
+	p, err := n.path(destination, info)
+	if err != nil || MaxPathLength <= 0 || pathLength(p) <= MaxPathLength {
+		return p, err
+	}
+	// The title may be used several times, like for the movie's directory and file:
+	// search the longest title that fits
+	title := []rune(strings.TrimSpace(info.Title))
+	shortened := *info
+	truncate := func(l int) (string, error) {
+		shortened.Title = strings.TrimSpace(string(title[:l])) + ellipsis
+		return n.path(destination, &shortened)
+	}
+	low, high := 0, len(title)-1 // title[:low] is kept when nothing fits
+	for low < high {
+		mid := (low + high + 1) / 2
+		p, err = truncate(mid)
+		if err != nil {
+			return "", err
+		}
+		if pathLength(p) <= MaxPathLength {
+			low = mid
+		} else {
+			high = mid - 1
+		}
+	}
+	if len(title) == 0 {
+		return p, nil
+	}
+	return truncate(low)
+}
+
+func (n *FileNamer) path(destination string, info *MediaInfo) (string, error) {
+	b := bytes.NewBuffer(nil)
+	err := n.t.Execute(b, info)
+	if err != nil {
+		return "", fmt.Errorf("Can't compute file name: %w", err)
+	}
+	return filepath.Join(destination, filepath.FromSlash(b.String())), nil
+}
+
+// pathLength gives the length of the media path in characters, with room for the longest side file name
+func pathLength(p string) int {
+	ext := filepath.Ext(p)
+	l := utf8.RuneCountInString(p) - utf8.RuneCountInString(ext)
+	if len(ext) > len(sideFileSuffix) {
+		return l + utf8.RuneCountInString(ext)
+	}
+	return l + len(sideFileSuffix)
+}
@@ -0,0 +1,153 @@
+package nfo
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileNamer(t *testing.T) {
+	aired := Aired(time.Date(2020, 5, 1, 0, 0, 0, 0, time.UTC))
+	flat := MustFileNamer(`{{clean .Showtitle}} {{twoDigits .Season}}x{{twoDigits .Episode}} {{clean .Title}}.mp4`)
+	tests := []struct {
+		name string
+		meta interface{ GetMediaPath(string) string }
+		want string
+	}{
+		{
+			"episode",
+			&EpisodeDetails{MediaInfo: MediaInfo{Showtitle: "Les Dalton", Title: "Le pique-nique", Season: 1, Episode: 2}},
+			"Les Dalton/Season 01/Les Dalton - s01e02 - Le pique-nique.mp4",
+		},
+		{
+			"episode without number",
+			&EpisodeDetails{MediaInfo: MediaInfo{Showtitle: "Le Journal", Title: "Edition: 20h?", Aired: aired}},
+			"Le Journal/Season 00/Le Journal - 2020-05-01 - Edition 20h.mp4",
+		},
+		{
+			"episode without title",
+			&EpisodeDetails{MediaInfo: MediaInfo{Showtitle: "Le Journal", Season: 2020, Aired: aired}},
+			"Le Journal/Season 2020/Le Journal - 2020-05-01.mp4",
+		},
+		{
+			"same day episodes, first",
+			&EpisodeDetails{MediaInfo: MediaInfo{Showtitle: "Les Dalton", Title: "Joe", Aired: Aired(time.Date(2020, 5, 1, 8, 30, 0, 0, time.UTC)), UniqueID: []ID{{ID: "1001"}}}},
+			"Les Dalton/Season 00/Les Dalton - 2020-05-01 08h30 [1001] - Joe.mp4",
+		},
+		{
+			"same day episodes, second",
+			&EpisodeDetails{MediaInfo: MediaInfo{Showtitle: "Les Dalton", Title: "Averell", Aired: Aired(time.Date(2020, 5, 1, 8, 45, 0, 0, time.UTC)), UniqueID: []ID{{ID: "1002"}}}},
+			"Les Dalton/Season 00/Les Dalton - 2020-05-01 08h45 [1002] - Averell.mp4",
+		},
+		{
+			"same day episodes without title",
+			&EpisodeDetails{MediaInfo: MediaInfo{Showtitle: "Le Journal", Aired: aired, UniqueID: []ID{{ID: "2001"}}}},
+			"Le Journal/Season 00/Le Journal - 2020-05-01 [2001].mp4",
+		},
+		{
+			"regional episode",
+			&EpisodeDetails{MediaInfo: MediaInfo{Showtitle: "JT 12-13", Title: "Edition régionale", Aired: aired, SubChannel: "Bretagne"}},
+			"JT 12-13/Season 00/JT 12-13 - 2020-05-01 - Edition régionale (Bretagne).mp4",
+		},
+		{
+			"movie",
+			&Movie{MediaInfo: MediaInfo{Title: "Cyrano de Bergerac"}},
+			"Cyrano de Bergerac/Cyrano de Bergerac.mp4",
+		},
+		{
+			"movie with year",
+			&Movie{MediaInfo: MediaInfo{Title: "Cyrano de Bergerac", Year: 1990}},
+			"Cyrano de Bergerac (1990)/Cyrano de Bergerac (1990).mp4",
+		},
+		{
+			"mkv movie",
+			&Movie{MediaInfo: MediaInfo{Title: "Cyrano de Bergerac", Container: "mkv"}},
+			"Cyrano de Bergerac/Cyrano de Bergerac.mkv",
+		},
+		{
+			"flat layout",
+			&EpisodeDetails{MediaInfo: MediaInfo{Showtitle: "Les Dalton", Title: "Le pique-nique", Season: 1, Episode: 2, Namer: flat}},
+			"Les Dalton 01x02 Le pique-nique.mp4",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := filepath.Join("/media", filepath.FromSlash(tt.want))
+			if got := tt.meta.GetMediaPath("/media"); got != want {
+				t.Errorf("GetMediaPath() = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestNewFileNamerError(t *testing.T) {
+	if _, err := NewFileNamer("{{clean .Title"); err == nil {
+		t.Errorf("Expecting an error")
+	}
+}
+
+func TestFileNamerMaxPathLength(t *testing.T) {
+	defer func(l int) { MaxPathLength = l }(MaxPathLength)
+	MaxPathLength = 120
+	destination := filepath.FromSlash("/media/Plex/Séries et téléfilms")
+	long := strings.Repeat("Très très long épisode, ", 20) + "fin"
+	tests := []struct {
+		name      string
+		meta      interface{ GetMediaPath(string) string }
+		contains  []string
+		truncated bool
+		fits      bool
+	}{
+		{
+			"short title",
+			&EpisodeDetails{MediaInfo: MediaInfo{Showtitle: "Les Dalton", Title: "Joe", Season: 1, Episode: 2}},
+			[]string{"Les Dalton - s01e02 - Joe.mp4"}, false, true,
+		},
+		{
+			"long episode title",
+			&EpisodeDetails{MediaInfo: MediaInfo{Showtitle: "Plus belle la vie", Title: long, Season: 3, Episode: 12}},
+			[]string{"Plus belle la vie - s03e12 - Très très", "….mp4"}, true, true,
+		},
+		{
+			"long title dated episode with id",
+			&EpisodeDetails{MediaInfo: MediaInfo{Showtitle: "Le Journal", Title: long, Aired: Aired(time.Date(2020, 5, 1, 8, 30, 0, 0, time.UTC)), UniqueID: []ID{{ID: "1001"}}, Container: "mkv"}},
+			[]string{"Le Journal - 2020-05-01 08h30 [1001] - Très", "….mkv"}, true, true,
+		},
+		{
+			"long movie title used twice",
+			&Movie{MediaInfo: MediaInfo{Title: long, Year: 1990}},
+			[]string{"… (1990)/", "… (1990).mp4"}, true, true,
+		},
+		{
+			"show name too long",
+			&EpisodeDetails{MediaInfo: MediaInfo{Showtitle: strings.Repeat("Émission ", 20), Title: "Joe", Season: 1, Episode: 2}},
+			[]string{"s01e02 - ….mp4"}, true, false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.meta.GetMediaPath(destination)
+			for _, c := range tt.contains {
+				if !strings.Contains(filepath.ToSlash(got), c) {
+					t.Errorf("Expecting %q in %q", c, got)
+				}
+			}
+			if strings.Contains(got, "…") != tt.truncated {
+				t.Errorf("Expecting truncation to be %v, got %q", tt.truncated, got)
+			}
+			if fits := pathLength(got) <= MaxPathLength; fits != tt.fits {
+				t.Errorf("Expecting %q to fit in %d characters: %v, got %d", got, MaxPathLength, tt.fits, pathLength(got))
+			}
+			if !strings.HasPrefix(got, destination) {
+				t.Errorf("Expecting destination to be kept, got %q", got)
+			}
+		})
+	}
+
+	MaxPathLength = 0
+	m := &EpisodeDetails{MediaInfo: MediaInfo{Showtitle: "Plus belle la vie", Title: long, Season: 3, Episode: 12}}
+	if got := m.GetMediaPath(destination); strings.Contains(got, "…") {
+		t.Errorf("Expecting no truncation without limit, got %q", got)
+	}
+}
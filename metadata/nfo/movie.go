@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 // Movie holds metadata for movies
@@ -20,8 +21,8 @@ func (n *Movie) GetMediaInfo() *MediaInfo {
 
 // GetNFOPath give the path where the episode's NFO should be
 func (n Movie) GetNFOPath(destination string) string {
-	cleanTitle := FileNameCleaner(n.Title)
-	return filepath.Join(destination, FileNameCleaner(n.Title), cleanTitle+".nfo")
+	nf := n.GetMediaPath(destination)
+	return strings.TrimSuffix(nf, filepath.Ext(nf)) + ".nfo"
 }
 
 // GetSeasonNFOPath returns the path for TVShow.nfo
@@ -36,8 +37,13 @@ func (n Movie) GetShowNFOPath(destination string) string {
 
 // GetMediaPath returns the media path
 func (n Movie) GetMediaPath(destination string) string {
-	cleanTitle := FileNameCleaner(n.Title)
-	return filepath.Join(destination, cleanTitle, cleanTitle+".mp4")
+	if n.Namer != nil {
+		if p, err := n.Namer.Path(destination, &n.MediaInfo); err == nil {
+			return p
+		}
+	}
+	p, _ := DefaultMovieNamer.Path(destination, &n.MediaInfo)
+	return p
 }
 
 // GetSeriesPath gives path for the whole series
@@ -50,8 +56,15 @@ func (n Movie) GetSeasonPath(destination string) string {
 	return n.GetSeriesPath(destination)
 }
 
+// GetMediaPathMatcher gives the path of movies named without their year, as before the year was part of the default layout
 func (n Movie) GetMediaPathMatcher(destination string) string {
-	return n.GetMediaPath(destination)
+	if n.Namer != nil || n.Year == 0 {
+		return n.GetMediaPath(destination)
+	}
+	info := n.MediaInfo
+	info.Year = 0
+	p, _ := DefaultMovieNamer.Path(destination, &info)
+	return p
 }
 
 // WriteNFO file at expected place
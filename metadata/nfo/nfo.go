@@ -2,6 +2,7 @@ package nfo
 
 import (
 	"fmt"
+	"strings"
 	"time"
 )
 
@@ -40,20 +41,61 @@ type MediaInfo struct {
 	DisplaySeason  int      `xml:"displayseason,omitempty"`
 	DisplayEpisode int      `xml:"displayepisode,omitempty"`
 	Plot           string   `xml:"plot,omitempty"`
-	Thumb          []Thumb  `xml:"-"`
+	Thumb          []Thumb  `xml:"thumb,omitempty"`
 	UniqueID       []ID     `xml:"uniqueid,omitempty"`
 	Genre          []string `xml:"genre,omitempty"`
 	Credits        []string `xml:"credits,omitempty"`
 	Director       []string `xml:"director,omitempty"`
 	Aired          Aired    `xml:"aired,omitempty"`
+	Year           int      `xml:"year,omitempty"`
 	Studio         string   `xml:"studio,omitempty"`
+	MPAA           string   `xml:"mpaa,omitempty"` // Content rating, see ContentRating
 	Actor          []Actor  `xml:"actor,omitempty"`
 	Tag            []string `xml:"tag,omitempty"`
 
 	URL        string  `xml:"-"` // Media URL
 	IsSpecial  bool    `xml:"-"` // True when special episode
+	SubChannel string  `xml:"-"` // Regional channel, like Bretagne for France 3 Bretagne
 	SeasonInfo *Season `xml:"-"` // Possible Season nfo
 	TVShow     *TVShow `xml:"-"` // Possible TVShow nfo
+
+	Namer     *FileNamer    `xml:"-"` // File namer, default one when nil
+	Container string        `xml:"-"` // File container: mp4, mkv or ts, mp4 when empty
+	Duration  time.Duration `xml:"-"` // Media duration, zero when unknown
+}
+
+// AiredTag names episodes without number. It gives the aired date followed by the time of broadcast when known,
+// and the media ID when known, so episodes broadcasted the same day are sorted and never share the same name:
+// 2020-05-01 20h45 [123456]
+func (i *MediaInfo) AiredTag() string {
+	t := i.Aired.Time()
+	tag := t.Format("2006-01-02")
+	if t.Hour() != 0 || t.Minute() != 0 {
+		tag += t.Format(" 15h04")
+	}
+	if len(i.UniqueID) > 0 {
+		if id := FileNameCleaner(i.UniqueID[0].ID); len(id) > 0 {
+			tag += " [" + id + "]"
+		}
+	}
+	return tag
+}
+
+// ContentRating gives the french content rating for a minimum age, like FR:12.
+// It's empty for media for all audiences.
+func ContentRating(minAge int) string {
+	if minAge <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("FR:%d", minAge)
+}
+
+// Ext gives the media file extension matching the container
+func (i *MediaInfo) Ext() string {
+	if len(i.Container) == 0 {
+		return ".mp4"
+	}
+	return "." + strings.ToLower(i.Container)
 }
 
 // Aired type helper
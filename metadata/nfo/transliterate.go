@@ -0,0 +1,28 @@
+package nfo
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// transliterations gives the ASCII spelling of letters and signs that aren't accented letters
+var transliterations = strings.NewReplacer(
+	"œ", "oe", "Œ", "OE", "æ", "ae", "Æ", "AE", "ß", "ss", "ø", "o", "Ø", "O", "đ", "d", "Đ", "D", "ł", "l", "Ł", "L",
+	"’", "'", "‘", "'", "«", "\"", "»", "\"", "“", "\"", "”", "\"", "–", "-", "—", "-", "…", "...", " ", " ", "€", "EUR",
+)
+
+// Transliterate spells s with ASCII characters: accents are removed and ligatures are split, "Cœur brisé" gives "Coeur brise".
+// Characters without ASCII spelling are kept.
+func Transliterate(s string) string {
+	s = transliterations.Replace(s)
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	r, _, err := transform.String(t, s)
+	if err != nil {
+		return s
+	}
+	return r
+}
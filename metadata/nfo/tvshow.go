@@ -20,7 +20,8 @@ type TVShow struct {
 	Studio        string   `xml:"studio,omitempty"`
 	Actor         []Actor  `xml:"actor,omitempty"`
 	Thumb         []Thumb  `xml:"-"`
-	HasEpisodes   bool     `xml:"-"` // True when the show has defined episodes
+	Art           []Thumb  `xml:"thumb,omitempty"` // Images referenced by the NFO, like the poster
+	HasEpisodes   bool     `xml:"-"`               // True when the show has defined episodes
 }
 
 // GetNFOPath returns the path for TVShow.nfo
@@ -0,0 +1,92 @@
+package myhttp
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// CachingClient wraps a Getter and keeps successful GET responses into a cache directory.
+// Cached responses are served until they are older than ttl.
+type CachingClient struct {
+	base     Getter
+	cacheDir string
+	ttl      time.Duration
+}
+
+// NewCachingClient create a client that caches responses into cacheDir for ttl
+func NewCachingClient(base Getter, cacheDir string, ttl time.Duration) *CachingClient {
+	return &CachingClient{
+		base:     base,
+		cacheDir: cacheDir,
+		ttl:      ttl,
+	}
+}
+
+// Get serves the response from the cache when available, or gets it and stores it into the cache.
+func (c *CachingClient) Get(ctx context.Context, uri string) (io.ReadCloser, error) {
+	name := c.cacheFile(uri)
+	if fi, err := os.Stat(name); err == nil && time.Since(fi.ModTime()) < c.ttl {
+		f, err := os.Open(name)
+		if err == nil {
+			return f, nil
+		}
+	}
+
+	r, err := c.base.Get(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	err = c.store(name, b)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(b)), nil
+}
+
+// DoWithContext sends the request to the underlying client. Only GET requests without body are cached.
+func (c *CachingClient) DoWithContext(ctx context.Context, method string, theURL string, headers http.Header, body io.Reader) (io.ReadCloser, error) {
+	if method == "GET" && body == nil && len(headers) == 0 {
+		return c.Get(ctx, theURL)
+	}
+	return c.base.DoWithContext(ctx, method, theURL, headers, body)
+}
+
+func (c *CachingClient) cacheFile(uri string) string {
+	h := sha1.Sum([]byte(uri))
+	return filepath.Join(c.cacheDir, hex.EncodeToString(h[:]))
+}
+
+// store writes the file into a temporary file and renames it, to prevent readers getting a partial response
+func (c *CachingClient) store(name string, b []byte) error {
+	err := os.MkdirAll(c.cacheDir, 0777)
+	if err != nil {
+		return fmt.Errorf("Can't create cache directory: %w", err)
+	}
+	f, err := ioutil.TempFile(c.cacheDir, "tmp-")
+	if err != nil {
+		return fmt.Errorf("Can't create cache file: %w", err)
+	}
+	_, err = f.Write(b)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return fmt.Errorf("Can't write cache file: %w", err)
+	}
+	return os.Rename(f.Name(), name)
+}
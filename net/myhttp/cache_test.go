@@ -0,0 +1,73 @@
+package myhttp
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestCachingClient(t *testing.T) {
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.URL.Path == "/error" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprintf(w, "response %d", calls)
+	}))
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "aspiratv-cache-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	get := func(c *CachingClient, u string) (string, error) {
+		r, err := c.Get(context.TODO(), u)
+		if err != nil {
+			return "", err
+		}
+		defer r.Close()
+		b, err := ioutil.ReadAll(r)
+		return string(b), err
+	}
+
+	c := NewCachingClient(NewClient(), dir, time.Hour)
+	for i := 0; i < 3; i++ {
+		got, err := get(c, ts.URL+"/catalog")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != "response 1" {
+			t.Errorf("Expecting cached response, got %q", got)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("Expecting 1 call, got %d", calls)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := get(c, ts.URL+"/error"); err == nil {
+			t.Errorf("Expecting an error")
+		}
+	}
+	if calls != 3 {
+		t.Errorf("Expecting errors not to be cached, got %d calls", calls)
+	}
+
+	expired := NewCachingClient(NewClient(), dir, 0)
+	got, err := get(expired, ts.URL+"/catalog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "response 4" {
+		t.Errorf("Expecting expired cache to be refreshed, got %q", got)
+	}
+}
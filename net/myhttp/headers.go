@@ -0,0 +1,43 @@
+package myhttp
+
+import (
+	"context"
+	"io"
+	"net/http"
+)
+
+// HeadersClient wraps a Getter and adds headers to each request, like User-Agent or Referer.
+// Headers given with the request are kept.
+type HeadersClient struct {
+	base    Getter
+	headers http.Header
+}
+
+// NewHeadersClient create a client that sends the given headers with each request made by base
+func NewHeadersClient(base Getter, headers map[string]string) *HeadersClient {
+	h := http.Header{}
+	for k, v := range headers {
+		h.Set(k, v)
+	}
+	return &HeadersClient{base: base, headers: h}
+}
+
+// Get establish a GET request with the headers. The response keeps the size announced by the server
+// when the base client gives it.
+func (c *HeadersClient) Get(ctx context.Context, uri string) (io.ReadCloser, error) {
+	return c.DoWithContext(ctx, "GET", uri, nil, nil)
+}
+
+// DoWithContext makes the request with the headers not given by headers
+func (c *HeadersClient) DoWithContext(ctx context.Context, method string, theURL string, headers http.Header, body io.Reader) (io.ReadCloser, error) {
+	h := headers.Clone()
+	if h == nil {
+		h = http.Header{}
+	}
+	for k, v := range c.headers {
+		if _, ok := h[k]; !ok {
+			h[k] = v
+		}
+	}
+	return c.base.DoWithContext(ctx, method, theURL, h, body)
+}
@@ -0,0 +1,48 @@
+package myhttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHeadersClient(t *testing.T) {
+	var got http.Header
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header
+		w.Write([]byte("hello"))
+	}))
+	defer ts.Close()
+
+	c := NewHeadersClient(NewClientWithHeaders(map[string]string{"X-Base": "kept"}), map[string]string{
+		"user-agent": "Firefox",
+		"Referer":    "https://www.france.tv",
+	})
+
+	r, err := c.Get(context.TODO(), ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Close()
+	if got.Get("User-Agent") != "Firefox" || got.Get("Referer") != "https://www.france.tv" || got.Get("X-Base") != "kept" {
+		t.Errorf("Unexpected headers for GET: %v", got)
+	}
+	if cl, ok := r.(ContentLengther); !ok || cl.ContentLength() != 5 {
+		t.Errorf("Expecting the response to keep its content length")
+	}
+
+	h := http.Header{}
+	h.Set("Referer", "https://example.com")
+	r, err = c.DoWithContext(context.TODO(), "POST", ts.URL, h, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Close()
+	if got.Get("User-Agent") != "Firefox" || got.Get("Referer") != "https://example.com" {
+		t.Errorf("Expecting request headers to be kept, got %v", got)
+	}
+	if len(h) != 1 {
+		t.Errorf("Expecting request headers to be left untouched, got %v", h)
+	}
+}
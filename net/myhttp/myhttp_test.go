@@ -47,6 +47,7 @@ func TestNewClient(t *testing.T) {
 				&http.Client{},
 				UserAgent,
 				nil,
+				nil,
 			},
 		},
 		{
@@ -58,6 +59,7 @@ func TestNewClient(t *testing.T) {
 				&http.Client{},
 				"Given Agent",
 				nil,
+				nil,
 			},
 		},
 		{
@@ -68,6 +70,7 @@ func TestNewClient(t *testing.T) {
 			&Client{
 				&http.Client{Jar: cj},
 				UserAgent,
+				nil,
 				makeJar(),
 			},
 		}, {
@@ -78,6 +81,7 @@ func TestNewClient(t *testing.T) {
 			&Client{
 				&http.Client{Jar: cj},
 				"Given Agent",
+				nil,
 				makeJar(),
 			},
 		},
@@ -199,8 +203,8 @@ func TestClient_Get(t *testing.T) {
 				t.Errorf("Client.Get() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
-			defer got.Close()
 			if err == nil {
+				defer got.Close()
 				b := &strings.Builder{}
 				_, err = io.Copy(b, got)
 				if b.String() != tt.testSrv.body.String() {
@@ -210,3 +214,66 @@ func TestClient_Get(t *testing.T) {
 		})
 	}
 }
+
+func TestClientHeaders(t *testing.T) {
+	var got http.Header
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header
+	}))
+	defer ts.Close()
+
+	c := NewClientWithHeaders(map[string]string{
+		"user-agent": "Firefox",
+		"Referer":    "https://www.france.tv",
+	})
+
+	r, err := c.Get(context.TODO(), ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Close()
+	if got.Get("User-Agent") != "Firefox" || got.Get("Referer") != "https://www.france.tv" {
+		t.Errorf("Unexpected headers for GET: %v", got)
+	}
+
+	h := http.Header{}
+	h.Set("Referer", "https://example.com")
+	r, err = c.DoWithContext(context.TODO(), "POST", ts.URL, h, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Close()
+	if got.Get("User-Agent") != "Firefox" || got.Get("Referer") != "https://example.com" {
+		t.Errorf("Expecting request headers to be kept, got %v", got)
+	}
+}
+
+func TestClientWithProxy(t *testing.T) {
+	var host string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host = r.URL.Host // Proxied requests have an absolute url
+		w.Write([]byte("proxied"))
+	}))
+	defer proxy.Close()
+
+	c, err := NewClientWithProxy(proxy.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := c.Get(context.TODO(), "http://www.france.tv/replay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &strings.Builder{}
+	io.Copy(b, r)
+	r.Close()
+	if b.String() != "proxied" || host != "www.france.tv" {
+		t.Errorf("Expecting request through the proxy, got %q for host %q", b.String(), host)
+	}
+
+	for _, u := range []string{"proxy.example.com:3128", "://bad"} {
+		if _, err := NewClientWithProxy(u); err == nil {
+			t.Errorf("Expecting an error for proxy url %q", u)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package myhttp
+
+import (
+	"context"
+	"io"
+	"net/http"
+
+	"golang.org/x/time/rate"
+)
+
+// RateLimitedClient wraps a Getter and limits the number of requests per second
+// sent to the server.
+type RateLimitedClient struct {
+	base    Getter
+	limiter *rate.Limiter
+}
+
+// NewRateLimitedClient create a client that sends at most rps requests per second,
+// with bursts of at most burst requests.
+func NewRateLimitedClient(base Getter, rps float64, burst int) *RateLimitedClient {
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimitedClient{
+		base:    base,
+		limiter: rate.NewLimiter(rate.Limit(rps), burst),
+	}
+}
+
+// Get waits for its turn and establish a GET request
+func (c *RateLimitedClient) Get(ctx context.Context, uri string) (io.ReadCloser, error) {
+	if err := c.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+	return c.base.Get(ctx, uri)
+}
+
+// DoWithContext waits for its turn and makes the request
+func (c *RateLimitedClient) DoWithContext(ctx context.Context, method string, theURL string, headers http.Header, body io.Reader) (io.ReadCloser, error) {
+	if err := c.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+	return c.base.DoWithContext(ctx, method, theURL, headers, body)
+}
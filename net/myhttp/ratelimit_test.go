@@ -0,0 +1,55 @@
+package myhttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimitedClient(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("OK"))
+	}))
+	defer ts.Close()
+
+	c := NewRateLimitedClient(NewClient(), 20, 2)
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		r, err := c.Get(context.TODO(), ts.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Close()
+	}
+	// 2 requests in the burst, then 2 requests spaced by 50ms
+	if d := time.Since(start); d < 90*time.Millisecond {
+		t.Errorf("Expecting requests to be spread over at least 90ms, got %s", d)
+	}
+}
+
+func TestRateLimitedClientCancel(t *testing.T) {
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+	}))
+	defer ts.Close()
+
+	c := NewRateLimitedClient(NewClient(), 0.1, 1)
+	r, err := c.Get(context.TODO(), ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = c.Get(ctx, ts.URL)
+	if err == nil {
+		t.Errorf("Expecting an error when the context expires before a token is available")
+	}
+	if calls != 1 {
+		t.Errorf("Expecting %d calls, got %d", 1, calls)
+	}
+}
@@ -0,0 +1,183 @@
+package myhttp
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// RecordEnv is the environment variable giving the directory where responses are recorded.
+// When it's set, providers' default getter records every response body into it.
+const RecordEnv = "ASPIRATV_RECORD"
+
+// FixtureNamer gives the file name of the fixture of a request
+type FixtureNamer func(method, uri string, body []byte) string
+
+var unsafeFixtureChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// FixtureName is the default FixtureNamer. The name is made of the method and the sanitized url,
+// followed by a hash of the body when any. Long names are shortened with a hash.
+func FixtureName(method, uri string, body []byte) string {
+	u := uri
+	if i := strings.Index(u, "://"); i >= 0 {
+		u = u[i+3:]
+	}
+	name := strings.ToLower(method) + "_" + strings.Trim(unsafeFixtureChars.ReplaceAllString(u, "_"), "_")
+	if len(body) > 0 {
+		h := sha1.Sum(body)
+		name += "-" + hex.EncodeToString(h[:4])
+	}
+	if len(name) > 150 {
+		h := sha1.Sum([]byte(name))
+		name = name[:140] + "-" + hex.EncodeToString(h[:4])
+	}
+	return name
+}
+
+// WithFixtureNamer set the function used to name fixture files
+func WithFixtureNamer(fn FixtureNamer) func(c *fixtureDir) {
+	return func(c *fixtureDir) {
+		c.namer = fn
+	}
+}
+
+type fixtureDir struct {
+	dir   string
+	namer FixtureNamer
+}
+
+func newFixtureDir(dir string, conf ...func(c *fixtureDir)) fixtureDir {
+	d := fixtureDir{
+		dir:   dir,
+		namer: FixtureName,
+	}
+	for _, f := range conf {
+		f(&d)
+	}
+	return d
+}
+
+func (d fixtureDir) path(method, uri string, body []byte) string {
+	return filepath.Join(d.dir, d.namer(method, uri, body))
+}
+
+// RecordingClient wraps a Getter and saves every successful response body into a fixtures directory.
+// Files can be served back by a ReplayingClient.
+type RecordingClient struct {
+	fixtureDir
+	base Getter
+}
+
+// NewRecordingClient create a client that records responses into dir
+func NewRecordingClient(base Getter, dir string, conf ...func(c *fixtureDir)) *RecordingClient {
+	return &RecordingClient{
+		fixtureDir: newFixtureDir(dir, conf...),
+		base:       base,
+	}
+}
+
+// RecorderFromEnv wraps base into a RecordingClient when the RecordEnv variable is set, or returns base.
+func RecorderFromEnv(base Getter) Getter {
+	dir := os.Getenv(RecordEnv)
+	if len(dir) == 0 {
+		return base
+	}
+	return NewRecordingClient(base, dir)
+}
+
+// Get gets the url and records the response
+func (c *RecordingClient) Get(ctx context.Context, uri string) (io.ReadCloser, error) {
+	r, err := c.base.Get(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+	return c.record(c.path("GET", uri, nil), r)
+}
+
+// DoWithContext sends the request and records the response. The body is buffered to compute the fixture name.
+func (c *RecordingClient) DoWithContext(ctx context.Context, method string, theURL string, headers http.Header, body io.Reader) (io.ReadCloser, error) {
+	b, err := readBody(body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		body = bytes.NewReader(b)
+	}
+	r, err := c.base.DoWithContext(ctx, method, theURL, headers, body)
+	if err != nil {
+		return nil, err
+	}
+	return c.record(c.path(method, theURL, b), r)
+}
+
+func (c *RecordingClient) record(name string, r io.ReadCloser) (io.ReadCloser, error) {
+	defer r.Close()
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	err = os.MkdirAll(c.dir, 0777)
+	if err != nil {
+		return nil, fmt.Errorf("Can't create fixtures directory: %w", err)
+	}
+	err = ioutil.WriteFile(name, b, 0666)
+	if err != nil {
+		return nil, fmt.Errorf("Can't write fixture: %w", err)
+	}
+	return ioutil.NopCloser(bytes.NewReader(b)), nil
+}
+
+// ReplayingClient serves the responses recorded by a RecordingClient, without network access.
+type ReplayingClient struct {
+	fixtureDir
+}
+
+// ReplayClient create a client that serves the fixtures found in dir.
+// A request without fixture fails with a 404 HTTPError.
+func ReplayClient(dir string, conf ...func(c *fixtureDir)) *ReplayingClient {
+	return &ReplayingClient{
+		fixtureDir: newFixtureDir(dir, conf...),
+	}
+}
+
+// Get serves the recorded response of the url
+func (c *ReplayingClient) Get(ctx context.Context, uri string) (io.ReadCloser, error) {
+	return c.replay(c.path("GET", uri, nil))
+}
+
+// DoWithContext serves the recorded response of the request
+func (c *ReplayingClient) DoWithContext(ctx context.Context, method string, theURL string, headers http.Header, body io.Reader) (io.ReadCloser, error) {
+	b, err := readBody(body)
+	if err != nil {
+		return nil, err
+	}
+	return c.replay(c.path(method, theURL, b))
+}
+
+func (c *ReplayingClient) replay(name string) (io.ReadCloser, error) {
+	f, err := os.Open(name)
+	if os.IsNotExist(err) {
+		return nil, &HTTPError{
+			StatusCode: http.StatusNotFound,
+			Status:     "404 Not Found",
+			msg:        fmt.Sprintf("Can't find fixture %q", name),
+		}
+	}
+	return f, err
+}
+
+func readBody(body io.Reader) ([]byte, error) {
+	if body == nil {
+		return nil, nil
+	}
+	return ioutil.ReadAll(body)
+}
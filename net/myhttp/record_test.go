@@ -0,0 +1,95 @@
+package myhttp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestFixtureName(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		uri    string
+		body   string
+		want   string
+	}{
+		{"get", "GET", "https://www.france.tv/", "", "get_www.france.tv"},
+		{"query", "GET", "https://api.example.com/v1/videos?id=12&page=2", "", "get_api.example.com_v1_videos_id_12_page_2"},
+		{"post", "POST", "https://api.example.com/search", "q=cyrano", "post_api.example.com_search-5a02fd2a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FixtureName(tt.method, tt.uri, []byte(tt.body)); got != tt.want {
+				t.Errorf("FixtureName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+	long := FixtureName("GET", "https://example.com/"+strings.Repeat("a", 300), nil)
+	if len(long) > 150 {
+		t.Errorf("Expecting long names to be shortened, got %d characters", len(long))
+	}
+}
+
+func TestRecordReplay(t *testing.T) {
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		b, _ := ioutil.ReadAll(r.Body)
+		fmt.Fprintf(w, "%s %s %s", r.Method, r.URL.Path, string(b))
+	}))
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "aspiratv-fixtures-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	read := func(c Getter, method, u, body string) (string, error) {
+		r, err := c.DoWithContext(context.TODO(), method, u, http.Header{}, strings.NewReader(body))
+		if err != nil {
+			return "", err
+		}
+		defer r.Close()
+		b, err := ioutil.ReadAll(r)
+		return string(b), err
+	}
+
+	rec := NewRecordingClient(NewClient(), dir)
+	for _, body := range []string{"page=0", "page=1"} {
+		got, err := read(rec, "POST", ts.URL+"/search", body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := "POST /search " + body; got != want {
+			t.Errorf("Expecting %q, got %q", want, got)
+		}
+	}
+
+	rep := ReplayClient(dir)
+	for _, body := range []string{"page=0", "page=1"} {
+		got, err := read(rep, "POST", ts.URL+"/search", body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := "POST /search " + body; got != want {
+			t.Errorf("Expecting replayed %q, got %q", want, got)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("Expecting server to be called %d times, got %d", 2, calls)
+	}
+
+	_, err = rep.Get(context.TODO(), ts.URL+"/missing")
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusNotFound {
+		t.Errorf("Expecting a 404 error, got %v", err)
+	}
+}
@@ -0,0 +1,128 @@
+package myhttp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// RetryingClient wraps a Getter and retries failed requests with an exponential backoff.
+// Only network errors and server errors (5xx) are retried.
+type RetryingClient struct {
+	base      Getter
+	attempts  int
+	baseDelay time.Duration
+	maxDelay  time.Duration
+	jitter    float64
+}
+
+// WithMaxDelay caps the delay between two attempts
+func WithMaxDelay(d time.Duration) func(c *RetryingClient) {
+	return func(c *RetryingClient) {
+		c.maxDelay = d
+	}
+}
+
+// WithJitter randomizes the delay between two attempts by the given fraction (0.0 to 1.0)
+func WithJitter(j float64) func(c *RetryingClient) {
+	return func(c *RetryingClient) {
+		c.jitter = j
+	}
+}
+
+// NewRetryingClient create a client that makes at most attempts tries for each request.
+// The delay between two attempts starts with baseDelay and doubles at each try.
+func NewRetryingClient(base Getter, attempts int, baseDelay time.Duration, conf ...func(c *RetryingClient)) *RetryingClient {
+	if attempts < 1 {
+		attempts = 1
+	}
+	c := &RetryingClient{
+		base:      base,
+		attempts:  attempts,
+		baseDelay: baseDelay,
+		maxDelay:  30 * time.Second,
+		jitter:    0.2,
+	}
+	for _, f := range conf {
+		f(c)
+	}
+	return c
+}
+
+// Get establish a GET request and retry it when needed
+func (c *RetryingClient) Get(ctx context.Context, uri string) (io.ReadCloser, error) {
+	return c.retry(ctx, func() (io.ReadCloser, error) {
+		return c.base.Get(ctx, uri)
+	})
+}
+
+// DoWithContext makes the request and retry it when needed. The body is buffered to be sent again.
+func (c *RetryingClient) DoWithContext(ctx context.Context, method string, theURL string, headers http.Header, body io.Reader) (io.ReadCloser, error) {
+	var b []byte
+	if body != nil {
+		var err error
+		b, err = ioutil.ReadAll(body)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return c.retry(ctx, func() (io.ReadCloser, error) {
+		var r io.Reader
+		if body != nil {
+			r = bytes.NewReader(b)
+		}
+		return c.base.DoWithContext(ctx, method, theURL, headers, r)
+	})
+}
+
+func (c *RetryingClient) retry(ctx context.Context, fn func() (io.ReadCloser, error)) (io.ReadCloser, error) {
+	var err error
+	for attempt := 0; attempt < c.attempts; attempt++ {
+		if attempt > 0 {
+			d := c.delay(attempt)
+			log.Printf("Retrying in %s after error: %s", d, err)
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(d):
+			}
+		}
+		var r io.ReadCloser
+		r, err = fn()
+		if err == nil {
+			return r, nil
+		}
+		if !isRetryable(ctx, err) {
+			return nil, err
+		}
+	}
+	return nil, err
+}
+
+func (c *RetryingClient) delay(attempt int) time.Duration {
+	d := c.baseDelay << uint(attempt-1)
+	if d > c.maxDelay || d <= 0 {
+		d = c.maxDelay
+	}
+	if c.jitter > 0 {
+		d += time.Duration((rand.Float64()*2 - 1) * c.jitter * float64(d))
+	}
+	return d
+}
+
+func isRetryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode >= 500
+	}
+	return true
+}
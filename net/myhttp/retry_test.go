@@ -0,0 +1,93 @@
+package myhttp
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type flakyHandler struct {
+	failures int
+	status   int
+	calls    int
+}
+
+func (h *flakyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.calls++
+	if h.calls <= h.failures {
+		w.WriteHeader(h.status)
+		return
+	}
+	b, _ := ioutil.ReadAll(r.Body)
+	w.Write([]byte("OK" + string(b)))
+}
+
+func TestRetryingClient(t *testing.T) {
+	testCases := []struct {
+		name      string
+		failures  int
+		status    int
+		attempts  int
+		wantCalls int
+		wantErr   bool
+	}{
+		{"no failure", 0, 503, 3, 1, false},
+		{"recover after 503", 2, 503, 3, 3, false},
+		{"too many 503", 5, 503, 3, 3, true},
+		{"no retry on 404", 5, 404, 3, 1, true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := &flakyHandler{failures: tc.failures, status: tc.status}
+			ts := httptest.NewServer(h)
+			defer ts.Close()
+
+			c := NewRetryingClient(NewClient(), tc.attempts, time.Millisecond)
+			r, err := c.Get(context.TODO(), ts.URL)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("Expecting error to be %v, got %v", tc.wantErr, err)
+			}
+			if err == nil {
+				r.Close()
+			}
+			if h.calls != tc.wantCalls {
+				t.Errorf("Expecting %d calls, got %d", tc.wantCalls, h.calls)
+			}
+		})
+	}
+}
+
+func TestRetryingClientBody(t *testing.T) {
+	h := &flakyHandler{failures: 1, status: 500}
+	ts := httptest.NewServer(h)
+	defer ts.Close()
+
+	c := NewRetryingClient(NewClient(), 2, time.Millisecond)
+	r, err := c.DoWithContext(context.TODO(), "POST", ts.URL, http.Header{}, strings.NewReader("body"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	b, _ := ioutil.ReadAll(r)
+	if string(b) != "OKbody" {
+		t.Errorf("Expecting body to be sent again, got %q", string(b))
+	}
+}
+
+func TestRetryingClientCancel(t *testing.T) {
+	h := &flakyHandler{failures: 5, status: 503}
+	ts := httptest.NewServer(h)
+	defer ts.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	c := NewRetryingClient(NewClient(), 5, time.Second)
+	_, err := c.Get(ctx, ts.URL)
+	if err != context.DeadlineExceeded {
+		t.Errorf("Expecting %v, got %v", context.DeadlineExceeded, err)
+	}
+}
@@ -16,9 +16,10 @@ type Getter interface {
 }
 
 type Master struct {
-	Variants []Variant
-	getter   Getter
-	URL      string
+	Variants   []Variant
+	Renditions []Rendition // Alternative renditions given by #EXT-X-MEDIA tags
+	getter     Getter
+	URL        string
 }
 
 type Variant struct {
@@ -26,6 +27,48 @@ type Variant struct {
 	Width, Height int64
 	worstURL      int64
 	URL           string
+	Audio         string // Group ID of the audio renditions played with this variant
+}
+
+// Rendition is an alternative rendition of the stream, like an audio track in another language
+type Rendition struct {
+	Type            string // AUDIO, SUBTITLES, VIDEO or CLOSED-CAPTIONS
+	GroupID         string
+	Language        string
+	Name            string
+	Default         bool
+	Characteristics string // Uniform Type Identifiers, separated by commas
+	URL             string // Media playlist of the rendition, empty when it's muxed into the variant
+}
+
+// IsAudioDescription tells if the rendition is an audio description for visually impaired people
+func (r Rendition) IsAudioDescription() bool {
+	if strings.Contains(r.Characteristics, "public.accessibility.describes-video") {
+		return true
+	}
+	name := strings.ToLower(r.Name)
+	return strings.Contains(name, "audiodescription") || strings.Contains(name, "audio description") || strings.HasSuffix(name, "(ad)")
+}
+
+// AudioRenditions gives the audio renditions having their own playlist, played with the variant at variantURL.
+// All audio renditions are given when the variant isn't found. Rendition URLs are absolute.
+func (m *Master) AudioRenditions(variantURL string) []Rendition {
+	group, found := "", false
+	for _, v := range m.Variants {
+		if myhttp.Rel(m.URL, v.URL) == variantURL {
+			group, found = v.Audio, true
+			break
+		}
+	}
+	renditions := []Rendition{}
+	for _, r := range m.Renditions {
+		if r.Type != "AUDIO" || len(r.URL) == 0 || (found && r.GroupID != group) {
+			continue
+		}
+		r.URL = myhttp.Rel(m.URL, r.URL)
+		renditions = append(renditions, r)
+	}
+	return renditions
 }
 
 func NewMaster(ctx context.Context, URL string, getter Getter) (*Master, error) {
@@ -48,6 +91,11 @@ func NewMaster(ctx context.Context, URL string, getter Getter) (*Master, error)
 	return m, nil
 }
 
+// IsMaster returns true when the decoded playlist has variants
+func (m *Master) IsMaster() bool {
+	return len(m.Variants) > 0
+}
+
 func (m *Master) WorstQuality() string {
 	worstURL := -1
 	worstPix := int64(^uint64(0) >> 1)
@@ -72,6 +120,58 @@ func (m *Master) BestQuality() string {
 	return myhttp.Rel(m.URL, m.Variants[bestURL].URL)
 }
 
+// Quality returns the url of the variant matching the requested quality:
+//   - "best": the variant with the highest bandwidth
+//   - "worst": the variant with the lowest bandwidth
+//   - "720p": the variant with the highest resolution not above the given height
+//
+// When no variant is under the requested height, the worst variant is returned.
+func (m *Master) Quality(q string) (string, error) {
+	if len(m.Variants) == 0 {
+		return "", fmt.Errorf("No variant in %q", m.URL)
+	}
+	q = strings.ToLower(strings.TrimSpace(q))
+	selected := -1
+	switch q {
+	case "best":
+		for i, v := range m.Variants {
+			if selected < 0 || v.Bandwidth > m.Variants[selected].Bandwidth {
+				selected = i
+			}
+		}
+	case "worst":
+		selected = m.lowestBandwidth()
+	default:
+		var height int64
+		if _, err := fmt.Sscanf(q, "%dp", &height); err != nil || height <= 0 {
+			return "", fmt.Errorf("Unknown quality %q", q)
+		}
+		for i, v := range m.Variants {
+			if v.Height > height {
+				continue
+			}
+			if selected < 0 || v.Height > m.Variants[selected].Height ||
+				(v.Height == m.Variants[selected].Height && v.Bandwidth > m.Variants[selected].Bandwidth) {
+				selected = i
+			}
+		}
+		if selected < 0 {
+			selected = m.lowestBandwidth()
+		}
+	}
+	return myhttp.Rel(m.URL, m.Variants[selected].URL), nil
+}
+
+func (m *Master) lowestBandwidth() int {
+	selected := -1
+	for i, v := range m.Variants {
+		if selected < 0 || v.Bandwidth < m.Variants[selected].Bandwidth {
+			selected = i
+		}
+	}
+	return selected
+}
+
 func (m *Master) decode(r io.Reader) error {
 	s := bufio.NewScanner(r)
 	var v *Variant
@@ -92,6 +192,9 @@ func (m *Master) decode(r io.Reader) error {
 			waitURL = true
 			continue
 		}
+		if strings.HasPrefix(l, "#EXT-X-MEDIA:") {
+			m.Renditions = append(m.Renditions, handleMedia(l))
+		}
 
 	}
 	if err := s.Err(); err != nil && err != io.EOF {
@@ -118,11 +221,26 @@ func handleStreamInf(s string) (*Variant, error) {
 				return nil, fmt.Errorf("Can't parse RESOLUTION: %v", err)
 			}
 			v.worstURL = v.Width * v.Height
+		case "AUDIO":
+			v.Audio = val
 		}
 	}
 	return v, nil
 }
 
+func handleMedia(s string) Rendition {
+	p := splitParams(s[len("#EXT-X-MEDIA:"):])
+	return Rendition{
+		Type:            p["TYPE"],
+		GroupID:         p["GROUP-ID"],
+		Language:        p["LANGUAGE"],
+		Name:            p["NAME"],
+		Default:         p["DEFAULT"] == "YES",
+		Characteristics: p["CHARACTERISTICS"],
+		URL:             p["URI"],
+	}
+}
+
 func splitParams(s string) map[string]string {
 	p := 0
 	params := map[string]string{}
@@ -174,7 +174,7 @@ func newStringGet(n int) *stringGet {
 
 	for i := 0; i < n; i++ {
 		c := 'A' + i%26
-		s.s[i] = strings.Repeat(string(c), 50)
+		s.s[i] = strings.Repeat(string(rune(c)), 50)
 		s.expected.WriteString(s.s[i])
 		h.Write([]byte(s.s[i]))
 	}
@@ -273,3 +273,86 @@ func TestMultiPartPlayList(t *testing.T) {
 		})
 	}
 }
+
+func TestMasterQuality(t *testing.T) {
+	m := &Master{
+		URL: "http://host/path/master.m3u8",
+		Variants: []Variant{
+			{Bandwidth: 800000, Width: 960, Height: 540, URL: "540.m3u8"},
+			{Bandwidth: 300000, Width: 640, Height: 360, URL: "360.m3u8"},
+			{Bandwidth: 2500000, Width: 1920, Height: 1080, URL: "1080.m3u8"},
+			{Bandwidth: 1500000, Width: 1280, Height: 720, URL: "720.m3u8"},
+		},
+	}
+	testCases := []struct {
+		quality string
+		want    string
+		wantErr bool
+	}{
+		{"best", "http://host/path/1080.m3u8", false},
+		{"Worst", "http://host/path/360.m3u8", false},
+		{"720p", "http://host/path/720.m3u8", false},
+		{"600p", "http://host/path/540.m3u8", false},
+		{"240p", "http://host/path/360.m3u8", false},
+		{"high", "", true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.quality, func(t *testing.T) {
+			got, err := m.Quality(tc.quality)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("Expecting error to be %v, got %v", tc.wantErr, err)
+			}
+			if got != tc.want {
+				t.Errorf("Expecting %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestAudioRenditions(t *testing.T) {
+	playlist := `#EXTM3U
+#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="audio-aacl-96",LANGUAGE="fr",NAME="Français",DEFAULT=YES,AUTOSELECT=YES,URI="audio_fr.m3u8"
+#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="audio-aacl-96",LANGUAGE="fr",NAME="Audiodescription",DEFAULT=NO,AUTOSELECT=NO,CHARACTERISTICS="public.accessibility.describes-video",URI="audio_ad.m3u8"
+#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="audio-aacl-64",LANGUAGE="fr",NAME="Français",DEFAULT=YES,AUTOSELECT=YES,URI="audio_fr_64.m3u8"
+#EXT-X-MEDIA:TYPE=SUBTITLES,GROUP-ID="subs",LANGUAGE="fr",NAME="Français",DEFAULT=NO,URI="subs_fr.m3u8"
+#EXT-X-STREAM-INF:BANDWIDTH=2500000,RESOLUTION=1920x1080,AUDIO="audio-aacl-96",SUBTITLES="subs"
+video_1080.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=500000,RESOLUTION=640x360,AUDIO="audio-aacl-64",SUBTITLES="subs"
+video_360.m3u8
+`
+	m := &Master{URL: "http://host/path/master.m3u8"}
+	if err := m.decode(strings.NewReader(playlist)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(m.Variants) != 2 || len(m.Renditions) != 4 {
+		t.Fatalf("Expecting 2 variants and 4 renditions, got %d and %d", len(m.Variants), len(m.Renditions))
+	}
+	if m.Variants[0].Audio != "audio-aacl-96" {
+		t.Errorf("Expecting variant audio group, got %q", m.Variants[0].Audio)
+	}
+
+	testCases := []struct {
+		variant string
+		want    []string
+	}{
+		{"http://host/path/video_1080.m3u8", []string{"http://host/path/audio_fr.m3u8", "http://host/path/audio_ad.m3u8"}},
+		{"http://host/path/video_360.m3u8", []string{"http://host/path/audio_fr_64.m3u8"}},
+		{"http://host/path/master.m3u8", []string{"http://host/path/audio_fr.m3u8", "http://host/path/audio_ad.m3u8", "http://host/path/audio_fr_64.m3u8"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.variant, func(t *testing.T) {
+			got := []string{}
+			for _, r := range m.AudioRenditions(tc.variant) {
+				got = append(got, r.URL)
+			}
+			if strings.Join(got, " ") != strings.Join(tc.want, " ") {
+				t.Errorf("Expecting %v, got %v", tc.want, got)
+			}
+		})
+	}
+
+	r := m.AudioRenditions("http://host/path/video_1080.m3u8")
+	if !r[0].Default || r[0].IsAudioDescription() || r[1].Default || !r[1].IsAudioDescription() {
+		t.Errorf("Unexpected renditions %+v", r)
+	}
+}
@@ -16,6 +16,7 @@ type Playlist struct {
 	URL        string
 	base       string
 	allowCache bool
+	ended      bool // True when the playlist has the EXT-X-ENDLIST tag
 	chunks     []chunk
 	getter     Getter
 }
@@ -54,6 +55,10 @@ func (p *Playlist) decode(r io.Reader) error {
 			p.allowCache = v == "YES"
 			continue
 		}
+		if strings.HasPrefix(l, "#EXT-X-ENDLIST") {
+			p.ended = true
+			continue
+		}
 		if strings.HasPrefix(l, "#EXTINF:") {
 			d := float64(0)
 			_, err := fmt.Sscanf(l, "#EXTINF:%f", &d)
@@ -83,6 +88,23 @@ func (p *Playlist) decode(r io.Reader) error {
 	return nil
 }
 
+// Ended tells if the playlist is complete. A playlist without EXT-X-ENDLIST may get more segments later.
+func (p *Playlist) Ended() bool {
+	return p.ended
+}
+
+// Segments returns the absolute URLs of playlist's chunks, in playlist order
+func (p *Playlist) Segments() []string {
+	urls := make([]string, len(p.chunks))
+	for i, c := range p.chunks {
+		urls[i] = c.url
+		if !myhttp.IsAbs(c.url) {
+			urls[i] = myhttp.Base(p.URL) + c.url
+		}
+	}
+	return urls
+}
+
 func (p *Playlist) Download(ctx context.Context) (io.Reader, error) {
 	pr, pw := io.Pipe()
 	go func() {
@@ -12,16 +12,25 @@ func TestPlayListContent(t *testing.T) {
 		totalDuration time.Duration
 		count         int
 		allowCache    bool
+		ended         bool
 	}{
 		{"testdata/f2-rg/index_3_av.m3u8",
 			time.Duration(time.Second*16726 + 613*time.Millisecond),
 			1673,
 			true,
+			true,
 		},
 		{"testdata/akamai/250kbit.m3u8",
 			time.Duration(time.Second * 444 * 2),
 			444,
 			false,
+			true,
+		},
+		{"testdata/live.m3u8",
+			time.Duration(time.Second * 20),
+			2,
+			false,
+			false,
 		}}
 
 	for _, tc := range testCases {
@@ -42,6 +51,9 @@ func TestPlayListContent(t *testing.T) {
 		if tc.allowCache != p.allowCache {
 			t.Errorf("Expecting allowCache to be %v, but got %v", tc.allowCache, p.allowCache)
 		}
+		if tc.ended != p.Ended() {
+			t.Errorf("Expecting ended to be %v, but got %v", tc.ended, p.Ended())
+		}
 
 	}
 }
@@ -5,7 +5,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
 	"net/url"
 	"regexp"
 	"sort"
@@ -14,6 +13,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/simulot/aspiratv/download"
 	"github.com/simulot/aspiratv/metadata/nfo"
 	"github.com/simulot/aspiratv/providers"
 
@@ -23,6 +23,7 @@ import (
 
 	"github.com/simulot/aspiratv/net/myhttp"
 	"github.com/simulot/aspiratv/parsers/htmlparser"
+	"github.com/simulot/aspiratv/playlists/m3u8"
 )
 
 // init registers ArteTV provider
@@ -51,6 +52,7 @@ type getter interface {
 
 // ArteTV structure handles arte  catalog of shows
 type ArteTV struct {
+	providers.NoHealthCheck
 	getter            getter
 	preferredVersions []string // versionCode List of version in order of preference VF,VA...
 	preferredQuality  []string
@@ -60,6 +62,7 @@ type ArteTV struct {
 	seenPrograms      map[string]bool
 	deadline          time.Duration
 	keepBonuses       bool
+	logger            providers.Logger
 }
 
 // WithGetter inject a getter in FranceTV object instead of normal one
@@ -123,7 +126,7 @@ func (t *throttler) Get(ctx context.Context, uri string) (io.ReadCloser, error)
 
 // New setup a Show provider for Arte
 func New() (*ArteTV, error) {
-	throttler := newThrottler(myhttp.DefaultClient, 20, 25)
+	throttler := newThrottler(myhttp.RecorderFromEnv(myhttp.DefaultClient), 20, 25)
 	p := &ArteTV{
 		getter: throttler,
 		//TODO: get preferences from config file
@@ -135,12 +138,14 @@ func New() (*ArteTV, error) {
 		deadline:          30 * time.Second,
 		keepBonuses:       true,
 	}
+	p.logger = providers.NewStdLogger(p.Name(), false)
 	return p, nil
 }
 
 func (p *ArteTV) Configure(c providers.Config) {
 	p.keepBonuses = c.KeepBonus
 	p.debug = c.Debug
+	p.logger = c.GetLogger(p.Name())
 	if p.debug {
 		p.deadline = time.Hour
 	} else {
@@ -159,42 +164,60 @@ func withGetter(g getter) func(p *ArteTV) {
 // Name return the name of the provider
 func (p ArteTV) Name() string { return "artetv" }
 
-// Shows download the shows catalog from the web site.
-func (p *ArteTV) MediaList(ctx context.Context, mm []*providers.MatchRequest) chan *providers.Media {
+// Subfolder is the preferred folder of the media in the destinations, when sources are kept apart
+func (p ArteTV) Subfolder() string { return "Arte" }
+
+// Capabilities gives the features supported by the provider
+func (p ArteTV) Capabilities() providers.Capabilities {
+	return providers.Capabilities{HLS: true, Thumbnails: true}
+}
+
+// MediaList download the shows catalog from the web site.
+func (p *ArteTV) MediaList(ctx context.Context, mm []*providers.MatchRequest) (chan *providers.Media, <-chan error) {
 	shows := make(chan *providers.Media)
+	errc := make(chan error, 1)
 
 	go func() {
-		defer close(shows)
+		var errs providers.Errors
+		defer func() {
+			providers.CloseMediaList(shows, errc, errs.Err())
+		}()
 		for _, m := range mm {
 			if ctx.Err() != nil {
 				return
 			}
 			if m.Provider == p.Name() {
-				for s := range p.getShowList(ctx, m) {
+				list, listErrc := p.getShowList(ctx, m)
+				for s := range list {
 					shows <- s
 				}
+				if err := <-listErrc; err != nil {
+					errs = append(errs, err)
+				}
 			}
 		}
 	}()
 
-	return shows
+	return shows, errc
 }
 
-func (p *ArteTV) getShowList(ctx context.Context, mr *providers.MatchRequest) chan *providers.Media {
+func (p *ArteTV) getShowList(ctx context.Context, mr *providers.MatchRequest) (chan *providers.Media, <-chan error) {
 	shows := make(chan *providers.Media)
+	errc := make(chan error, 1)
 
 	go func() {
+		var err error
 		defer func() {
-			close(shows)
-
+			providers.CloseMediaList(shows, errc, err)
 		}()
 
 		//TODO: use user's preferred language
 		const apiSEARCH = "https://www.arte.tv/guide/api/emac/v3/fr/web/data/SEARCH_LISTING"
 
-		u, err := url.Parse(apiSEARCH)
+		var u *url.URL
+		u, err = url.Parse(apiSEARCH)
 		if err != nil {
-			log.Printf("[%s] Can't call search API: %q", p.Name(), err)
+			err = fmt.Errorf("Can't call search API: %w", err)
 			return
 		}
 		v := u.Query()
@@ -207,16 +230,15 @@ func (p *ArteTV) getShowList(ctx context.Context, mr *providers.MatchRequest) ch
 
 		u.RawQuery = v.Encode()
 
-		if p.debug {
-			log.Printf("[%s] Search url: %q", p.Name(), u.String())
-		}
+		p.logger.Debugf("Search url: %q", u.String())
 
 		var result APIResult
 		ctxLocal, doneLocal := context.WithTimeout(ctx, p.deadline)
 
-		r, err := p.getter.Get(ctxLocal, u.String())
+		var r io.ReadCloser
+		r, err = p.getter.Get(ctxLocal, u.String())
 		if err != nil {
-			log.Printf("[%s] Can't call search API: %q", p.Name(), err)
+			err = fmt.Errorf("Can't call search API: %w", err)
 			doneLocal()
 			return
 		}
@@ -229,7 +251,7 @@ func (p *ArteTV) getShowList(ctx context.Context, mr *providers.MatchRequest) ch
 
 		err = json.NewDecoder(r).Decode(&result)
 		if err != nil {
-			log.Printf("[%s] Can't decode search API result: %q", p.Name(), err)
+			err = fmt.Errorf("Can't decode search API result: %w", err)
 			doneLocal()
 			return
 		}
@@ -244,14 +266,14 @@ func (p *ArteTV) getShowList(ctx context.Context, mr *providers.MatchRequest) ch
 		matchedShows := []Data{}
 
 		for _, d := range result.Data {
-			if strings.Contains(strings.ToLower(d.Title), mr.Show) {
+			if providers.ContainsForMatch(d.Title, mr.Show) {
 				if !p.keepBonuses && d.Kind.Code != "SHOW" {
 					continue
 				}
 				if d.Kind.IsCollection {
 					matchedSeries = append(matchedSeries, d)
 				} else {
-					if strings.ToLower(d.Title) == mr.Show {
+					if providers.NormalizeForMatch(d.Title) == providers.NormalizeForMatch(mr.Show) {
 						matchedShows = append(matchedShows, d)
 					}
 				}
@@ -274,7 +296,7 @@ func (p *ArteTV) getShowList(ctx context.Context, mr *providers.MatchRequest) ch
 			}
 		}
 	}()
-	return shows
+	return shows, errc
 }
 
 func (p *ArteTV) getShows(ctx context.Context, mr *providers.MatchRequest, data []Data) chan *providers.Media {
@@ -297,11 +319,13 @@ func (p *ArteTV) getShows(ctx context.Context, mr *providers.MatchRequest, data
 							Type: "ARTETV",
 						},
 					},
-					Title: ep.Title,
-					Plot:  ep.ShortDescription,
-					Thumb: getThumbs(ep.Images),
+					Title:    ep.Title,
+					Plot:     ep.ShortDescription,
+					Thumb:    getThumbs(ep.Images),
+					Duration: ep.duration(),
 					// TVShow:    &tvshow,
-					Tag: []string{"Arte"},
+					Tag:    []string{"Arte"},
+					Studio: "Arte",
 				},
 			}
 			// TODO Actors
@@ -363,16 +387,14 @@ func (p *ArteTV) getSerie(ctx context.Context, mr *providers.MatchRequest, d Dat
 
 				u2, err := url.Parse(u)
 				if err != nil {
-					log.Printf("[%s] Can't get collection: %q", p.Name(), err)
+					p.logger.Errorf("Can't get collection: %s", err)
 					return
 				}
 				u2.Host = "www.arte.tv"
 				u2.Path = "guide/api/emac/v3/fr/web/data/COLLECTION_VIDEOS"
 				u = u2.String()
 
-				if p.debug {
-					log.Println(u)
-				}
+				p.logger.Debugf("Collection url %q", u)
 
 				r, err := p.getter.Get(ctx, u)
 
@@ -380,7 +402,7 @@ func (p *ArteTV) getSerie(ctx context.Context, mr *providers.MatchRequest, d Dat
 					r = httptest.DumpReaderToFile(r, "artetv-getcollection-")
 				}
 				if err != nil {
-					log.Printf("[%s] Can't get collection: %q", p.Name(), err)
+					p.logger.Errorf("Can't get collection: %s", err)
 					return
 				}
 				if ctx.Err() != nil {
@@ -390,14 +412,14 @@ func (p *ArteTV) getSerie(ctx context.Context, mr *providers.MatchRequest, d Dat
 				err = json.NewDecoder(r).Decode(&result)
 				r.Close()
 				if err != nil {
-					log.Printf("[%s] Can't get decode collection: %q", p.Name(), err)
+					p.logger.Errorf("Can't get decode collection: %s", err)
 					return
 				}
 
 				if len(result.Data) == 0 {
 					// A collection of collection (a series, indeed) entry hasn't any Data. We have to fetch collections for each season
 					if seasonSearched {
-						log.Printf("[%s] Can't found collection with ID(%s): %q", p.Name(), d.ProgramID, err)
+						p.logger.Errorf("Can't found collection with ID(%s): %s", d.ProgramID, err)
 						return
 					}
 					seasonSearched = true
@@ -423,7 +445,7 @@ func (p *ArteTV) getSerie(ctx context.Context, mr *providers.MatchRequest, d Dat
 
 					err := parser.Visit(d.URL)
 					if err != nil {
-						log.Printf("[%s] Can't get collection: %q", p.Name(), err)
+						p.logger.Errorf("Can't get collection: %s", err)
 						return
 					}
 					continue collectionLoop
@@ -449,8 +471,10 @@ func (p *ArteTV) getSerie(ctx context.Context, mr *providers.MatchRequest, d Dat
 							Showtitle: d.Title,
 							Plot:      ep.ShortDescription,
 							Thumb:     getThumbs(ep.Images),
+							Duration:  ep.duration(),
 							TVShow:    &tvshow,
 							Tag:       []string{"Arte"},
+							Studio:    "Arte",
 						},
 					}
 					setEpisodeFormTitle(&info, ep.Title)
@@ -561,18 +585,18 @@ func getBestImage(image Image) string {
 
 const arteDetails = "https://api.arte.tv/api/player/v1/config/fr/%s?autostart=1&lifeCycle=1" // Player to get Video streams ProgID
 
-// GetMediaDetails return the show's URL, a mp4 file
+// GetMediaDetails return the show's URL, a mp4 file or a HLS stream. The variant of the
+// requested quality is selected in HLS master playlists, as francetv does.
 func (p *ArteTV) GetMediaDetails(ctx context.Context, m *providers.Media) error {
 	info := m.Metadata.GetMediaInfo()
 
 	if info.URL != "" {
+		m.StreamType = streamType(info.URL)
 		return nil
 	}
 
 	url := fmt.Sprintf(arteDetails, m.ID)
-	if p.debug {
-		log.Println(url)
-	}
+	p.logger.Debugf("Player url %q", url)
 	r, err := p.getter.Get(ctx, url)
 	if err != nil {
 		return fmt.Errorf("Can't get show's detailled information: %w", err)
@@ -589,6 +613,19 @@ func (p *ArteTV) GetMediaDetails(ctx context.Context, m *providers.Media) error
 
 	info.URL = p.getBestVideo(player.VideoJSONPlayer.VSR)
 	info.Aired = nfo.Aired(player.VideoJSONPlayer.VRA.Time())
+	m.StreamType = streamType(info.URL)
+	if m.StreamType == providers.StreamHLS && m.Match != nil && len(m.Match.Quality) > 0 {
+		master, err := m3u8.NewMaster(ctx, info.URL, p.getter)
+		if err != nil {
+			return fmt.Errorf("Can't get master playlist: %w", err)
+		}
+		if master.IsMaster() {
+			info.URL, err = master.Quality(m.Match.Quality)
+			if err != nil {
+				return fmt.Errorf("Can't select stream quality: %w", err)
+			}
+		}
+	}
 
 	if info.TVShow != nil && !info.TVShow.HasEpisodes && info.Episode == 0 {
 		info.Season = info.Aired.Time().Year()
@@ -596,6 +633,14 @@ func (p *ArteTV) GetMediaDetails(ctx context.Context, m *providers.Media) error
 	return nil
 }
 
+// streamType tells if the url is a HLS stream or a mp4 file
+func streamType(u string) string {
+	if strings.Contains(strings.ToLower(u), ".m3u8") {
+		return providers.StreamHLS
+	}
+	return providers.StreamMP4
+}
+
 type mapStrInt map[string]uint64
 
 // getBestVideo return the best video stream given preferences
@@ -613,8 +658,32 @@ func (p *ArteTV) getBestVideo(ss map[string]StreamInfo) string {
 			}
 		}
 	}
-	if p.debug {
-		log.Printf("[%s] Couldn't find a suitable stream", p.Name())
-	}
+	p.logger.Debugf("Couldn't find a suitable stream")
 	return ""
 }
+
+// Download the media into destPath. Arte serves either mp4 files or HLS streams.
+func (p *ArteTV) Download(ctx context.Context, m *providers.Media, destPath string, progress providers.ProgressFunc) error {
+	info := m.Metadata.GetMediaInfo()
+	if len(info.URL) == 0 {
+		err := p.GetMediaDetails(ctx, m)
+		if err != nil {
+			return err
+		}
+	}
+	if len(info.URL) == 0 {
+		return fmt.Errorf("Can't get stream url for %q", m.ID)
+	}
+	if streamType(info.URL) == providers.StreamHLS {
+		return download.HLS(ctx, info.URL, destPath,
+			download.WithGetter(p.getter),
+			download.WithProgress(progress),
+			download.WithDebug(p.debug),
+		)
+	}
+	return download.HTTP(ctx, info.URL, destPath,
+		download.WithGetter(p.getter),
+		download.WithProgress(progress),
+		download.WithDebug(p.debug),
+	)
+}
@@ -0,0 +1,48 @@
+package artetv
+
+import (
+	"context"
+	"testing"
+
+	"github.com/simulot/aspiratv/metadata/nfo"
+	"github.com/simulot/aspiratv/providers"
+)
+
+func TestGetMediaDetailsStream(t *testing.T) {
+	player := func(u string) string {
+		return `{"videoJsonPlayer":{"VRA":"01/05/2020 20:45:00 +0000","VSR":{"HTTPS_SQ_1":{"quality":"SQ","versionCode":"VF","url":"` + u + `"}}}}`
+	}
+	master := "#EXTM3U\n" +
+		"#EXT-X-STREAM-INF:BANDWIDTH=800000,RESOLUTION=640x360\nlow.m3u8\n" +
+		"#EXT-X-STREAM-INF:BANDWIDTH=2500000,RESOLUTION=1280x720\nhigh.m3u8\n"
+	tests := []struct {
+		name       string
+		url        string
+		quality    string
+		wantURL    string
+		wantStream string
+	}{
+		{"mp4 file", "http://example.com/video.mp4", "", "http://example.com/video.mp4", providers.StreamMP4},
+		{"hls stream", "http://example.com/master.m3u8", "", "http://example.com/master.m3u8", providers.StreamHLS},
+		{"hls quality", "http://example.com/master.m3u8", "360p", "http://example.com/low.m3u8", providers.StreamHLS},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, _ := New()
+			WithGetter(providers.StaticGetter(map[string]string{
+				"https://api.arte.tv/":           player(tt.url),
+				"http://example.com/master.m3u8": master,
+			}))(p)
+			m := &providers.Media{ID: "083668-012-A", Metadata: &nfo.Movie{}, Match: &providers.MatchRequest{Quality: tt.quality}}
+			if err := p.GetMediaDetails(context.TODO(), m); err != nil {
+				t.Fatal(err)
+			}
+			if got := m.Metadata.GetMediaInfo().URL; got != tt.wantURL {
+				t.Errorf("Expecting url %q, got %q", tt.wantURL, got)
+			}
+			if m.StreamType != tt.wantStream {
+				t.Errorf("Expecting stream type %q, got %q", tt.wantStream, m.StreamType)
+			}
+		})
+	}
+}
@@ -102,6 +102,14 @@ type Zones struct {
 	Data           []Data         `json:"data"`
 }
 
+// duration gives the program's duration, given in seconds by the API. Zero when unknown.
+func (d Data) duration() time.Duration {
+	if s, ok := d.Duration.(float64); ok {
+		return time.Duration(s) * time.Second
+	}
+	return 0
+}
+
 // tsGuide read broadcast time
 var utcTZ, _ = time.LoadLocation("UTC")
 
@@ -0,0 +1,57 @@
+package providers
+
+import "strings"
+
+// AudioVersion is an audio track of the stream, given by its own playlist
+type AudioVersion struct {
+	Language         string // Language code, as given by the stream
+	Name             string
+	Default          bool   // The stream's primary audio
+	AudioDescription bool   // Audio description for visually impaired people
+	URL              string // Playlist of the audio track
+}
+
+// SelectAudio gives the audio versions to keep: the main audio in the language, followed by its audio
+// description when description is true and one is available.
+// The main audio is the primary one when language is empty or not available.
+// Nothing is selected when versions are unknown.
+func SelectAudio(versions []AudioVersion, language string, description bool) []AudioVersion {
+	main, best := -1, -1
+	for i, v := range versions {
+		if v.AudioDescription {
+			continue
+		}
+		score := 0
+		if len(language) > 0 && sameLanguage(v.Language, language) {
+			score += 2
+		}
+		if v.Default {
+			score++
+		}
+		if score > best {
+			main, best = i, score
+		}
+	}
+	if main < 0 {
+		return nil
+	}
+	selected := []AudioVersion{versions[main]}
+	if description {
+		for _, v := range versions {
+			if v.AudioDescription && sameLanguage(v.Language, versions[main].Language) {
+				selected = append(selected, v)
+				break
+			}
+		}
+	}
+	return selected
+}
+
+// sameLanguage compares language codes, ISO 639-1 or ISO 639-2 like fr, fra or fre
+func sameLanguage(a, b string) bool {
+	a, b = strings.ToLower(a), strings.ToLower(b)
+	if len(a) < 2 || len(b) < 2 {
+		return a == b
+	}
+	return a[:2] == b[:2]
+}
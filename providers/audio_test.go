@@ -0,0 +1,41 @@
+package providers
+
+import "testing"
+
+func TestSelectAudio(t *testing.T) {
+	versions := []AudioVersion{
+		{Language: "en", Name: "English", URL: "en"},
+		{Language: "fr", Name: "Audiodescription", AudioDescription: true, URL: "fr-ad"},
+		{Language: "fr", Name: "Français", Default: true, URL: "fr"},
+		{Language: "de", Name: "Deutsch", URL: "de"},
+	}
+	tests := []struct {
+		name        string
+		versions    []AudioVersion
+		language    string
+		description bool
+		want        []string
+	}{
+		{"primary", versions, "", false, []string{"fr"}},
+		{"french", versions, "fr", false, []string{"fr"}},
+		{"french with description", versions, "fra", true, []string{"fr", "fr-ad"}},
+		{"german", versions, "de", false, []string{"de"}},
+		{"no german description", versions, "de", true, []string{"de"}},
+		{"unavailable language", versions, "it", false, []string{"fr"}},
+		{"only description", versions[1:2], "fr", true, nil},
+		{"unknown", nil, "fr", true, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SelectAudio(tt.versions, tt.language, tt.description)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Expecting %v, got %+v", tt.want, got)
+			}
+			for i := range got {
+				if got[i].URL != tt.want[i] {
+					t.Errorf("Expecting %v, got %+v", tt.want, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package providers
+
+// Capabilities tells the features supported by a provider, so a frontend can enable the features
+// per provider instead of calling methods the provider can't fulfill.
+type Capabilities struct {
+	Search      bool // The provider has a search engine, see francetv's Search
+	HealthCheck bool // HealthCheck actually reaches the backend
+	HLS         bool // Streams are HLS playlists
+	DASH        bool // Some streams are MPEG-DASH manifests
+	Subtitles   bool // Media may have subtitles
+	Thumbnails  bool // Media have thumbnails
+	Chapters    bool // Media may have chapter markers
+}
+
+// Features gives the names of the supported features
+func (c Capabilities) Features() []string {
+	fs := []string{}
+	for _, f := range []struct {
+		name string
+		ok   bool
+	}{
+		{"search", c.Search},
+		{"health check", c.HealthCheck},
+		{"hls", c.HLS},
+		{"dash", c.DASH},
+		{"subtitles", c.Subtitles},
+		{"thumbnails", c.Thumbnails},
+		{"chapters", c.Chapters},
+	} {
+		if f.ok {
+			fs = append(fs, f.name)
+		}
+	}
+	return fs
+}
@@ -0,0 +1,166 @@
+package providers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/simulot/aspiratv/metadata/nfo"
+)
+
+// CatalogVersion is the version of the catalog JSON schema written by ExportJSON
+const CatalogVersion = 1
+
+// Catalog is the JSON document written by ExportJSON:
+//
+//	{
+//	  "version": 1,
+//	  "media": [ CatalogEntry... ]
+//	}
+type Catalog struct {
+	Version int            `json:"version"`
+	Media   []CatalogEntry `json:"media"`
+}
+
+// CatalogEntry is the JSON schema of an exported media. Dates are written in RFC3339,
+// the duration is given in seconds. Empty fields are omitted.
+type CatalogEntry struct {
+	ID        string          `json:"id"`
+	Provider  string          `json:"provider,omitempty"`
+	Type      string          `json:"type"`           // series or movie
+	Kind      string          `json:"kind,omitempty"` // episode, extract or bonus
+	Show      string          `json:"show,omitempty"`
+	Title     string          `json:"title"`
+	Season    int             `json:"season,omitempty"`
+	Episode   int             `json:"episode,omitempty"`
+	Plot      string          `json:"plot,omitempty"`
+	Aired     string          `json:"aired,omitempty"` // RFC3339
+	Duration  int             `json:"duration,omitempty"`
+	Channel   string          `json:"channel,omitempty"`
+	Genres    []string        `json:"genres,omitempty"`
+	URL       string          `json:"url,omitempty"`
+	DRM       bool            `json:"drm,omitempty"`
+	Subtitles []SubtitleTrack `json:"subtitles,omitempty"`
+	UniqueIDs []CatalogID     `json:"unique_ids,omitempty"`
+}
+
+// CatalogID is a media identifier given by the provider
+type CatalogID struct {
+	ID   string `json:"id"`
+	Type string `json:"type,omitempty"`
+}
+
+var showTypeNames = map[ShowType]string{
+	Series: "series",
+	Movie:  "movie",
+}
+
+// ExportJSON writes the media list as a Catalog
+func ExportJSON(w io.Writer, medias []*Media) error {
+	c := Catalog{
+		Version: CatalogVersion,
+		Media:   make([]CatalogEntry, 0, len(medias)),
+	}
+	for _, m := range medias {
+		c.Media = append(c.Media, newCatalogEntry(m))
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	err := enc.Encode(c)
+	if err != nil {
+		return fmt.Errorf("Can't export catalog: %w", err)
+	}
+	return nil
+}
+
+// newCatalogEntry describes the media with the catalog schema
+func newCatalogEntry(m *Media) CatalogEntry {
+	e := CatalogEntry{
+		ID:        m.ID,
+		Type:      showTypeNames[m.ShowType],
+		Kind:      m.Kind,
+		DRM:       m.DRM,
+		Subtitles: m.Subtitles,
+	}
+	if m.Match != nil {
+		e.Provider = m.Match.Provider
+	}
+	if m.Metadata != nil {
+		info := m.Metadata.GetMediaInfo()
+		e.Show = info.Showtitle
+		e.Title = info.Title
+		e.Season = info.Season
+		e.Episode = info.Episode
+		e.Plot = info.Plot
+		if t := info.Aired.Time(); !t.IsZero() {
+			e.Aired = t.Format(time.RFC3339)
+		}
+		e.Duration = int(info.Duration / time.Second)
+		e.Channel = info.Studio
+		e.Genres = info.Genre
+		e.URL = info.URL
+		for _, id := range info.UniqueID {
+			e.UniqueIDs = append(e.UniqueIDs, CatalogID{ID: id.ID, Type: id.Type})
+		}
+	}
+	return e
+}
+
+// ImportJSON reads a Catalog written by ExportJSON and gives back its media.
+// The provider of the media is kept in their Match request.
+func ImportJSON(r io.Reader) ([]*Media, error) {
+	var c Catalog
+	err := json.NewDecoder(r).Decode(&c)
+	if err != nil {
+		return nil, fmt.Errorf("Can't import catalog: %w", err)
+	}
+	if c.Version != CatalogVersion {
+		return nil, fmt.Errorf("Can't import catalog: unsupported version %d", c.Version)
+	}
+	medias := make([]*Media, 0, len(c.Media))
+	for _, e := range c.Media {
+		info := nfo.MediaInfo{
+			Showtitle: e.Show,
+			Title:     e.Title,
+			Season:    e.Season,
+			Episode:   e.Episode,
+			Plot:      e.Plot,
+			Duration:  time.Duration(e.Duration) * time.Second,
+			Studio:    e.Channel,
+			Genre:     e.Genres,
+			URL:       e.URL,
+		}
+		for _, id := range e.UniqueIDs {
+			info.UniqueID = append(info.UniqueID, nfo.ID{ID: id.ID, Type: id.Type})
+		}
+		if len(e.Aired) > 0 {
+			t, err := time.Parse(time.RFC3339, e.Aired)
+			if err != nil {
+				return nil, fmt.Errorf("Can't import media %q: %w", e.ID, err)
+			}
+			info.Aired = nfo.Aired(t)
+		}
+		m := &Media{
+			ID:        e.ID,
+			Kind:      e.Kind,
+			Subtitles: e.Subtitles,
+			DRM:       e.DRM,
+		}
+		if len(e.Provider) > 0 {
+			m.Match = &MatchRequest{Provider: e.Provider}
+		}
+		switch e.Type {
+		case "series":
+			m.ShowType = Series
+			m.SetMetaData(&nfo.EpisodeDetails{MediaInfo: info})
+		case "movie":
+			m.ShowType = Movie
+			m.SetMetaData(&nfo.Movie{MediaInfo: info})
+		default:
+			return nil, fmt.Errorf("Can't import media %q: unknown type %q", e.ID, e.Type)
+		}
+		medias = append(medias, m)
+	}
+	return medias, nil
+}
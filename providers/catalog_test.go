@@ -0,0 +1,90 @@
+package providers
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/simulot/aspiratv/metadata/nfo"
+)
+
+func TestCatalogJSON(t *testing.T) {
+	aired := time.Date(2020, 5, 1, 0, 0, 0, 0, time.UTC)
+	medias := []*Media{
+		{
+			ID:       "123",
+			ShowType: Series,
+			Kind:     KindExtract,
+			Match:    &MatchRequest{Provider: "francetv"},
+			Metadata: &nfo.EpisodeDetails{MediaInfo: nfo.MediaInfo{
+				Showtitle: "Télématin",
+				Title:     "Edition du 1er mai",
+				Season:    2020,
+				Episode:   5,
+				Aired:     nfo.Aired(aired),
+				Duration:  52 * time.Minute,
+				Studio:    "France 2",
+				Genre:     []string{"Magazine"},
+				UniqueID:  []nfo.ID{{ID: "123", Type: "FRANCETV:ID"}},
+			}},
+			Subtitles: []SubtitleTrack{{Language: "fr", URL: "http://example.com/st.vtt", Format: "vtt"}},
+		},
+		{
+			ID:       "456",
+			ShowType: Movie,
+			Metadata: &nfo.Movie{MediaInfo: nfo.MediaInfo{Title: "Cyrano de Bergerac"}},
+			DRM:      true,
+		},
+	}
+
+	b := bytes.Buffer{}
+	if err := ExportJSON(&b, medias); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), `"aired": "2020-05-01T00:00:00Z"`) {
+		t.Errorf("Expecting aired date in RFC3339, got %s", b.String())
+	}
+
+	got, err := ImportJSON(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(medias) {
+		t.Fatalf("Expecting %d media, got %d", len(medias), len(got))
+	}
+	for i := range medias {
+		if got[i].ID != medias[i].ID || got[i].ShowType != medias[i].ShowType || got[i].DRM != medias[i].DRM || got[i].Kind != medias[i].Kind {
+			t.Errorf("Media %d: expecting %+v, got %+v", i, medias[i], got[i])
+		}
+		if !reflect.DeepEqual(got[i].Metadata, medias[i].Metadata) {
+			t.Errorf("Media %d: expecting metadata %+v, got %+v", i, medias[i].Metadata, got[i].Metadata)
+		}
+		if !reflect.DeepEqual(got[i].Subtitles, medias[i].Subtitles) {
+			t.Errorf("Media %d: expecting subtitles %+v, got %+v", i, medias[i].Subtitles, got[i].Subtitles)
+		}
+	}
+	if got[0].Match == nil || got[0].Match.Provider != "francetv" {
+		t.Errorf("Expecting provider to be kept, got %+v", got[0].Match)
+	}
+}
+
+func TestImportJSONErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+	}{
+		{"not json", `catalog`},
+		{"unknown version", `{"version":2,"media":[]}`},
+		{"unknown type", `{"version":1,"media":[{"id":"1","type":"podcast","title":"x"}]}`},
+		{"bad date", `{"version":1,"media":[{"id":"1","type":"movie","title":"x","aired":"2020-05-01"}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ImportJSON(strings.NewReader(tt.doc)); err == nil {
+				t.Errorf("Expecting an error")
+			}
+		})
+	}
+}
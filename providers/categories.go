@@ -0,0 +1,55 @@
+package providers
+
+import (
+	"context"
+	"sort"
+	"strings"
+)
+
+func isCategoryIn(genres []string, categories []string) bool {
+	for _, g := range genres {
+		g = NormalizeForMatch(g)
+		if len(g) == 0 {
+			continue
+		}
+		for _, c := range categories {
+			if NormalizeForMatch(c) == g {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Categories scans the media listed for the requests and gives the distinct categories found
+// in their genres, sorted. Categories differing only by case or accents are given once.
+func Categories(ctx context.Context, p Provider, mm []*MatchRequest) ([]string, error) {
+	seen := map[string]string{}
+	medias, errc := p.MediaList(ctx, mm)
+	for m := range medias {
+		if m.Metadata == nil {
+			continue
+		}
+		for _, g := range m.Metadata.GetMediaInfo().Genre {
+			k := NormalizeForMatch(g)
+			if _, ok := seen[k]; len(k) > 0 && !ok {
+				seen[k] = strings.TrimSpace(g)
+			}
+		}
+	}
+	var err error
+	if ctx.Err() == nil {
+		err = <-errc
+	} else {
+		err = ctx.Err()
+	}
+
+	categories := make([]string, 0, len(seen))
+	for _, c := range seen {
+		categories = append(categories, c)
+	}
+	sort.Slice(categories, func(i, j int) bool {
+		return NormalizeForMatch(categories[i]) < NormalizeForMatch(categories[j])
+	})
+	return categories, err
+}
@@ -0,0 +1,86 @@
+package providers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/simulot/aspiratv/metadata/nfo"
+)
+
+func TestIsMediaMatchCategories(t *testing.T) {
+	tests := []struct {
+		name       string
+		categories []string
+		genres     []string
+		want       bool
+	}{
+		{"no filter", nil, []string{"Séries & fictions"}, true},
+		{"same", []string{"Documentaires"}, []string{"Documentaires"}, true},
+		{"case and accents", []string{"series & fictions"}, []string{"Jeunesse", "Séries & Fictions"}, true},
+		{"other category", []string{"Jeunesse"}, []string{"Documentaires"}, false},
+		{"no genre", []string{"Jeunesse"}, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Media{
+				Match:    &MatchRequest{Categories: tt.categories},
+				Metadata: &nfo.Movie{MediaInfo: nfo.MediaInfo{Genre: tt.genres}},
+			}
+			if got := IsMediaMatch(m); got != tt.want {
+				t.Errorf("IsMediaMatch() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsMediaMatchTags(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter []string
+		tags   []string
+		genres []string
+		want   bool
+	}{
+		{"no filter", nil, []string{"Policier"}, nil, true},
+		{"any tag", []string{"thriller", "policier"}, []string{"Séries & fictions", "Policier"}, nil, true},
+		{"case and accents", []string{"enquete"}, []string{"Enquête"}, nil, true},
+		{"other tag", []string{"Comédie"}, []string{"Policier"}, []string{"Comédie"}, false},
+		{"genres without tags", []string{"comedie"}, nil, []string{"Comédie"}, true},
+		{"no tag", []string{"Policier"}, nil, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Media{
+				Match:    &MatchRequest{Tags: tt.filter},
+				Metadata: &nfo.Movie{MediaInfo: nfo.MediaInfo{Genre: tt.genres}},
+				Tags:     tt.tags,
+			}
+			if got := IsMediaMatch(m); got != tt.want {
+				t.Errorf("IsMediaMatch() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCategories(t *testing.T) {
+	movie := func(genres ...string) *Media {
+		return &Media{Metadata: &nfo.Movie{MediaInfo: nfo.MediaInfo{Genre: genres}}}
+	}
+	p := &listProvider{
+		mm: []*Media{
+			movie("Séries & fictions", "Policier"),
+			movie("Documentaires"),
+			movie("series & fictions", ""),
+			{},
+		},
+	}
+	got, err := Categories(context.TODO(), p, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"Documentaires", "Policier", "Séries & fictions"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Categories() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,43 @@
+package providers
+
+import "strings"
+
+var channelReplacer = strings.NewReplacer(" ", "", "-", "", "_", "", ":", "", "é", "e", "è", "e", "ô", "o")
+
+// channelAliases maps usual short names onto channel codes
+var channelAliases = map[string]string{
+	"f2":           "france2",
+	"f3":           "france3",
+	"f4":           "france4",
+	"f5":           "france5",
+	"fo":           "franceo",
+	"1ere":         "la1ere",
+	"fi":           "franceinfo",
+	"finfo":        "franceinfo",
+	"franceinfotv": "franceinfo",
+	"m6":           "m6",
+	"6play":        "m6",
+	"artetv":       "arte",
+}
+
+// NormalizeChannel turns a channel name or code into its code: "France 2" and "france-2" give france2
+func NormalizeChannel(c string) string {
+	c = channelReplacer.Replace(strings.ToLower(strings.TrimSpace(c)))
+	if a, ok := channelAliases[c]; ok {
+		return a
+	}
+	return c
+}
+
+func isChannelIn(c string, channels []string) bool {
+	if len(c) == 0 {
+		return false
+	}
+	c = NormalizeChannel(c)
+	for _, ch := range channels {
+		if NormalizeChannel(ch) == c {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+package providers
+
+import (
+	"context"
+	"sync"
+)
+
+// StartAfter skips the media listed up to the media with the given ID, so a scan resumes where it was interrupted.
+// Media listed before are held back: they are emitted once the input is closed, when the ID isn't found
+// because the catalog has changed. All media are emitted when id is empty.
+func StartAfter(ctx context.Context, in <-chan *Media, id string) chan *Media {
+	out := make(chan *Media)
+	go func() {
+		defer close(out)
+		found := len(id) == 0
+		held := []*Media{}
+		emit := func(m *Media) bool {
+			select {
+			case <-ctx.Done():
+				// Drain the input to release the producer
+				for range in {
+				}
+				return false
+			case out <- m:
+				return true
+			}
+		}
+		for m := range in {
+			if !found {
+				if m.ID == id {
+					found = true
+					held = nil
+					continue
+				}
+				held = append(held, m)
+				continue
+			}
+			if !emit(m) {
+				return
+			}
+		}
+		for _, m := range held {
+			if !emit(m) {
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// ScanProgress tracks the media of a scan processed out of order, like concurrent downloads.
+// Its cursor is the last media processed along with all the media listed before it, so a scan
+// resumed after the cursor doesn't miss a media still pending. Its methods can be called concurrently.
+type ScanProgress struct {
+	mu    sync.Mutex
+	first int      // Sequence number of ids[0]
+	ids   []string // Media listed after the cursor
+	done  []bool
+}
+
+// Add records the next listed media, and gives its sequence number for Done
+func (s *ScanProgress) Add(id string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ids = append(s.ids, id)
+	s.done = append(s.done, false)
+	return s.first + len(s.ids) - 1
+}
+
+// Done tells that the media seq is processed. It gives the new cursor and the number of media
+// it has moved past, 0 when the cursor hasn't moved because a media listed before is still pending.
+func (s *ScanProgress) Done(seq int) (string, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := seq - s.first
+	if i < 0 || i >= len(s.done) {
+		return "", 0
+	}
+	s.done[i] = true
+	cursor, n := "", 0
+	for len(s.done) > 0 && s.done[0] {
+		cursor = s.ids[0]
+		s.ids, s.done = s.ids[1:], s.done[1:]
+		s.first++
+		n++
+	}
+	return cursor, n
+}
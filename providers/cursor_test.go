@@ -0,0 +1,66 @@
+package providers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestStartAfter(t *testing.T) {
+	tests := []struct {
+		name string
+		id   string
+		want []string
+	}{
+		{"no cursor", "", []string{"1", "2", "3", "4"}},
+		{"resumed", "2", []string{"3", "4"}},
+		{"last one", "4", []string{}},
+		{"not found", "9", []string{"1", "2", "3", "4"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := make(chan *Media)
+			go func() {
+				for _, id := range []string{"1", "2", "3", "4"} {
+					in <- &Media{ID: id}
+				}
+				close(in)
+			}()
+			got := []string{}
+			for m := range StartAfter(context.TODO(), in, tt.id) {
+				got = append(got, m.ID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expecting %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestScanProgress(t *testing.T) {
+	s := ScanProgress{}
+	seq := map[string]int{}
+	for _, id := range []string{"1", "2", "3", "4"} {
+		seq[id] = s.Add(id)
+	}
+	steps := []struct {
+		done   string
+		cursor string
+		n      int
+	}{
+		{"2", "", 0}, // 1 is still pending
+		{"1", "2", 2},
+		{"4", "", 0},
+		{"4", "", 0},
+		{"3", "4", 2},
+	}
+	for _, st := range steps {
+		cursor, n := s.Done(seq[st.done])
+		if cursor != st.cursor || n != st.n {
+			t.Errorf("Done(%s) = %q, %d, want %q, %d", st.done, cursor, n, st.cursor, st.n)
+		}
+	}
+	if got := s.Add("5"); got != 4 {
+		t.Errorf("Add() = %d, want 4", got)
+	}
+}
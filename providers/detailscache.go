@@ -0,0 +1,155 @@
+package providers
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/simulot/aspiratv/metadata/nfo"
+)
+
+// StreamDetails are the details resolved by GetMediaDetails that are kept by the DetailsCache:
+// a copy of the media fields and of its information, like the stream URL or a backfilled title
+type StreamDetails struct {
+	Media    Media         // Media fields, its Match and Metadata excepted
+	Info     nfo.MediaInfo // Information of the media's metadata
+	Replaced bool          // True when the provider has replaced the media's metadata
+}
+
+// restore sets the details into m, keeping its matched request
+func (d StreamDetails) restore(m *Media) {
+	match, metadata := m.Match, m.Metadata
+	*m = d.Media
+	m.Match = match
+	if d.Replaced || metadata == nil {
+		if m.ShowType == Series {
+			metadata = &nfo.EpisodeDetails{}
+		} else {
+			metadata = &nfo.Movie{}
+		}
+	}
+	m.Metadata = metadata
+	*m.Metadata.GetMediaInfo() = d.Info
+}
+
+type detailsEntry struct {
+	details StreamDetails
+	expires time.Time
+}
+
+// DetailsCache keeps the stream details of media for a while, so resolving them again
+// within a run doesn't hit the provider's API. Entries are keyed by media ID and requested quality.
+// Errors aren't kept. A nil cache calls the provider each time.
+type DetailsCache struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]detailsEntry
+}
+
+// NewDetailsCache creates a cache keeping the details for ttl
+func NewDetailsCache(ttl time.Duration) *DetailsCache {
+	return &DetailsCache{
+		ttl:     ttl,
+		now:     time.Now,
+		entries: map[string]detailsEntry{},
+	}
+}
+
+func detailsKey(m *Media) string {
+	if m.Match != nil && len(m.Match.Quality) > 0 {
+		return m.ID + "|" + m.Match.Quality
+	}
+	return m.ID
+}
+
+// GetMediaDetails sets the details of the media from the cache, or calls the provider's GetMediaDetails
+// and keeps the details when it succeeds.
+func (c *DetailsCache) GetMediaDetails(ctx context.Context, p Provider, m *Media) error {
+	if c == nil || len(m.ID) == 0 {
+		return p.GetMediaDetails(ctx, m)
+	}
+	key := detailsKey(m)
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	if ok && !c.now().Before(e.expires) {
+		delete(c.entries, key)
+		ok = false
+	}
+	c.mu.Unlock()
+
+	if ok {
+		e.details.restore(m)
+		return nil
+	}
+
+	metadata := m.Metadata
+	err := p.GetMediaDetails(ctx, m)
+	if err != nil {
+		return err
+	}
+	d := StreamDetails{Media: *m, Replaced: m.Metadata != metadata}
+	d.Media.Match, d.Media.Metadata = nil, nil
+	if m.Metadata != nil {
+		d.Info = *m.Metadata.GetMediaInfo()
+	}
+	c.mu.Lock()
+	c.entries[key] = detailsEntry{
+		details: d,
+		expires: c.now().Add(c.ttl),
+	}
+	c.mu.Unlock()
+	return nil
+}
+
+// Invalidate forgets the details of the media, when its stream URL may have expired
+func (c *DetailsCache) Invalidate(m *Media) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	delete(c.entries, detailsKey(m))
+	c.mu.Unlock()
+}
+
+// Refresh forgets the details of the media and resolves them again
+func (c *DetailsCache) Refresh(ctx context.Context, p Provider, m *Media) error {
+	c.Invalidate(m)
+	m.Metadata.GetMediaInfo().URL = ""
+	return c.GetMediaDetails(ctx, p, m)
+}
+
+// CachedProvider is a provider whose details are kept in a DetailsCache.
+// When a download fails, the details are resolved again and the download is retried once.
+type CachedProvider struct {
+	Provider
+	Cache *DetailsCache
+}
+
+// WithDetailsCache wraps the provider with the cache
+func WithDetailsCache(p Provider, c *DetailsCache) *CachedProvider {
+	return &CachedProvider{Provider: p, Cache: c}
+}
+
+// GetMediaDetails gets the details from the cache, or from the provider
+func (p *CachedProvider) GetMediaDetails(ctx context.Context, m *Media) error {
+	return p.Cache.GetMediaDetails(ctx, p.Provider, m)
+}
+
+// Download downloads the media, and retries with freshly resolved details when it fails
+func (p *CachedProvider) Download(ctx context.Context, m *Media, destPath string, progress ProgressFunc) error {
+	if len(m.Metadata.GetMediaInfo().URL) == 0 {
+		if err := p.GetMediaDetails(ctx, m); err != nil {
+			return err
+		}
+	}
+	err := p.Provider.Download(ctx, m, destPath, progress)
+	if err == nil || ctx.Err() != nil || p.Cache == nil {
+		return err
+	}
+	if err := p.Cache.Refresh(ctx, p.Provider, m); err != nil {
+		return err
+	}
+	return p.Provider.Download(ctx, m, destPath, progress)
+}
@@ -0,0 +1,153 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/simulot/aspiratv/metadata/nfo"
+)
+
+// resolvingProvider gives a new stream url each time details are resolved,
+// and fails downloads of the first urls
+type resolvingProvider struct {
+	fakeProvider
+	resolved  int
+	downloads int
+	failUntil int
+}
+
+func (p *resolvingProvider) GetMediaDetails(ctx context.Context, m *Media) error {
+	p.resolved++
+	m.Metadata.GetMediaInfo().URL = fmt.Sprintf("https://example.com/%s/%d.m3u8", m.ID, p.resolved)
+	m.StreamType = StreamHLS
+	return nil
+}
+
+func (p *resolvingProvider) Download(ctx context.Context, m *Media, dest string, progress ProgressFunc) error {
+	p.downloads++
+	if p.resolved <= p.failUntil {
+		return errors.New("url expired")
+	}
+	return nil
+}
+
+func newCachedMedia(id string) *Media {
+	return &Media{ID: id, Metadata: &nfo.EpisodeDetails{}}
+}
+
+func TestDetailsCache(t *testing.T) {
+	now := time.Date(2020, 5, 1, 20, 0, 0, 0, time.UTC)
+	c := NewDetailsCache(time.Hour)
+	c.now = func() time.Time { return now }
+	p := &resolvingProvider{}
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		m := newCachedMedia("1")
+		if err := c.GetMediaDetails(ctx, p, m); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := m.Metadata.GetMediaInfo().URL, "https://example.com/1/1.m3u8"; got != want {
+			t.Errorf("URL = %q, want %q", got, want)
+		}
+	}
+	if p.resolved != 1 {
+		t.Errorf("Expecting details to be resolved once, got %d", p.resolved)
+	}
+
+	c.GetMediaDetails(ctx, p, newCachedMedia("2"))
+	if p.resolved != 2 {
+		t.Errorf("Expecting details of an other media to be resolved, got %d", p.resolved)
+	}
+
+	now = now.Add(2 * time.Hour)
+	c.GetMediaDetails(ctx, p, newCachedMedia("1"))
+	if p.resolved != 3 {
+		t.Errorf("Expecting expired details to be resolved again, got %d", p.resolved)
+	}
+
+	c.Invalidate(newCachedMedia("1"))
+	c.GetMediaDetails(ctx, p, newCachedMedia("1"))
+	if p.resolved != 4 {
+		t.Errorf("Expecting invalidated details to be resolved again, got %d", p.resolved)
+	}
+
+	var nilCache *DetailsCache
+	nilCache.GetMediaDetails(ctx, p, newCachedMedia("1"))
+	if p.resolved != 5 {
+		t.Errorf("Expecting a nil cache to resolve details each time, got %d", p.resolved)
+	}
+}
+
+func TestCachedProviderDownload(t *testing.T) {
+	tests := []struct {
+		name          string
+		failUntil     int
+		wantErr       bool
+		wantDownloads int
+		wantResolved  int
+	}{
+		{"success", 0, false, 1, 1},
+		{"expired url", 1, false, 2, 2},
+		{"failing", 2, true, 2, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &resolvingProvider{failUntil: tt.failUntil}
+			cp := WithDetailsCache(p, NewDetailsCache(time.Hour))
+			err := cp.Download(context.Background(), newCachedMedia("1"), "dest.mp4", nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Download() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if p.downloads != tt.wantDownloads || p.resolved != tt.wantResolved {
+				t.Errorf("Got %d downloads and %d resolutions, want %d and %d", p.downloads, p.resolved, tt.wantDownloads, tt.wantResolved)
+			}
+		})
+	}
+}
+
+// backfillingProvider replaces the metadata of media without title, like francetv's media built by GetShowByID
+type backfillingProvider struct {
+	fakeProvider
+	resolved int
+}
+
+func (p *backfillingProvider) GetMediaDetails(ctx context.Context, m *Media) error {
+	p.resolved++
+	meta := &nfo.EpisodeDetails{}
+	meta.Showtitle, meta.Title = "Les Lapins Crétins", "Lapin chef"
+	meta.URL = "https://example.com/" + m.ID + ".m3u8"
+	m.SetMetaData(meta)
+	m.ShowType = Series
+	m.StreamType = StreamHLS
+	m.Chapters = []Chapter{{Title: "Opening", End: time.Minute}}
+	m.AudioVersions = []AudioVersion{{Language: "fr", AudioDescription: true}}
+	return nil
+}
+
+func TestDetailsCacheRestore(t *testing.T) {
+	c := NewDetailsCache(time.Hour)
+	p := &backfillingProvider{}
+	for i := 0; i < 2; i++ {
+		m := &Media{ID: "1", ShowType: Movie, Metadata: &nfo.Movie{}, Match: &MatchRequest{}}
+		if err := c.GetMediaDetails(context.Background(), p, m); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := m.Metadata.(*nfo.EpisodeDetails); !ok || m.ShowType != Series {
+			t.Errorf("Expecting episode metadata, got %T", m.Metadata)
+		}
+		info := m.Metadata.GetMediaInfo()
+		if info.Title != "Lapin chef" || info.URL != "https://example.com/1.m3u8" {
+			t.Errorf("Unexpected information %+v", info)
+		}
+		if len(m.Chapters) != 1 || len(m.AudioVersions) != 1 || m.Match == nil {
+			t.Errorf("Unexpected media %+v", m)
+		}
+	}
+	if p.resolved != 1 {
+		t.Errorf("Expecting details to be resolved once, got %d", p.resolved)
+	}
+}
@@ -0,0 +1,68 @@
+package providers
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/simulot/aspiratv/metadata/nfo"
+)
+
+// AlreadyDownloaded checks if the media is already present into the library.
+// The media is searched by its file name, then with its file name matcher to catch
+// episodes named before their season and episode numbers were known. At last,
+// the NFO files of the series are searched for the media ID.
+func AlreadyDownloaded(m *Media, libraryRoot string) (bool, error) {
+	mediaPath := m.Metadata.GetMediaPath(libraryRoot)
+	if _, err := os.Stat(mediaPath); err == nil {
+		return true, nil
+	}
+
+	pattern := m.Metadata.GetMediaPathMatcher(libraryRoot)
+	files, err := filepath.Glob(pattern)
+	if err != nil {
+		return false, fmt.Errorf("Can't glob %s: %w", pattern, err)
+	}
+	if len(files) > 0 {
+		return true, nil
+	}
+
+	if m.ShowType != Series || len(m.ID) == 0 {
+		return false, nil
+	}
+	pattern = filepath.Join(m.Metadata.GetSeriesPath(libraryRoot), "*", "*.nfo")
+	files, err = filepath.Glob(pattern)
+	if err != nil {
+		return false, fmt.Errorf("Can't glob %s: %w", pattern, err)
+	}
+	for _, f := range files {
+		ids, err := readNFOIDs(f)
+		if err != nil {
+			continue
+		}
+		for _, id := range ids {
+			if id.ID == m.ID {
+				video := strings.TrimSuffix(f, filepath.Ext(f)) + filepath.Ext(mediaPath)
+				if _, err := os.Stat(video); err == nil {
+					return true, nil
+				}
+			}
+		}
+	}
+	return false, nil
+}
+
+func readNFOIDs(nfoPath string) ([]nfo.ID, error) {
+	f, err := os.Open(nfoPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	ids := struct {
+		UniqueID []nfo.ID `xml:"uniqueid"`
+	}{}
+	err = xml.NewDecoder(f).Decode(&ids)
+	return ids.UniqueID, err
+}
@@ -0,0 +1,105 @@
+package providers
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/simulot/aspiratv/metadata/nfo"
+)
+
+func TestAlreadyDownloaded(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aspiratv-library-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	touch := func(p string) {
+		os.MkdirAll(filepath.Dir(p), 0777)
+		if err := ioutil.WriteFile(p, []byte{}, 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	episode := func(id string, season, episode int, title string) *Media {
+		return &Media{
+			ID:       id,
+			ShowType: Series,
+			Metadata: &nfo.EpisodeDetails{
+				MediaInfo: nfo.MediaInfo{
+					Showtitle: "Les Dalton",
+					Title:     title,
+					Season:    season,
+					Episode:   episode,
+					Aired:     nfo.Aired(time.Date(2020, 5, 1, 0, 0, 0, 0, time.UTC)),
+					UniqueID:  []nfo.ID{{ID: id, Type: "TEST:ID"}},
+				},
+			},
+		}
+	}
+
+	// Exact name
+	exact := episode("1", 1, 2, "Le pique-nique")
+	touch(exact.Metadata.GetMediaPath(dir))
+
+	// Media downloaded without episode number
+	dated := episode("2", 0, 0, "La diligence")
+	touch(dated.Metadata.GetMediaPath(dir))
+
+	// Media named before the time and the id were part of the aired tag
+	touch(filepath.Join(dir, "Les Dalton", "Season 00", "Les Dalton - 2020-05-01 - La caravane.mp4"))
+	touch(filepath.Join(dir, "Les Dalton", "Season 00", "Les Dalton - 2020-05-01.mp4"))
+	broadcast := func(id, title string) *Media {
+		m := episode(id, 0, 0, title)
+		m.Metadata.GetMediaInfo().Aired = nfo.Aired(time.Date(2020, 5, 1, 20, 45, 0, 0, time.UTC))
+		return m
+	}
+
+	// Media downloaded without title, identified by its NFO
+	untitled := episode("3", 0, 0, "")
+	touch(untitled.Metadata.GetMediaPath(dir))
+	if err := WriteNFO(untitled, untitled.Metadata.GetMediaPath(dir)); err != nil {
+		t.Fatal(err)
+	}
+
+	movie := func(title string, year int) *Media {
+		return &Media{
+			ShowType: Movie,
+			Metadata: &nfo.Movie{MediaInfo: nfo.MediaInfo{Title: title, Year: year}},
+		}
+	}
+	touch(movie("Cyrano de Bergerac", 1990).Metadata.GetMediaPath(dir))
+
+	// Movie of a library made before the year was part of the name
+	touch(filepath.Join(dir, "Les Tontons flingueurs", "Les Tontons flingueurs.mp4"))
+
+	tests := []struct {
+		name string
+		m    *Media
+		want bool
+	}{
+		{"exact", exact, true},
+		{"numbered later", episode("2", 1, 5, "La diligence"), true},
+		{"identified by NFO", episode("3", 1, 6, "Le train"), true},
+		{"not downloaded", episode("4", 1, 7, "Le bateau"), false},
+		{"named by date only", broadcast("5", "La caravane"), true},
+		{"untitled named by date only", broadcast("6", ""), true},
+		{"other title the same day", broadcast("7", "Le bateau"), false},
+		{"movie", movie("Cyrano de Bergerac", 1990), true},
+		{"movie named without year", movie("Les Tontons flingueurs", 1963), true},
+		{"movie not downloaded", movie("La Grande Vadrouille", 1966), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := AlreadyDownloaded(tt.m, dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("AlreadyDownloaded() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
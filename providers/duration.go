@@ -0,0 +1,32 @@
+package providers
+
+import (
+	"fmt"
+	"time"
+)
+
+// TextDuration is a time.Duration written as a string like "1h30m" in JSON configuration
+type TextDuration time.Duration
+
+// Duration converts TextDuration to time.Duration
+func (t TextDuration) Duration() time.Duration {
+	return time.Duration(t)
+}
+
+// MarshalJSON writes the duration as a string
+func (t TextDuration) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + time.Duration(t).String() + `"`), nil
+}
+
+// UnmarshalJSON reads a duration given as a string
+func (t *TextDuration) UnmarshalJSON(b []byte) error {
+	if len(b) > 1 && b[0] == '"' {
+		b = b[1 : len(b)-1]
+	}
+	v, err := time.ParseDuration(string(b))
+	if err != nil {
+		return fmt.Errorf("Can't parse duration: %w", err)
+	}
+	*t = TextDuration(v)
+	return nil
+}
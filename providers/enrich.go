@@ -0,0 +1,70 @@
+package providers
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+// Errors aggregates errors returned by concurrent calls
+type Errors []error
+
+func (e Errors) Error() string {
+	s := make([]string, len(e))
+	for i, err := range e {
+		s[i] = err.Error()
+	}
+	return strings.Join(s, "; ")
+}
+
+// Err returns nil when there is no error, the error itself when there is only one
+func (e Errors) Err() error {
+	switch len(e) {
+	case 0:
+		return nil
+	case 1:
+		return e[0]
+	}
+	return e
+}
+
+// EnrichMedia calls p.GetMediaDetails for each media with at most concurrency calls in flight.
+// Each media is updated by one goroutine only. All errors are returned together.
+func EnrichMedia(ctx context.Context, p Provider, mm []*Media, concurrency int) error {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	var (
+		wg   sync.WaitGroup
+		lock sync.Mutex
+		errs Errors
+	)
+	sem := make(chan struct{}, concurrency)
+
+	for _, m := range mm {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return ctx.Err()
+		case sem <- struct{}{}:
+		}
+		wg.Add(1)
+		go func(m *Media) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			err := p.GetMediaDetails(ctx, m)
+			if err != nil {
+				lock.Lock()
+				errs = append(errs, err)
+				lock.Unlock()
+			}
+		}(m)
+	}
+	wg.Wait()
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type detailProvider struct {
+	fakeProvider
+	inFlight int32
+	maxSeen  int32
+}
+
+func (p *detailProvider) GetMediaDetails(ctx context.Context, m *Media) error {
+	n := atomic.AddInt32(&p.inFlight, 1)
+	defer atomic.AddInt32(&p.inFlight, -1)
+	for {
+		max := atomic.LoadInt32(&p.maxSeen)
+		if n <= max || atomic.CompareAndSwapInt32(&p.maxSeen, max, n) {
+			break
+		}
+	}
+	time.Sleep(5 * time.Millisecond)
+	if m.ID == "bad" {
+		return errors.New("bad media")
+	}
+	m.ShowType = Movie
+	return nil
+}
+
+func TestEnrichMedia(t *testing.T) {
+	p := &detailProvider{}
+	mm := []*Media{}
+	for i := 0; i < 20; i++ {
+		mm = append(mm, &Media{ID: "ok", ShowType: Series})
+	}
+	mm = append(mm, &Media{ID: "bad"}, &Media{ID: "bad"})
+
+	err := EnrichMedia(context.TODO(), p, mm, 4)
+	errs, ok := err.(Errors)
+	if !ok || len(errs) != 2 {
+		t.Errorf("Expecting 2 errors, got %v", err)
+	}
+	if p.maxSeen > 4 {
+		t.Errorf("Expecting at most %d calls in flight, got %d", 4, p.maxSeen)
+	}
+	for _, m := range mm {
+		if m.ID == "ok" && m.ShowType != Movie {
+			t.Errorf("Expecting media to be updated")
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package providers
+
+import (
+	"regexp"
+	"strconv"
+)
+
+var (
+	compactSeasonEpisode = regexp.MustCompile(`(?i)\bS(\d{1,3})\s?E(\d{1,4})\b`)                               // S02E05, S2 E5
+	frenchSeasonEpisode  = regexp.MustCompile(`(?i)\bSaison\s+(\d{1,3})\W+(?:[ée]pisode|ep\.?)\s*(\d{1,4})\b`) // Saison 2 épisode 5, Saison 2 - Ep 5
+	frenchEpisode        = regexp.MustCompile(`(?i)(?:^|[^\pL\d])[ée]pisode\s*(\d{1,4})\b`)                    // Épisode 5
+	frenchSeason         = regexp.MustCompile(`(?i)\bSaison\s+(\d{1,3})\b`)                                    // Saison 2
+)
+
+// Part patterns, applied on titles normalized by NormalizeForMatch
+var (
+	numberedPart = regexp.MustCompile(`\bpart(?:ie)?\s*(\d{1,2})(?:\s*(?:/|sur|of|de)\s*(\d{1,2}))?\b`) // Partie 1, Partie 1/2, Part 2 of 3
+	ordinalPart  = regexp.MustCompile(`\b(\d{1,2})\s*(?:er|ere|re|e|eme|nd|nde)\s+partie\b`)            // 1ère partie, 2e partie
+	wordPart     = regexp.MustCompile(`\b(premiere|deuxieme|seconde|troisieme|quatrieme|cinquieme)\s+partie\b`)
+	partNumbers  = map[string]int{"premiere": 1, "deuxieme": 2, "seconde": 2, "troisieme": 3, "quatrieme": 4, "cinquieme": 5}
+)
+
+// ParsePart searches the part number of a media split in several parts, like "Partie 1/2",
+// "Part 2 of 3", "1ère partie" or "Deuxième partie". total is 0 when not given.
+// ok is false when nothing is found.
+func ParsePart(title string) (part, total int, ok bool) {
+	t := NormalizeForMatch(title)
+	if m := numberedPart.FindStringSubmatch(t); m != nil {
+		part, _ = strconv.Atoi(m[1])
+		total, _ = strconv.Atoi(m[2])
+		return part, total, part > 0
+	}
+	if m := ordinalPart.FindStringSubmatch(t); m != nil {
+		part, _ = strconv.Atoi(m[1])
+		return part, 0, part > 0
+	}
+	if m := wordPart.FindStringSubmatch(t); m != nil {
+		return partNumbers[m[1]], 0, true
+	}
+	return 0, 0, false
+}
+
+// ParseSeasonEpisode searches season and episode numbers in a title.
+// Compact notation like "S02E05" and French notations like "Saison 2 épisode 5"
+// or "Épisode 5" are recognized. Numbers are returned without leading zeros, and
+// are empty when not found. ok is false when nothing is found.
+func ParseSeasonEpisode(title string) (season, episode string, ok bool) {
+	if m := compactSeasonEpisode.FindStringSubmatch(title); m != nil {
+		return trimNumber(m[1]), trimNumber(m[2]), true
+	}
+	if m := frenchSeasonEpisode.FindStringSubmatch(title); m != nil {
+		return trimNumber(m[1]), trimNumber(m[2]), true
+	}
+	if m := frenchEpisode.FindStringSubmatch(title); m != nil {
+		episode = trimNumber(m[1])
+	}
+	if m := frenchSeason.FindStringSubmatch(title); m != nil {
+		season = trimNumber(m[1])
+	}
+	return season, episode, len(season) > 0 || len(episode) > 0
+}
+
+func trimNumber(s string) string {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return s
+	}
+	return strconv.Itoa(n)
+}
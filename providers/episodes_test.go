@@ -0,0 +1,59 @@
+package providers
+
+import "testing"
+
+func TestParseSeasonEpisode(t *testing.T) {
+	testCases := []struct {
+		title   string
+		season  string
+		episode string
+		ok      bool
+	}{
+		{"S02E05", "2", "5", true},
+		{"Les Dalton S1 E12 - La ruée", "1", "12", true},
+		{"Saison 2 épisode 5", "2", "5", true},
+		{"Saison 3, Épisode 10 : Le retour", "3", "10", true},
+		{"ÉPISODE 7", "", "7", true},
+		{"Épisode 5", "", "5", true},
+		{"Les Dalton - Épisode 5", "", "5", true},
+		{"Saison 4", "4", "", true},
+		{"Un dimanche à la campagne", "", "", false},
+		{"Les Aventures de SOS", "", "", false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			season, episode, ok := ParseSeasonEpisode(tc.title)
+			if season != tc.season || episode != tc.episode || ok != tc.ok {
+				t.Errorf("Expecting (%q, %q, %v), got (%q, %q, %v)", tc.season, tc.episode, tc.ok, season, episode, ok)
+			}
+		})
+	}
+}
+
+func TestParsePart(t *testing.T) {
+	testCases := []struct {
+		title string
+		part  int
+		total int
+		ok    bool
+	}{
+		{"Partie 1", 1, 0, true},
+		{"Les Misérables - Partie 2/2", 2, 2, true},
+		{"Part 2 of 3", 2, 3, true},
+		{"PARTIE 3 sur 4", 3, 4, true},
+		{"1ère partie", 1, 0, true},
+		{"Les Misérables (2e partie)", 2, 0, true},
+		{"Première partie", 1, 0, true},
+		{"Deuxième partie : le retour", 2, 0, true},
+		{"La partie de campagne", 0, 0, false},
+		{"Un dimanche à la campagne", 0, 0, false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			part, total, ok := ParsePart(tc.title)
+			if part != tc.part || total != tc.total || ok != tc.ok {
+				t.Errorf("Expecting (%d, %d, %v), got (%d, %d, %v)", tc.part, tc.total, tc.ok, part, total, ok)
+			}
+		})
+	}
+}
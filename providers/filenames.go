@@ -1,29 +1,107 @@
 package providers
 
-import "strings"
+import (
+	"fmt"
+	"strings"
 
+	"github.com/simulot/aspiratv/metadata/nfo"
+)
+
+// CleanerConfig gives the rules used to make names safe for the file system, see nfo.CleanerConfig
+type CleanerConfig = nfo.CleanerConfig
+
+// Cleaners rules of providers matching the historical behavior, they differ slightly from nfo's ones
 var (
-	fileNameReplacer = strings.NewReplacer("/", "-", "\\", "-", "!", "", "?", "", ":", "-", ",", "", "*", "-", "|", "-", "\"", "", ">", "", "<", "")
-	pathNameReplacer = strings.NewReplacer("!", "", "?", "", ":", " ", ",", " ", "*", "", "|", " ", "\"", "", ">", "", "<", "", " - ", " ")
+	defaultFileCleanerConfig = CleanerConfig{
+		Replacements: map[string]string{"/": "-", "\\": "-", "!": "", "?": "", ":": "-", ",": "", "*": "-", "|": "-", "\"": "", ">": "", "<": ""},
+	}
+	defaultPathCleanerConfig = CleanerConfig{
+		Replacements: map[string]string{"!": "", "?": "", ":": " ", ",": " ", "*": "", "|": " ", "\"": "", ">": "", "<": "", " - ": " "},
+	}
+)
+
+var (
+	fileNameCleaner = nfo.NewCleaner(defaultFileCleanerConfig)
+	pathNameCleaner = nfo.NewCleaner(defaultPathCleanerConfig)
+	cleaners        = CleanerPresets["default"] // Current rules, see SetCleaners
 )
 
 // FileNameCleaner return a safe file name from a given show name.
 func FileNameCleaner(s string) string {
-	return strings.TrimSpace(fileNameReplacer.Replace(s))
+	return fileNameCleaner.Clean(s)
 }
 
 // PathNameCleaner return a safe path name from a given show name.
 func PathNameCleaner(s string) string {
-	if i := strings.Index(s, ":"); i >= 0 && i < 2 {
-		return s[:i] + strings.TrimSpace(pathNameReplacer.Replace(s[i:]))
+	return pathNameCleaner.CleanPath(s)
+}
+
+// CleanerPreset gives the rules of the names made by providers and of the names made by nfo
+type CleanerPreset struct {
+	File, Path       CleanerConfig // Rules of FileNameCleaner and PathNameCleaner
+	NFOFile, NFOPath CleanerConfig // Rules of nfo.FileNameCleaner and nfo.PathNameCleaner
+}
+
+// CleanerPresets gives the rules by name. The default preset keeps the historical names.
+var CleanerPresets = map[string]CleanerPreset{
+	"default": {
+		File: defaultFileCleanerConfig, Path: defaultPathCleanerConfig,
+		NFOFile: nfo.DefaultFileCleanerConfig, NFOPath: nfo.DefaultPathCleanerConfig,
+	},
+	"windows": {
+		File: nfo.WindowsFileCleanerConfig, Path: nfo.WindowsPathCleanerConfig,
+		NFOFile: nfo.WindowsFileCleanerConfig, NFOPath: nfo.WindowsPathCleanerConfig,
+	},
+}
+
+// SetCleaners replaces the rules used to name media files and directories
+func SetCleaners(p CleanerPreset) {
+	cleaners = p
+	fileNameCleaner = nfo.NewCleaner(p.File)
+	pathNameCleaner = nfo.NewCleaner(p.Path)
+	nfo.FileCleaner = nfo.NewCleaner(p.NFOFile)
+	nfo.PathCleaner = nfo.NewCleaner(p.NFOPath)
+}
+
+// CleanerOption changes the rules of a preset, see SetCleanerPreset
+type CleanerOption func(*CleanerConfig)
+
+// WithTransliteration spells names with ASCII characters when enabled, "Cœur brisé" gives "Coeur brise",
+// for file systems or players not supporting accented names. Accented names are kept by default.
+func WithTransliteration(enabled bool) CleanerOption {
+	return func(c *CleanerConfig) {
+		c.ASCII = enabled
 	}
-	return strings.TrimSpace(pathNameReplacer.Replace(s))
 }
 
-// Format2Digits return a number with 2 digits when there is only one digit
-func Format2Digits(d string) string {
-	if len(d) < 2 {
-		return "0" + d
+// SetCleanerPreset applies the named preset, with maxLength limiting the length of path components when not zero
+func SetCleanerPreset(name string, maxLength int, options ...CleanerOption) error {
+	p, ok := CleanerPresets[strings.ToLower(name)]
+	if !ok {
+		return fmt.Errorf("Can't set file name rules: unknown preset %q", name)
 	}
-	return d
+	updateCleaners(p, maxLength, options)
+	return nil
+}
+
+// UpdateCleaners changes the current rules, with maxLength limiting the length of path components when not zero
+func UpdateCleaners(maxLength int, options ...CleanerOption) {
+	updateCleaners(cleaners, maxLength, options)
+}
+
+func updateCleaners(p CleanerPreset, maxLength int, options []CleanerOption) {
+	for _, c := range []*CleanerConfig{&p.File, &p.Path, &p.NFOFile, &p.NFOPath} {
+		if maxLength > 0 {
+			c.MaxLength = maxLength
+		}
+		for _, o := range options {
+			o(c)
+		}
+	}
+	SetCleaners(p)
+}
+
+// Format2Digits return a number with at least 2 digits, see nfo.Format2Digits
+func Format2Digits(d string) string {
+	return nfo.Format2Digits(d)
 }
@@ -0,0 +1,69 @@
+package providers
+
+import (
+	"testing"
+
+	"github.com/simulot/aspiratv/metadata/nfo"
+)
+
+func TestSetCleanerPreset(t *testing.T) {
+	file, path, nfoFile, nfoPath, current := fileNameCleaner, pathNameCleaner, nfo.FileCleaner, nfo.PathCleaner, cleaners
+	defer func() {
+		fileNameCleaner, pathNameCleaner, nfo.FileCleaner, nfo.PathCleaner, cleaners = file, path, nfoFile, nfoPath, current
+	}()
+
+	if err := SetCleanerPreset("fat12", 0); err == nil {
+		t.Errorf("Expecting an error for an unknown preset")
+	}
+	if err := SetCleanerPreset("Windows", 12); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := nfo.FileNameCleaner("Star Wars: a*b.mp4"), "Star War.mp4"; got != want {
+		t.Errorf("Expecting %q, got %q", want, got)
+	}
+	if got, want := FileNameCleaner("Qui est-ce?"), "Qui est-ce"; got != want {
+		t.Errorf("Expecting %q, got %q", want, got)
+	}
+	if got, want := FileNameCleaner("Télématin"), "Télématin"; got != want {
+		t.Errorf("Expecting %q, got %q", want, got)
+	}
+
+	// Only the length is limited
+	if err := SetCleanerPreset("default", 40); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := FileNameCleaner("Qui, quoi: où?"), "Qui quoi- où"; got != want {
+		t.Errorf("Expecting %q, got %q", want, got)
+	}
+	if got, want := nfo.FileNameCleaner("Qui, quoi: où?"), "Qui, quoi où"; got != want {
+		t.Errorf("Expecting %q, got %q", want, got)
+	}
+	if got, want := PathNameCleaner("Qui - quoi, où?"), "Qui quoi  où"; got != want {
+		t.Errorf("Expecting %q, got %q", want, got)
+	}
+
+	if err := SetCleanerPreset("default", 0, WithTransliteration(true)); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := FileNameCleaner("Télématin: les cœurs"), "Telematin- les coeurs"; got != want {
+		t.Errorf("Expecting %q, got %q", want, got)
+	}
+	if got, want := nfo.PathNameCleaner("/vidéos/Noël"), "/videos/Noel"; got != want {
+		t.Errorf("Expecting %q, got %q", want, got)
+	}
+}
+
+func TestUpdateCleaners(t *testing.T) {
+	file, path, nfoFile, nfoPath, current := fileNameCleaner, pathNameCleaner, nfo.FileCleaner, nfo.PathCleaner, cleaners
+	defer func() {
+		fileNameCleaner, pathNameCleaner, nfo.FileCleaner, nfo.PathCleaner, cleaners = file, path, nfoFile, nfoPath, current
+	}()
+
+	if err := SetCleanerPreset("windows", 0); err != nil {
+		t.Fatal(err)
+	}
+	UpdateCleaners(0, WithTransliteration(true))
+	if got, want := nfo.FileNameCleaner("Télématin: a*b"), "Telematin - a_b"; got != want {
+		t.Errorf("Expecting the windows rules to be kept, %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,225 @@
+package providers
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/simulot/aspiratv/metadata/nfo"
+)
+
+// Filter accepts or rejects media. Filters are built from a MatchRequest by its Filters method,
+// so all providers apply the same criteria.
+type Filter interface {
+	Match(m *Media) bool
+}
+
+// FilterFunc is a function used as a Filter
+type FilterFunc func(m *Media) bool
+
+// Match calls f
+func (f FilterFunc) Match(m *Media) bool { return f(m) }
+
+// namedFilter is a filter described by its name, given by String
+type namedFilter struct {
+	name string
+	f    FilterFunc
+}
+
+func (f namedFilter) Match(m *Media) bool { return f.f(m) }
+func (f namedFilter) String() string      { return f.name }
+
+// Filters is a chain of filters
+type Filters []Filter
+
+// All tells if all filters accept the media. An empty chain accepts all media.
+func (fs Filters) All(m *Media) bool {
+	for _, f := range fs {
+		if !f.Match(m) {
+			return false
+		}
+	}
+	return true
+}
+
+// Rejecting gives the first filter rejecting the media, nil when all filters accept it
+func (fs Filters) Rejecting(m *Media) Filter {
+	for _, f := range fs {
+		if !f.Match(m) {
+			return f
+		}
+	}
+	return nil
+}
+
+// Any tells if one of the filters accepts the media. An empty chain rejects all media.
+func (fs Filters) Any(m *Media) bool {
+	for _, f := range fs {
+		if f.Match(m) {
+			return true
+		}
+	}
+	return false
+}
+
+// Not inverts the filter
+func Not(f Filter) Filter {
+	return namedFilter{fmt.Sprintf("not %v", f), func(m *Media) bool { return !f.Match(m) }}
+}
+
+// info gives the media information, empty when unknown
+func info(m *Media) *nfo.MediaInfo {
+	if m.Metadata == nil {
+		return &nfo.MediaInfo{}
+	}
+	return m.Metadata.GetMediaInfo()
+}
+
+// TitleFilter accepts media whose title contains title, regardless of case and accents
+func TitleFilter(title string) Filter {
+	return namedFilter{fmt.Sprintf("title %q", title), func(m *Media) bool { return ContainsForMatch(info(m).Title, title) }}
+}
+
+// PitchFilter accepts media whose plot contains pitch, regardless of case and accents
+func PitchFilter(pitch string) Filter {
+	return namedFilter{fmt.Sprintf("pitch %q", pitch), func(m *Media) bool { return ContainsForMatch(info(m).Plot, pitch) }}
+}
+
+// ShowRegexpFilter accepts media whose show title matches re. Movies are tested with their title.
+func ShowRegexpFilter(re *regexp.Regexp) Filter {
+	return namedFilter{fmt.Sprintf("show regexp %q", re), func(m *Media) bool {
+		i := info(m)
+		title := i.Showtitle
+		if len(title) == 0 {
+			title = i.Title
+		}
+		return re.MatchString(title)
+	}}
+}
+
+// ChannelFilter accepts media broadcasted on one of the channels, given by name or code
+func ChannelFilter(channels ...string) Filter {
+	return namedFilter{"channel " + strings.Join(channels, ","), func(m *Media) bool { return isChannelIn(info(m).Studio, channels) }}
+}
+
+// CategoryFilter accepts media having one of the categories as genre, regardless of case and accents
+func CategoryFilter(categories ...string) Filter {
+	return namedFilter{"category " + strings.Join(categories, ","), func(m *Media) bool { return isCategoryIn(info(m).Genre, categories) }}
+}
+
+// TagFilter accepts media having one of the tags, regardless of case and accents.
+// The genres are used as tags when the provider doesn't give tags.
+func TagFilter(tags ...string) Filter {
+	return namedFilter{"tag " + strings.Join(tags, ","), func(m *Media) bool {
+		if len(m.Tags) == 0 {
+			return isCategoryIn(info(m).Genre, tags)
+		}
+		return isCategoryIn(m.Tags, tags)
+	}}
+}
+
+// DateRangeFilter accepts media aired within after and before. A zero time doesn't limit the range.
+func DateRangeFilter(after, before time.Time) Filter {
+	return namedFilter{fmt.Sprintf("aired between %s and %s", boundString(after), boundString(before)), func(m *Media) bool {
+		aired := info(m).Aired.Time()
+		if !after.IsZero() && aired.Before(after) {
+			return false
+		}
+		return before.IsZero() || !aired.After(before)
+	}}
+}
+
+func boundString(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Format("2006-01-02")
+}
+
+// DurationFilter accepts media lasting between min and max. A zero value doesn't limit the duration,
+// and media of unknown duration are accepted.
+func DurationFilter(min, max time.Duration) Filter {
+	return namedFilter{fmt.Sprintf("duration between %s and %s", min, max), func(m *Media) bool {
+		d := info(m).Duration
+		if d <= 0 {
+			return true
+		}
+		return (min <= 0 || d >= min) && (max <= 0 || d <= max)
+	}}
+}
+
+// KindFilter accepts full episodes, and extracts and bonuses when extracts is true
+func KindFilter(extracts bool) Filter {
+	return namedFilter{"full episodes", func(m *Media) bool { return extracts || len(m.Kind) == 0 || m.Kind == KindEpisode }}
+}
+
+// AgeFilter accepts media advised for an audience of the given age or younger
+func AgeFilter(age int) Filter {
+	return namedFilter{fmt.Sprintf("audience of %d years", age), func(m *Media) bool { return m.MinAge <= age }}
+}
+
+// BlockedFilter rejects the media having one of the ids
+func BlockedFilter(ids ...string) Filter {
+	return blockedFilter(idSet(ids))
+}
+
+func blockedFilter(blocked map[string]bool) Filter {
+	return namedFilter{fmt.Sprintf("%d blocked ids", len(blocked)), func(m *Media) bool { return !blocked[m.ID] }}
+}
+
+func idSet(ids []string) map[string]bool {
+	set := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		set[strings.TrimSpace(id)] = true
+	}
+	return set
+}
+
+// Filters gives the criteria of the request as a chain of filters. The kind of media
+// and Negate aren't part of the chain, see IsMediaMatch.
+// The chain is built by Compile, it's built for each call when the request isn't compiled.
+// The request is never modified, so it's safe to call concurrently.
+func (mr *MatchRequest) Filters() Filters {
+	if mr.filters != nil {
+		return mr.filters
+	}
+	return mr.buildFilters()
+}
+
+func (mr *MatchRequest) buildFilters() Filters {
+	fs := Filters{}
+	if !mr.AiredAfter.IsZero() || !mr.AiredBefore.IsZero() {
+		fs = append(fs, DateRangeFilter(mr.AiredAfter, mr.AiredBefore))
+	}
+	if mr.MinDuration > 0 || mr.MaxDuration > 0 {
+		fs = append(fs, DurationFilter(mr.MinDuration.Duration(), mr.MaxDuration.Duration()))
+	}
+	if len(mr.Channels) > 0 {
+		fs = append(fs, ChannelFilter(mr.Channels...))
+	}
+	if len(mr.Categories) > 0 {
+		fs = append(fs, CategoryFilter(mr.Categories...))
+	}
+	if len(mr.Tags) > 0 {
+		fs = append(fs, TagFilter(mr.Tags...))
+	}
+	if len(mr.Title) > 0 {
+		fs = append(fs, TitleFilter(mr.Title))
+	}
+	if len(mr.Pitch) > 0 {
+		fs = append(fs, PitchFilter(mr.Pitch))
+	}
+	if len(mr.TitleRegexp) > 0 {
+		re := mr.titleRe
+		if re == nil {
+			var err error
+			re, err = regexp.Compile(mr.TitleRegexp)
+			if err != nil {
+				return Filters{namedFilter{fmt.Sprintf("invalid show regexp %q", mr.TitleRegexp), func(*Media) bool { return false }}}
+			}
+		}
+		fs = append(fs, ShowRegexpFilter(re))
+	}
+	return fs
+}
@@ -0,0 +1,142 @@
+package providers
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/simulot/aspiratv/metadata/nfo"
+)
+
+func TestFilters(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2020, 5, d, 0, 0, 0, 0, time.UTC)
+	}
+	m := &Media{
+		Metadata: &nfo.EpisodeDetails{MediaInfo: nfo.MediaInfo{
+			Showtitle: "Le Journal de 20h",
+			Title:     "Édition spéciale",
+			Studio:    "France 2",
+			Genre:     []string{"Information"},
+			Aired:     nfo.Aired(day(10)),
+			Duration:  40 * time.Minute,
+		}},
+	}
+	tests := []struct {
+		name string
+		f    Filters
+		want bool
+	}{
+		{"empty chain", Filters{}, true},
+		{"title", Filters{TitleFilter("edition speciale")}, true},
+		{"channel", Filters{ChannelFilter("france3", "france2")}, true},
+		{"category", Filters{CategoryFilter("information")}, true},
+		{"genre as tag", Filters{TagFilter("sport", "information")}, true},
+		{"date range", Filters{DateRangeFilter(day(5), day(15))}, true},
+		{"open date range", Filters{DateRangeFilter(time.Time{}, day(5))}, false},
+		{"duration", Filters{DurationFilter(30*time.Minute, 0)}, true},
+		{"too long", Filters{DurationFilter(0, 30*time.Minute)}, false},
+		{"all match", Filters{TitleFilter("spéciale"), ChannelFilter("France 2"), DurationFilter(30*time.Minute, time.Hour)}, true},
+		{"one doesn't match", Filters{TitleFilter("spéciale"), ChannelFilter("France 3")}, false},
+		{"not", Filters{Not(ChannelFilter("France 3"))}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.f.All(m); got != tt.want {
+				t.Errorf("All() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchRequestFilters(t *testing.T) {
+	tests := []struct {
+		name string
+		mr   *MatchRequest
+		want int
+	}{
+		{"no criterion", &MatchRequest{}, 0},
+		{"show and channel", &MatchRequest{TitleRegexp: "^Le Journal", Channels: []string{"france2"}}, 2},
+		{"durations make one filter", &MatchRequest{MinDuration: TextDuration(time.Minute), MaxDuration: TextDuration(time.Hour)}, 1},
+		{"invalid regexp rejects all", &MatchRequest{TitleRegexp: "(("}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := len(tt.mr.Filters()); got != tt.want {
+				t.Errorf("len(Filters()) = %d, want %d", got, tt.want)
+			}
+			if tt.mr.titleRe != nil || tt.mr.filters != nil {
+				t.Errorf("Expecting Filters() to leave the request untouched")
+			}
+		})
+	}
+}
+
+func TestCompiledFilters(t *testing.T) {
+	mr := &MatchRequest{TitleRegexp: "^Le Journal", Channels: []string{"france2"}}
+	if err := mr.Compile(); err != nil {
+		t.Fatal(err)
+	}
+	m := &Media{Metadata: &nfo.EpisodeDetails{MediaInfo: nfo.MediaInfo{Showtitle: "Le Journal de 20h", Studio: "France 2"}}}
+	done := make(chan bool)
+	for i := 0; i < 4; i++ {
+		go func() {
+			done <- mr.Filters().All(m)
+		}()
+	}
+	for i := 0; i < 4; i++ {
+		if !<-done {
+			t.Errorf("Expecting the media to match the compiled request")
+		}
+	}
+	if len(mr.filters) != 2 {
+		t.Errorf("Expecting Compile to build the filters, got %d", len(mr.filters))
+	}
+}
+
+type recordLogger struct {
+	lines []string
+}
+
+func (l *recordLogger) Debugf(format string, args ...interface{}) {
+	l.lines = append(l.lines, fmt.Sprintf(format, args...))
+}
+func (l *recordLogger) Infof(format string, args ...interface{})  {}
+func (l *recordLogger) Warnf(format string, args ...interface{})  {}
+func (l *recordLogger) Errorf(format string, args ...interface{}) {}
+
+func TestMatchLogger(t *testing.T) {
+	l := &recordLogger{}
+	SetMatchLogger(l)
+	defer SetMatchLogger(nil)
+
+	meta := &nfo.EpisodeDetails{MediaInfo: nfo.MediaInfo{Showtitle: "Le Journal", Title: "Edition du 1er mai", Studio: "France 3", Duration: time.Hour}}
+	tests := []struct {
+		name string
+		mr   *MatchRequest
+		kind string
+		want string
+	}{
+		{"accepted", &MatchRequest{Show: "le journal", Channels: []string{"France 3"}}, "", ""},
+		{"channel", &MatchRequest{Show: "le journal", Channels: []string{"france2"}}, "", "channel france2"},
+		{"duration", &MatchRequest{Show: "le journal", MaxDuration: TextDuration(30 * time.Minute)}, "", "duration between 0s and 30m0s"},
+		{"kind", &MatchRequest{Show: "le journal"}, KindBonus, "full episodes"},
+		{"negated", &MatchRequest{Show: "le journal", Negate: true}, "", "negated request"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l.lines = nil
+			IsMediaMatch(&Media{ID: "1", Kind: tt.kind, Match: tt.mr, Metadata: meta})
+			if len(tt.want) == 0 {
+				if len(l.lines) != 0 {
+					t.Errorf("Expecting no log, got %v", l.lines)
+				}
+				return
+			}
+			if len(l.lines) != 1 || !strings.Contains(l.lines[0], tt.want) {
+				t.Errorf("Expecting a log with %q, got %v", tt.want, l.lines)
+			}
+		})
+	}
+}
@@ -7,7 +7,6 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
-	"log"
 	"net/http"
 	"net/url"
 	"regexp"
@@ -87,32 +86,198 @@ type Requests struct {
 	Params    AlgoliaParam `json:"params"`
 }
 
+// hitKinds maps the video types of the catalog onto media kinds. Other types are ignored.
+var hitKinds = map[string]string{
+	"integrale": providers.KindEpisode,
+	"extrait":   providers.KindExtract,
+	"resume":    providers.KindExtract,
+	"bonus":     providers.KindBonus,
+}
+
 const algoliaURL = "https://vwdlashufe-dsn.algolia.net/1/indexes/*/queries"
 
-func (p *FranceTV) queryAlgolia(ctx context.Context, mr *providers.MatchRequest) chan *providers.Media {
+// Catalog indexes: videos, and collections like programs and seasons
+const (
+	contentsIndex   = "yatta_prod_contents"
+	taxonomiesIndex = "yatta_prod_taxonomies"
+)
+
+// searchAlgolia sends one search request to the videos of the catalog and decodes the response
+func (p *FranceTV) searchAlgolia(ctx context.Context, req AlgoliaParam) (query.QueryResults, error) {
+	return p.searchIndex(ctx, contentsIndex, req)
+}
+
+// searchIndex sends one search request to the index and decodes the response
+func (p *FranceTV) searchIndex(ctx context.Context, index string, req AlgoliaParam) (query.QueryResults, error) {
+	results := query.QueryResults{}
+
+	v := url.Values{}
+	v.Set("x-algolia-agent", "Algolia for vanilla JavaScript (lite) 3.27.0;instantsearch.js 2.10.2;JS Helper 2.26.0")
+	v.Set("x-algolia-application-id", p.algolia.AlgoliaAppID)
+	v.Set("x-algolia-api-key", p.algolia.AlgoliaAPIKey)
+
+	u := algoliaURL + "?" + v.Encode()
+	p.logger.Debugf("Search url %q", u)
+
+	w := algoliaRequestWrapper{
+		Requests: []Requests{
+			{
+				IndexName: index,
+				Params:    req,
+			},
+		},
+	}
+	b := bytes.NewBuffer([]byte{})
+	encodeRequest(b, &w) // Special encoding... WTF
+
+	h := make(http.Header)
+	h.Add("Accept", "application/json")
+	h.Add("Accept-Language", "fr-FR,fr;q=0.5")
+	h.Add("Accept-Encoding", "gzip")
+	h.Add("Referer", "https://www.france.tv")
+	h.Add("content-type", "https://www.france.tv")
+	h.Add("Origin", "https://www.france.tv")
+	h.Add("TE", "Trailers")
+	if p.debug {
+		for k, s := range h {
+			p.logger.Debugf("Request header %q %s", k, strings.Join(s, ","))
+		}
+		p.logger.Debugf("Request body %s", b.String())
+	}
+
+	r, err := p.getter.DoWithContext(ctx, "POST", u, h, b)
+	if err != nil {
+		return results, fmt.Errorf("Can't call algolia API: %w", err)
+	}
+	if p.debug {
+		r = httptest.DumpReaderToFile(r, "francetv-algolia-")
+	}
+
+	resp, err := ioutil.ReadAll(r)
+	r.Close()
+	if err != nil {
+		return results, fmt.Errorf("Can't get API result: %w", err)
+	}
+	err = json.Unmarshal(resp, &results)
+	if err != nil {
+		return results, fmt.Errorf("Can't decode API result: %w", err)
+	}
+	p.reportSkipped(results)
+	return results, nil
+}
+
+// reportSkipped logs the catalog entries that couldn't be decoded
+func (p *FranceTV) reportSkipped(results query.QueryResults) {
+	skipped := 0
+	for _, r := range results.Results {
+		for _, err := range r.Skipped {
+			p.logger.Debugf("%s", err)
+		}
+		skipped += len(r.Skipped)
+	}
+	if skipped > 0 {
+		p.logger.Warnf("%d catalog entries skipped, they can't be decoded", skipped)
+	}
+}
+
+// replayFilter selects videos available in replay at ts
+func replayFilter(ts int64) string {
+	return fmt.Sprintf("class:video AND ranges.replay.web.begin_date < %d AND ranges.replay.web.end_date > %d", ts, ts)
+}
+
+// upcomingFilter selects videos that will be available in replay after ts
+func upcomingFilter(ts int64) string {
+	return fmt.Sprintf("class:video AND ranges.replay.web.begin_date > %d", ts)
+}
+
+// queryAlgolia sends the collections whose name matches mr, to be expanded with ExpandCollection,
+// followed by the videos of the catalog matching mr
+func (p *FranceTV) queryAlgolia(ctx context.Context, mr *providers.MatchRequest) (chan *providers.Media, <-chan error) {
 	mm := make(chan *providers.Media)
+	errc := make(chan error, 1)
 
 	go func() {
-		defer close(mm)
-		// ctx, done := context.WithTimeout(ctx, p.deadline)
-		// defer done()
+		var err error
+		defer func() {
+			providers.CloseMediaList(mm, errc, err)
+		}()
+		err = p.queryCollections(ctx, mr, mm)
+		if err != nil {
+			return
+		}
+		medias, verrc := p.queryCatalog(ctx, mr, replayFilter)
+		for m := range medias {
+			select {
+			case mm <- m:
+			case <-ctx.Done():
+				for range medias {
+				}
+			}
+		}
+		err = <-verrc
+	}()
+	return mm, errc
+}
 
-		v := url.Values{}
-		v.Set("x-algolia-agent", "Algolia for vanilla JavaScript (lite) 3.27.0;instantsearch.js 2.10.2;JS Helper 2.26.0")
-		v.Set("x-algolia-application-id", p.algolia.AlgoliaAppID)
-		v.Set("x-algolia-api-key", p.algolia.AlgoliaAPIKey)
+// queryCollections sends the programs and the seasons of the catalog whose name matches mr.
+// They aren't videos, they are searched apart.
+func (p *FranceTV) queryCollections(ctx context.Context, mr *providers.MatchRequest, mm chan<- *providers.Media) error {
+	req := AlgoliaParam{
+		"query":        mr.Show,
+		"hitsPerPage":  "20",
+		"filters":      "class:program",
+		"facetFilters": `[["class:program"]]`,
+		"facets":       "[]",
+		"tagFilters":   "",
+	}
+	for page := 0; ; page++ {
+		req["page"] = strconv.Itoa(page)
+		results, err := p.searchIndex(ctx, taxonomiesIndex, req)
+		if err != nil {
+			return err
+		}
+		for _, r := range results.Results {
+			for _, h := range r.Hits {
+				if !isCollection(h) || !(providers.ContainsForMatch(h.Label, mr.Show) || providers.ContainsForMatch(h.Title, mr.Show)) {
+					continue
+				}
+				select {
+				case mm <- collectionMedia(h, mr):
+				case <-ctx.Done():
+					return nil
+				}
+			}
+		}
+		if len(results.Results) == 0 || page+1 >= results.Results[0].NbPages {
+			return nil
+		}
+	}
+}
 
-		u := algoliaURL + "?" + v.Encode()
+// queryCatalog sends the media of the catalog matching mr, selected with the filter given by filter for the current time
+func (p *FranceTV) queryCatalog(ctx context.Context, mr *providers.MatchRequest, filter func(ts int64) string) (chan *providers.Media, <-chan error) {
+	mm := make(chan *providers.Media)
+	errc := make(chan error, 1)
+
+	go func() {
+		var err error
+		defer func() {
+			providers.CloseMediaList(mm, errc, err)
+		}()
+		// ctx, done := context.WithTimeout(ctx, p.deadline)
+		// defer done()
 
-		if p.debug {
-			log.Printf("[%s] Search url %q", p.Name(), u)
-		}
 		page := 0
+		hits := 0
+		hitsPerPage := 20
+		if p.limit > 0 && p.limit < hitsPerPage {
+			hitsPerPage = p.limit
+		}
 		ts := time.Now().Unix()
 		req := AlgoliaParam{
 			"query":        mr.Show,
-			"hitsPerPage":  "20",
-			"filters":      fmt.Sprintf("class:video AND ranges.replay.web.begin_date < %d AND ranges.replay.web.end_date > %d", ts, ts),
+			"hitsPerPage":  strconv.Itoa(hitsPerPage),
+			"filters":      filter(ts),
 			"facetFilters": `[["class:video"]]`,
 			"facets":       "[]",
 			"tagFilters":   "",
@@ -124,76 +289,45 @@ func (p *FranceTV) queryAlgolia(ctx context.Context, mr *providers.MatchRequest)
 
 		for {
 			req["page"] = strconv.Itoa(page)
-			w := algoliaRequestWrapper{
-				Requests: []Requests{
-					{
-						IndexName: "yatta_prod_contents",
-						Params:    req,
-					},
-				},
-			}
-			_ = w
-			b := bytes.NewBuffer([]byte{})
-			encodeRequest(b, &w) // Special encoding... WTF
-
-			h := make(http.Header)
-			h.Add("Accept", "application/json")
-			h.Add("Accept-Language", "fr-FR,fr;q=0.5")
-			h.Add("Accept-Encoding", "gzip")
-			h.Add("Referer", "https://www.france.tv")
-			h.Add("content-type", "https://www.france.tv")
-			h.Add("Origin", "https://www.france.tv")
-			h.Add("TE", "Trailers")
-			if p.debug {
-				log.Printf("[%s] Request body", p.Name())
-				for k, s := range h {
-					log.Printf("%q %s", k, strings.Join(s, ","))
-				}
-				log.Println(b.String())
-			}
-
-			r, err := p.getter.DoWithContext(ctx, "POST", u, h, b)
-			if err != nil {
-				log.Printf("[%s] Can't call algolia API: %s", p.Name(), err)
-				return
-			}
-			if p.debug {
-				r = httptest.DumpReaderToFile(r, "francetv-algolia-")
-			}
-
-			resp, err := ioutil.ReadAll(r)
+			var results query.QueryResults
+			results, err = p.searchAlgolia(ctx, req)
 			if err != nil {
-				log.Printf("[%s] Can't get API result: %s", p.Name(), err)
 				return
 			}
-			results := query.QueryResults{}
-			err = json.Unmarshal(resp, &results)
-			if err != nil {
-				log.Printf("[%s] Can't decode API result: %s", p.Name(), err)
-				return
-			}
-			r.Close()
 			for resNum := range results.Results {
 				for _, h := range results.Results[resNum].Hits {
-					if h.Type != "integrale" {
+					hits++
+					if p.limit > 0 && hits > p.limit {
+						break
+					}
+					kind, ok := hitKinds[h.Type]
+					if !ok {
 						continue
 					}
 
-					if len(h.Program.Label) > 0 && !strings.Contains(strings.ToLower(h.Program.Label), mr.Show) {
+					if len(h.Program.Label) > 0 && !providers.ContainsForMatch(h.Program.Label, mr.Show) {
 						continue
 					}
 
-					if len(h.Program.Label) == 0 && !strings.Contains(strings.ToLower(h.Title), mr.Show) {
+					if len(h.Program.Label) == 0 && !providers.ContainsForMatch(h.Title, mr.Show) {
 						continue
 					}
 
 					media := &providers.Media{
-						ID:    h.SiID.String(),
-						Match: mr,
+						ID:     h.SiID.String(),
+						Kind:   kind,
+						Match:  mr,
+						MinAge: csaMinAge(h.RatingCsaCode),
+					}
+					if len(h.Program.URLComplete) > 0 {
+						media.ShowURL = homeFranceTV + "/" + strings.Trim(h.Program.URLComplete, "/") + "/"
+					}
+					if d, ok := h.Dates["last_unpublication_date"]; ok {
+						media.Expires = time.Time(d)
 					}
 					var info *nfo.MediaInfo
 
-					if len(h.Program.Label) > 0 {
+					if len(h.Program.Label) > 0 && !isFilm(h) {
 						meta := nfo.EpisodeDetails{}
 						info = &meta.MediaInfo
 						media.SetMetaData(&meta)
@@ -206,9 +340,12 @@ func (p *FranceTV) queryAlgolia(ctx context.Context, mr *providers.MatchRequest)
 					}
 
 					*info = nfo.MediaInfo{
-						Title: h.Title,
-						Plot:  h.Description,
-						Aired: nfo.Aired(h.Dates["broadcast_begin_date"].Time()),
+						Title:    h.Title,
+						Plot:     h.Description,
+						Aired:    nfo.Aired(h.Dates["broadcast_begin_date"].Time().In(p.location)),
+						Duration: h.Duration.Duration(),
+						Year:     h.ProductionYear,
+						MPAA:     nfo.ContentRating(media.MinAge),
 						UniqueID: []nfo.ID{
 							{
 								ID:   strconv.Itoa(h.ID),
@@ -256,18 +393,27 @@ func (p *FranceTV) queryAlgolia(ctx context.Context, mr *providers.MatchRequest)
 					}
 
 					if len(h.Categories) > 0 {
-						info.Genre = make([]string, len(h.Categories))
+						info.Genre = make([]string, 0, len(h.Categories))
 						for i := 0; i < len(h.Categories); i++ {
 							info.Genre = append(info.Genre, h.Categories[i].Label)
 						}
 					}
+					media.Tags = hitTags(h)
+					info.Genre = appendMissing(info.Genre, media.Tags...)
 
 					if len(h.Channels) > 0 {
-						info.Tag = append(info.Tag, h.Channels[0].Label)
+						label := channelLabel(h.Channels[0])
+						info.Tag = append(info.Tag, label)
+						info.Studio = label
+						info.SubChannel = regionOf(h.Channels)
 					}
 
 					info.Season = h.SeasonNumber
 					info.Episode = h.EpisodeNumber
+					if info.Season == 0 || info.Episode == 0 {
+						backfillSeasonEpisode(info, h.Title, h.HeadlineTitle)
+					}
+					backfillPart(info, media.ShowType, h.Title, h.HeadlineTitle)
 					info.Thumb = make([]nfo.Thumb, 0)
 					for k, format := range h.Image.Formats {
 						url := ""
@@ -291,6 +437,9 @@ func (p *FranceTV) queryAlgolia(ctx context.Context, mr *providers.MatchRequest)
 						}
 					}
 
+					if media.ShowType == providers.Movie && info.Year == 0 && !time.Time(info.Aired).IsZero() {
+						info.Year = time.Time(info.Aired).Year()
+					}
 					if media.ShowType == providers.Series {
 						info.SeasonInfo, info.TVShow = p.getProgram(ctx, info.Showtitle, h.Season.ID, h.Program.ID)
 						if !info.IsSpecial {
@@ -299,16 +448,23 @@ func (p *FranceTV) queryAlgolia(ctx context.Context, mr *providers.MatchRequest)
 							}
 						}
 					}
-					mm <- media
+					select {
+					case mm <- media:
+					case <-ctx.Done():
+						return
+					}
 				}
 			}
 			page++
-			if page >= results.Results[0].NbPages {
+			if len(results.Results) == 0 || page >= results.Results[0].NbPages {
+				break
+			}
+			if p.limit > 0 && hits >= p.limit {
 				break
 			}
 		}
 	}()
-	return mm
+	return mm, errc
 }
 
 func (p *FranceTV) getProgram(ctx context.Context, program string, seasonID, programID int) (*nfo.Season, *nfo.TVShow) {
@@ -328,9 +484,7 @@ func (p *FranceTV) getProgram(ctx context.Context, program string, seasonID, pro
 
 	u := algoliaURL + "?" + v.Encode()
 
-	if p.debug {
-		log.Printf("[%s] Search url %q", p.Name(), u)
-	}
+	p.logger.Debugf("Search url %q", u)
 	page := 0
 	req := AlgoliaParam{
 		"query":       program,
@@ -364,16 +518,15 @@ func (p *FranceTV) getProgram(ctx context.Context, program string, seasonID, pro
 		h.Add("Origin", "https://www.france.tv")
 		h.Add("TE", "Trailers")
 		if p.debug {
-			log.Printf("[%s] Request body", p.Name())
 			for k, s := range h {
-				log.Printf("%q %s", k, strings.Join(s, ","))
+				p.logger.Debugf("Request header %q %s", k, strings.Join(s, ","))
 			}
-			log.Println(b.String())
+			p.logger.Debugf("Request body %s", b.String())
 		}
 
 		r, err := p.getter.DoWithContext(ctx, "POST", u, h, b)
 		if err != nil {
-			log.Printf("[%s] Can't call algolia API: %s", p.Name(), err)
+			p.logger.Errorf("Can't call algolia API: %s", err)
 			return nil, nil
 		}
 		if p.debug {
@@ -382,15 +535,16 @@ func (p *FranceTV) getProgram(ctx context.Context, program string, seasonID, pro
 
 		resp, err := ioutil.ReadAll(r)
 		if err != nil {
-			log.Printf("[%s] Can't get API result: %s", p.Name(), err)
+			p.logger.Errorf("Can't get API result: %s", err)
 			return nil, nil
 		}
 		results := query.QueryResults{}
 		err = json.Unmarshal(resp, &results)
 		if err != nil {
-			log.Printf("[%s] Can't decode API result: %s", p.Name(), err)
+			p.logger.Errorf("Can't decode API result: %s", err)
 			return nil, nil
 		}
+		p.reportSkipped(results)
 		r.Close()
 
 		for resNum := range results.Results {
@@ -520,3 +674,127 @@ func encodeHex(b *bytes.Buffer, c byte) {
 	b.WriteByte("0123456789ABCDEF"[c>>4])
 	b.WriteByte("0123456789ABCDEF"[c&15])
 }
+
+// isFilm tells if the hit is a one-off film of a program: it has
+// neither season nor episode, and one of its categories is films or cinema
+func isFilm(h query.Hits) bool {
+	if h.SeasonNumber != 0 || h.EpisodeNumber != 0 {
+		return false
+	}
+	for _, c := range h.Categories {
+		l := providers.NormalizeForMatch(c.Label)
+		if strings.Contains(l, "film") || strings.Contains(l, "cinema") {
+			return true
+		}
+	}
+	return false
+}
+
+// hitTags gives the categories and the tags of the hit, without duplicates
+func hitTags(h query.Hits) []string {
+	tags := []string{}
+	for _, c := range h.Categories {
+		tags = appendMissing(tags, c.Label)
+	}
+	return appendMissing(tags, h.Tags...)
+}
+
+// appendMissing appends the labels not already in list, compared without case and accents
+func appendMissing(list []string, labels ...string) []string {
+	for _, l := range labels {
+		n := providers.NormalizeForMatch(l)
+		if len(n) == 0 {
+			continue
+		}
+		found := false
+		for _, e := range list {
+			if providers.NormalizeForMatch(e) == n {
+				found = true
+				break
+			}
+		}
+		if !found {
+			list = append(list, strings.TrimSpace(l))
+		}
+	}
+	return list
+}
+
+// channelLabel gives the channel name, without the colon of franceinfo's label "franceinfo:"
+func channelLabel(c query.Channels) string {
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(c.Label), ":"))
+}
+
+// regionOf gives the region of France 3 regional channels, like Bretagne for France 3 Bretagne
+func regionOf(channels []query.Channels) string {
+	for _, c := range channels {
+		if c.Type == "region" || strings.HasPrefix(c.URL, "france-3-") {
+			return strings.TrimSpace(strings.TrimPrefix(c.Label, "France 3"))
+		}
+	}
+	return ""
+}
+
+// backfillSeasonEpisode sets season and episode numbers left empty by the API
+// with numbers found in titles.
+func backfillSeasonEpisode(info *nfo.MediaInfo, titles ...string) {
+	for _, t := range titles {
+		season, episode, ok := providers.ParseSeasonEpisode(t)
+		if !ok {
+			continue
+		}
+		if info.Season == 0 && len(season) > 0 {
+			info.Season, _ = strconv.Atoi(season)
+		}
+		if info.Episode == 0 && len(episode) > 0 {
+			info.Episode, _ = strconv.Atoi(episode)
+		}
+		if info.Season != 0 && info.Episode != 0 {
+			return
+		}
+	}
+}
+
+// backfillPart numbers the parts of a media split in several parts, which would get the same file name otherwise.
+// Episodes without number get the part as episode number, movies get it appended to their title.
+func backfillPart(info *nfo.MediaInfo, showType providers.ShowType, titles ...string) {
+	for _, t := range titles {
+		part, _, ok := providers.ParsePart(t)
+		if !ok {
+			continue
+		}
+		if showType == providers.Series {
+			if info.Episode == 0 {
+				info.Episode = part
+			}
+			return
+		}
+		if _, _, inTitle := providers.ParsePart(info.Title); !inTitle {
+			info.Title = fmt.Sprintf("%s - Partie %d", info.Title, part)
+		}
+		return
+	}
+}
+
+// HealthCheck gets the catalog configuration and sends a minimal search request
+func (p *FranceTV) HealthCheck(ctx context.Context) error {
+	err := p.getAlgoliaConfig(ctx)
+	if err != nil {
+		return err
+	}
+	results, err := p.searchAlgolia(ctx, AlgoliaParam{
+		"query":        "",
+		"hitsPerPage":  "1",
+		"page":         "0",
+		"facetFilters": `[["class:video"]]`,
+		"facets":       "[]",
+		"tagFilters":   "",
+	})
+	if err != nil {
+		return err
+	}
+	if len(results.Results) == 0 {
+		return errors.New("Can't get any result from algolia API")
+	}
+	return nil
+}
@@ -1,5 +1,14 @@
 package francetv
 
+import (
+	"reflect"
+	"testing"
+
+	"github.com/simulot/aspiratv/metadata/nfo"
+	"github.com/simulot/aspiratv/providers"
+	"github.com/simulot/aspiratv/providers/francetv/query"
+)
+
 /*
 
 import (
@@ -37,3 +46,87 @@ func Test_getAlgoliaConfig(t *testing.T) {
 
 }
 */
+
+func TestRegionOf(t *testing.T) {
+	tests := []struct {
+		name     string
+		channels []query.Channels
+		want     string
+	}{
+		{"national", []query.Channels{{Type: "channel", Label: "France 3", URL: "france-3"}}, ""},
+		{"regional", []query.Channels{{Type: "channel", Label: "France 3", URL: "france-3"}, {Type: "region", Label: "France 3 Bretagne", URL: "france-3-bretagne"}}, "Bretagne"},
+		{"regional by url", []query.Channels{{Type: "channel", Label: "France 3 Corse ViaStella", URL: "france-3-corse-viastella"}}, "Corse ViaStella"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := regionOf(tt.channels); got != tt.want {
+				t.Errorf("regionOf() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChannelLabel(t *testing.T) {
+	for label, want := range map[string]string{"franceinfo:": "franceinfo", "France 2": "France 2", " franceinfo: ": "franceinfo"} {
+		if got := channelLabel(query.Channels{Label: label}); got != want {
+			t.Errorf("channelLabel(%q) = %q, want %q", label, got, want)
+		}
+	}
+}
+
+func TestIsFilm(t *testing.T) {
+	tests := []struct {
+		name string
+		hit  query.Hits
+		want bool
+	}{
+		{"film", query.Hits{Categories: []query.Categories{{Label: "Films"}}}, true},
+		{"cinema", query.Hits{Categories: []query.Categories{{Label: "Cinéma"}}}, true},
+		{"episode", query.Hits{SeasonNumber: 1, EpisodeNumber: 3, Categories: []query.Categories{{Label: "Films"}}}, false},
+		{"documentary", query.Hits{Categories: []query.Categories{{Label: "Documentaires"}}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isFilm(tt.hit); got != tt.want {
+				t.Errorf("isFilm() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHitTags(t *testing.T) {
+	h := query.Hits{
+		Categories: []query.Categories{{Label: "Séries & fictions"}, {Label: "Policier"}},
+		Tags:       []string{"policier", "Enquête", "Thriller"},
+	}
+	want := []string{"Séries & fictions", "Policier", "Enquête", "Thriller"}
+	if got := hitTags(h); !reflect.DeepEqual(got, want) {
+		t.Errorf("hitTags() = %q, want %q", got, want)
+	}
+}
+
+func TestBackfillPart(t *testing.T) {
+	tests := []struct {
+		name        string
+		showType    providers.ShowType
+		info        nfo.MediaInfo
+		titles      []string
+		wantTitle   string
+		wantEpisode int
+	}{
+		{"episode", providers.Series, nfo.MediaInfo{Title: "Les Misérables"}, []string{"Les Misérables", "Partie 2/2"}, "Les Misérables", 2},
+		{"numbered episode", providers.Series, nfo.MediaInfo{Title: "Les Misérables", Episode: 5}, []string{"Partie 2"}, "Les Misérables", 5},
+		{"movie", providers.Movie, nfo.MediaInfo{Title: "Les Misérables"}, []string{"Les Misérables", "1ère partie"}, "Les Misérables - Partie 1", 0},
+		{"movie with part in title", providers.Movie, nfo.MediaInfo{Title: "Les Misérables, partie 1"}, []string{"Les Misérables, partie 1"}, "Les Misérables, partie 1", 0},
+		{"no part", providers.Movie, nfo.MediaInfo{Title: "Cyrano"}, []string{"Cyrano", "Un film de Jean-Paul Rappeneau"}, "Cyrano", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := tt.info
+			backfillPart(&info, tt.showType, tt.titles...)
+			if info.Title != tt.wantTitle || info.Episode != tt.wantEpisode {
+				t.Errorf("Expecting %q episode %d, got %q episode %d", tt.wantTitle, tt.wantEpisode, info.Title, info.Episode)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package francetv
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/simulot/aspiratv/net/myhttp"
+)
+
+// TokenRefresher gives a new session token when the current one has expired
+type TokenRefresher func(ctx context.Context) (string, error)
+
+// auth holds the session token sent with player and stream requests
+type auth struct {
+	mu      sync.Mutex
+	token   string
+	refresh TokenRefresher
+}
+
+// WithAuth sends the session token with player and stream requests, as needed by premium content
+func WithAuth(token string) func(ftv *FranceTV) {
+	return func(ftv *FranceTV) {
+		if ftv.auth == nil {
+			ftv.auth = &auth{}
+		}
+		ftv.auth.token = token
+	}
+}
+
+// WithAuthRefresh set the function called to get a new token when the server rejects the current one
+func WithAuthRefresh(refresh TokenRefresher) func(ftv *FranceTV) {
+	return func(ftv *FranceTV) {
+		if ftv.auth == nil {
+			ftv.auth = &auth{}
+		}
+		ftv.auth.refresh = refresh
+	}
+}
+
+// TokenFromFile gives a TokenRefresher reading the token from the file, kept up to date by an other tool
+func TokenFromFile(name string) TokenRefresher {
+	return func(ctx context.Context) (string, error) {
+		b, err := ioutil.ReadFile(name)
+		if err != nil {
+			return "", fmt.Errorf("Can't read session token: %w", err)
+		}
+		token := strings.TrimSpace(string(b))
+		if len(token) == 0 {
+			return "", fmt.Errorf("Can't read session token: %q is empty", name)
+		}
+		return token, nil
+	}
+}
+
+func (a *auth) getToken() string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.token
+}
+
+// renew calls the refresh function, unless the token has been renewed since used was sent
+func (a *auth) renew(ctx context.Context, used string) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.token != used {
+		return a.token, nil
+	}
+	if a.refresh == nil {
+		return "", fmt.Errorf("Can't refresh session token: the token is rejected and no refresh is configured")
+	}
+	token, err := a.refresh(ctx)
+	if err != nil {
+		return "", fmt.Errorf("Can't refresh session token: %w", err)
+	}
+	a.token = token
+	return token, nil
+}
+
+// authGet gets the url with the session token when one is configured. When the token is
+// rejected, it is refreshed and the request is sent again.
+func (p *FranceTV) authGet(ctx context.Context, u string) (io.ReadCloser, error) {
+	if p.auth == nil {
+		return p.getter.Get(ctx, u)
+	}
+	token := p.auth.getToken()
+	r, err := p.getWithToken(ctx, u, token)
+	if !isUnauthorized(err) {
+		return r, err
+	}
+	p.logger.Debugf("Session token rejected for %q, refreshing it", u)
+	token, err = p.auth.renew(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	return p.getWithToken(ctx, u, token)
+}
+
+func (p *FranceTV) getWithToken(ctx context.Context, u string, token string) (io.ReadCloser, error) {
+	h := make(http.Header)
+	if len(token) > 0 {
+		h.Set("Authorization", "Bearer "+token)
+	}
+	return p.getter.DoWithContext(ctx, "GET", u, h, nil)
+}
+
+// sessionGetter sends the session token with the requests of the provider's getter, like segment
+// and manifest requests made by the downloader
+type sessionGetter struct {
+	p *FranceTV
+}
+
+func (g sessionGetter) Get(ctx context.Context, u string) (io.ReadCloser, error) {
+	return g.p.authGet(ctx, u)
+}
+
+func (g sessionGetter) DoWithContext(ctx context.Context, method string, theURL string, headers http.Header, body io.Reader) (io.ReadCloser, error) {
+	h := headers.Clone()
+	if h == nil {
+		h = http.Header{}
+	}
+	if len(h.Get("Authorization")) == 0 {
+		if token := g.p.auth.getToken(); len(token) > 0 {
+			h.Set("Authorization", "Bearer "+token)
+		}
+	}
+	return g.p.getter.DoWithContext(ctx, method, theURL, h, body)
+}
+
+// streamGetter gives the getter of manifests and segments, sending the session token when one is configured
+func (p *FranceTV) streamGetter() getter {
+	if p.auth == nil {
+		return p.getter
+	}
+	return sessionGetter{p: p}
+}
+
+func isUnauthorized(err error) bool {
+	var httpErr *myhttp.HTTPError
+	return errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusUnauthorized
+}
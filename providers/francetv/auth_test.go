@@ -0,0 +1,151 @@
+package francetv
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/simulot/aspiratv/metadata/nfo"
+	"github.com/simulot/aspiratv/net/myhttp"
+	"github.com/simulot/aspiratv/providers"
+)
+
+// authGetter serves the player to requests having the valid token
+type authGetter struct {
+	valid string
+	sent  *[]string // Authorization headers received
+}
+
+func (g authGetter) Get(ctx context.Context, uri string) (io.ReadCloser, error) {
+	return g.DoWithContext(ctx, "GET", uri, nil, nil)
+}
+
+func (g authGetter) DoWithContext(ctx context.Context, method string, theURL string, headers http.Header, body io.Reader) (io.ReadCloser, error) {
+	a := headers.Get("Authorization")
+	*g.sent = append(*g.sent, a)
+	if len(g.valid) > 0 && a != "Bearer "+g.valid {
+		return nil, &myhttp.HTTPError{StatusCode: http.StatusUnauthorized, Status: "401 Unauthorized"}
+	}
+	return ioutil.NopCloser(strings.NewReader(`{"video":{"url":"http://example.com/master.m3u8"}}`)), nil
+}
+
+func TestAuth(t *testing.T) {
+	tests := []struct {
+		name    string
+		valid   string
+		conf    []func(*FranceTV)
+		want    []string
+		wantErr bool
+	}{
+		{"no auth", "", nil, []string{""}, false},
+		{"valid token", "abc", []func(*FranceTV){WithAuth("abc")}, []string{"Bearer abc"}, false},
+		{
+			"expired token",
+			"new",
+			[]func(*FranceTV){WithAuth("old"), WithAuthRefresh(func(ctx context.Context) (string, error) { return "new", nil })},
+			[]string{"Bearer old", "Bearer new"},
+			false,
+		},
+		{"expired token without refresh", "new", []func(*FranceTV){WithAuth("old")}, []string{"Bearer old"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sent := []string{}
+			p, _ := New(append([]func(*FranceTV){WithGetter(authGetter{valid: tt.valid, sent: &sent})}, tt.conf...)...)
+			m := &providers.Media{ID: "1", Metadata: &nfo.Movie{}}
+			err := p.GetMediaDetails(context.TODO(), m)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetMediaDetails() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if strings.Join(sent, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Expecting authorizations %q, got %q", tt.want, sent)
+			}
+		})
+	}
+}
+
+// fileGetter serves the player and the video file, and records the Authorization header of each url
+type fileGetter struct {
+	sent map[string]string
+}
+
+func (g fileGetter) Get(ctx context.Context, uri string) (io.ReadCloser, error) {
+	return g.DoWithContext(ctx, "GET", uri, nil, nil)
+}
+
+func (g fileGetter) DoWithContext(ctx context.Context, method string, theURL string, headers http.Header, body io.Reader) (io.ReadCloser, error) {
+	g.sent[theURL] = headers.Get("Authorization")
+	if theURL == "http://example.com/video.mp4" {
+		return ioutil.NopCloser(strings.NewReader("mp4 content")), nil
+	}
+	return ioutil.NopCloser(strings.NewReader(`{"video":{"url":"http://example.com/master.m3u8"},"videos":[` +
+		`{"format":"mp4-dp","url":"http://example.com/video.mp4","statut":"ONLINE"}]}`)), nil
+}
+
+func TestDownloadAuth(t *testing.T) {
+	dir, err := ioutil.TempDir("", "francetv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	g := fileGetter{sent: map[string]string{}}
+	p, _ := New(WithGetter(g), WithAuth("abc"))
+	m := &providers.Media{ID: "1", Metadata: &nfo.Movie{}}
+	if err := p.Download(context.TODO(), m, filepath.Join(dir, "video.mp4"), nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := g.sent["http://example.com/video.mp4"]; got != "Bearer abc" {
+		t.Errorf("Expecting the video to be downloaded with the session token, got %q", got)
+	}
+}
+
+func TestApplyConfigAuth(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aspiratv-token-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "token")
+	if err := ioutil.WriteFile(file, []byte("old\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	sent := []string{}
+	p, err := NewWithConfig(FranceTVConfig{Settings: providers.Settings{TokenFile: file}}, WithGetter(authGetter{valid: "new", sent: &sent}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The token is renewed by an other tool
+	if err := ioutil.WriteFile(file, []byte("new\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.GetMediaDetails(context.TODO(), &providers.Media{ID: "1", Metadata: &nfo.Movie{}}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.Join(sent, ","), "Bearer old,Bearer new"; got != want {
+		t.Errorf("Expecting authorizations %q, got %q", want, got)
+	}
+
+	sent = sent[:0]
+	p, err = NewWithConfig(FranceTVConfig{Settings: providers.Settings{Token: "new"}}, WithGetter(authGetter{valid: "new", sent: &sent}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.GetMediaDetails(context.TODO(), &providers.Media{ID: "1", Metadata: &nfo.Movie{}}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.Join(sent, ","), "Bearer new"; got != want {
+		t.Errorf("Expecting authorizations %q, got %q", want, got)
+	}
+
+	_, err = NewWithConfig(FranceTVConfig{Settings: providers.Settings{TokenFile: filepath.Join(dir, "missing")}})
+	if err == nil {
+		t.Errorf("Expecting an error for a missing token file")
+	}
+}
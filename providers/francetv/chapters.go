@@ -0,0 +1,49 @@
+package francetv
+
+import (
+	"sort"
+	"time"
+
+	"github.com/simulot/aspiratv/providers"
+)
+
+// playerMarker is a part of the video given by the player, with offsets in seconds
+type playerMarker struct {
+	Type  string  `json:"type"` // opening, credits...
+	Title string  `json:"title"`
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+}
+
+// markerTitles names the markers without title
+var markerTitles = map[string]string{
+	"opening": "Générique de début",
+	"intro":   "Générique de début",
+	"recap":   "Résumé",
+	"credits": "Générique de fin",
+	"ending":  "Générique de fin",
+}
+
+// chapters converts the player's markers into chapters sorted by start. Markers ending before they start are ignored.
+func chapters(markers []playerMarker) []providers.Chapter {
+	var cs []providers.Chapter
+	for _, mk := range markers {
+		if mk.Start < 0 || mk.End <= mk.Start {
+			continue
+		}
+		title := mk.Title
+		if len(title) == 0 {
+			title = markerTitles[mk.Type]
+		}
+		if len(title) == 0 {
+			title = mk.Type
+		}
+		cs = append(cs, providers.Chapter{
+			Title: title,
+			Start: time.Duration(mk.Start * float64(time.Second)),
+			End:   time.Duration(mk.End * float64(time.Second)),
+		})
+	}
+	sort.SliceStable(cs, func(i, j int) bool { return cs[i].Start < cs[j].Start })
+	return cs
+}
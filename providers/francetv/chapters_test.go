@@ -0,0 +1,37 @@
+package francetv
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/simulot/aspiratv/providers"
+)
+
+func Test_chapters(t *testing.T) {
+	tests := []struct {
+		name    string
+		markers []playerMarker
+		want    []providers.Chapter
+	}{
+		{"no markers", nil, nil},
+		{"named by type", []playerMarker{
+			{Type: "credits", Start: 1500, End: 1560},
+			{Type: "opening", Start: 0, End: 42.5},
+		}, []providers.Chapter{
+			{Title: "Générique de début", Start: 0, End: 42500 * time.Millisecond},
+			{Title: "Générique de fin", Start: 1500 * time.Second, End: 1560 * time.Second},
+		}},
+		{"given title", []playerMarker{{Type: "chapter", Title: "Le reportage", Start: 60, End: 600}}, []providers.Chapter{
+			{Title: "Le reportage", Start: time.Minute, End: 10 * time.Minute},
+		}},
+		{"invalid", []playerMarker{{Type: "opening", Start: 10, End: 0}}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := chapters(tt.markers); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("chapters() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
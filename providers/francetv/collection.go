@@ -0,0 +1,105 @@
+package francetv
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/simulot/aspiratv/metadata/nfo"
+	"github.com/simulot/aspiratv/providers"
+	"github.com/simulot/aspiratv/providers/francetv/query"
+)
+
+// UniqueID types of collections: programs and seasons grouping several videos
+const (
+	collectionProgram = "FRANCETV:PROGRAM"
+	collectionSeason  = "FRANCETV:SEASON"
+)
+
+// isCollection tells if the catalog entry is a program or a season instead of a video
+func isCollection(h query.Hits) bool {
+	return h.Class == "program"
+}
+
+// collectionMedia gives the media standing for a collection entry of the catalog, to be expanded with ExpandCollection
+func collectionMedia(h query.Hits, mr *providers.MatchRequest) *providers.Media {
+	idType := collectionProgram
+	if h.Type == "saison" {
+		idType = collectionSeason
+	}
+	label := h.Label
+	if len(label) == 0 {
+		label = h.Title
+	}
+	meta := nfo.EpisodeDetails{}
+	meta.MediaInfo = nfo.MediaInfo{
+		Showtitle: label,
+		Title:     label,
+		Plot:      h.Description,
+		UniqueID:  []nfo.ID{{ID: strconv.Itoa(h.ID), Type: idType}},
+	}
+	m := &providers.Media{
+		ID:       idType + ":" + strconv.Itoa(h.ID),
+		ShowType: providers.Series,
+		Kind:     providers.KindEpisode,
+		Match:    mr,
+	}
+	m.SetMetaData(&meta)
+	return m
+}
+
+// collectionFilter gives the catalog filter selecting the videos of the collection, false when the media isn't a collection
+func collectionFilter(m *providers.Media) (string, bool) {
+	if m.Metadata == nil {
+		return "", false
+	}
+	for _, id := range m.Metadata.GetMediaInfo().UniqueID {
+		switch id.Type {
+		case collectionProgram:
+			return "program.id = " + id.ID, true
+		case collectionSeason:
+			return "season.id = " + id.ID, true
+		}
+	}
+	return "", false
+}
+
+// ExpandCollection gives the videos of a collection, like a program or a season, as individual media.
+// A media that isn't a collection is returned alone.
+func (p *FranceTV) ExpandCollection(ctx context.Context, m *providers.Media) ([]*providers.Media, error) {
+	filter, ok := collectionFilter(m)
+	if !ok {
+		return []*providers.Media{m}, nil
+	}
+	err := p.getAlgoliaConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// All videos of the collection are wanted, whatever their title
+	mr := &providers.MatchRequest{Provider: p.Name()}
+	if m.Match != nil {
+		c := *m.Match
+		mr = &c
+	}
+	mr.Show = ""
+
+	children := []*providers.Media{}
+	seen := map[string]bool{}
+	medias, errc := p.queryCatalog(ctx, mr, func(ts int64) string {
+		return replayFilter(ts) + " AND " + filter
+	})
+	for c := range medias {
+		if _, isColl := collectionFilter(c); isColl || seen[c.ID] {
+			continue
+		}
+		seen[c.ID] = true
+		c.Match = m.Match
+		children = append(children, c)
+	}
+	if err := <-errc; err != nil {
+		return nil, fmt.Errorf("Can't expand collection %q: %w", m.Metadata.GetMediaInfo().Title, err)
+	}
+	p.logger.Debugf("Collection %q has %d videos", m.Metadata.GetMediaInfo().Title, len(children))
+	return children, nil
+}
@@ -0,0 +1,116 @@
+package francetv
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/simulot/aspiratv/metadata/nfo"
+	"github.com/simulot/aspiratv/providers"
+)
+
+// collectionGetter answers the requests of a collection's videos with them, and the other requests with a collection and a video
+type collectionGetter struct {
+	catalogGetter
+	mu     *sync.Mutex
+	bodies *[]string
+}
+
+func newCollectionGetter(bodies ...string) collectionGetter {
+	return collectionGetter{mu: &sync.Mutex{}, bodies: &bodies}
+}
+
+func (g collectionGetter) DoWithContext(ctx context.Context, method string, theURL string, headers http.Header, body io.Reader) (io.ReadCloser, error) {
+	b, _ := ioutil.ReadAll(body)
+	g.mu.Lock()
+	*g.bodies = append(*g.bodies, string(b))
+	g.mu.Unlock()
+	if strings.Contains(string(b), ".id%20=%20") {
+		return os.Open(filepath.Join("testdata", "children.json"))
+	}
+	return os.Open(filepath.Join("testdata", "collection.json"))
+}
+
+// requested gives the first request body containing all the strings, empty when none
+func (g collectionGetter) requested(s ...string) string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+bodies:
+	for _, b := range *g.bodies {
+		for _, e := range s {
+			if !strings.Contains(b, e) {
+				continue bodies
+			}
+		}
+		return b
+	}
+	return ""
+}
+
+func TestMediaListCollection(t *testing.T) {
+	g := newCollectionGetter()
+	p, _ := New(WithGetter(g))
+	mr := &providers.MatchRequest{Provider: "francetv", Show: "cyrano"}
+	medias, errc := p.MediaList(context.Background(), []*providers.MatchRequest{mr})
+	ids := []string{}
+	for m := range medias {
+		ids = append(ids, m.ID)
+		if m.Match != mr {
+			t.Errorf("Expecting %q to keep the matched request", m.ID)
+		}
+	}
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(ids)
+	if got, want := strings.Join(ids, ","), "1002,1011,1012"; got != want {
+		t.Errorf("Expecting media %s, got %s", want, got)
+	}
+	if len(g.requested(taxonomiesIndex, "class%3Aprogram")) == 0 {
+		t.Errorf("Expecting the programs to be requested, got %v", *g.bodies)
+	}
+	if b := g.requested(contentsIndex, "filters"); len(b) == 0 || strings.Contains(b, "class%3Aprogram") {
+		t.Errorf("Expecting the videos to be requested apart, got %v", *g.bodies)
+	}
+	if len(g.requested("season.id%20=%20500")) == 0 {
+		t.Errorf("Expecting the videos of season 500 to be requested, got %v", *g.bodies)
+	}
+}
+
+func TestExpandCollection(t *testing.T) {
+	g := newCollectionGetter("collection already fetched")
+	p, _ := New(WithGetter(g))
+
+	m := &providers.Media{ID: "1002"}
+	m.SetMetaData(&nfo.Movie{MediaInfo: nfo.MediaInfo{Title: "Cyrano et Roxane"}})
+	got, err := p.ExpandCollection(context.Background(), m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0] != m {
+		t.Errorf("Expecting a video to be returned alone, got %v", got)
+	}
+	if len(*g.bodies) != 1 {
+		t.Errorf("Expecting no catalog request for a video")
+	}
+
+	m = &providers.Media{}
+	m.SetMetaData(&nfo.EpisodeDetails{MediaInfo: nfo.MediaInfo{Title: "Cyrano", UniqueID: []nfo.ID{{ID: "42", Type: collectionProgram}}}})
+	got, err = p.ExpandCollection(context.Background(), m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 {
+		t.Errorf("Expecting 3 videos, got %d", len(got))
+	}
+	if len(g.requested(contentsIndex, "program.id%20=%2042")) == 0 {
+		t.Errorf("Expecting the videos of program 42 to be requested, got %v", *g.bodies)
+	}
+}
@@ -0,0 +1,71 @@
+package francetv
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/simulot/aspiratv/providers"
+)
+
+// FranceTVConfig gives the settings of the provider, usually read from the configuration file
+type FranceTVConfig struct {
+	providers.Settings
+}
+
+// NewWithConfig setup a FranceTV provider with the given settings, applied after conf functions
+func NewWithConfig(cfg FranceTVConfig, conf ...func(ftv *FranceTV)) (*FranceTV, error) {
+	p, err := New(conf...)
+	if err != nil {
+		return nil, err
+	}
+	err = p.ApplyConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// ApplyConfig applies the settings. Empty settings keep the current values.
+func (p *FranceTV) ApplyConfig(cfg FranceTVConfig) error {
+	loc, err := cfg.Location()
+	if err != nil {
+		return fmt.Errorf("Can't configure %s: %w", p.Name(), err)
+	}
+	WithTimezone(loc)(p)
+	if cfg.CatalogLimit > 0 {
+		WithCatalogLimit(cfg.CatalogLimit)(p)
+	}
+	if len(cfg.Headers) > 0 {
+		WithHeaders(cfg.Headers)(p)
+	}
+	if len(cfg.Quality) > 0 {
+		p.quality = cfg.Quality
+	}
+	if len(cfg.Diffusion) > 0 {
+		if cfg.Diffusion != DiffusionLatest && cfg.Diffusion != DiffusionSoonest {
+			return fmt.Errorf("Can't configure %s: unknown diffusion %q, possible values are %s or %s", p.Name(), cfg.Diffusion, DiffusionLatest, DiffusionSoonest)
+		}
+		WithDiffusion(cfg.Diffusion)(p)
+	}
+	if cfg.SkipProgressive {
+		WithProgressive(false)(p)
+	}
+	if cfg.EpisodeGuide {
+		WithEpisodeGuide(true)(p)
+	}
+	if len(cfg.Token) > 0 {
+		WithAuth(cfg.Token)(p)
+	}
+	if len(cfg.TokenFile) > 0 {
+		refresh := TokenFromFile(cfg.TokenFile)
+		if len(cfg.Token) == 0 {
+			token, err := refresh(context.Background())
+			if err != nil {
+				return fmt.Errorf("Can't configure %s: %w", p.Name(), err)
+			}
+			WithAuth(token)(p)
+		}
+		WithAuthRefresh(refresh)(p)
+	}
+	return nil
+}
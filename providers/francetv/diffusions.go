@@ -0,0 +1,77 @@
+package francetv
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/simulot/aspiratv/providers"
+)
+
+// Diffusions kept among the re-airings of a media
+const (
+	DiffusionLatest  = "latest"  // The diffusion available for the longest time
+	DiffusionSoonest = "soonest" // The diffusion expiring first
+)
+
+// WithDiffusion selects the diffusion kept among the re-airings of a media, DiffusionLatest by default
+func WithDiffusion(preference string) func(ftv *FranceTV) {
+	return func(ftv *FranceTV) {
+		ftv.diffusion = preference
+	}
+}
+
+// diffusionKey identifies the diffusions of the same media: same show, numbers, title and plot.
+// Media without title can't be compared, their key is empty.
+func diffusionKey(m *providers.Media) string {
+	if m.Metadata == nil {
+		return ""
+	}
+	info := m.Metadata.GetMediaInfo()
+	if len(info.Title) == 0 {
+		return ""
+	}
+	return strings.Join([]string{
+		m.Kind,
+		providers.NormalizeForMatch(info.Showtitle),
+		strconv.Itoa(info.Season),
+		strconv.Itoa(info.Episode),
+		providers.NormalizeForMatch(info.Title),
+		providers.NormalizeForMatch(info.Plot),
+	}, "|")
+}
+
+// selectDiffusions keeps one diffusion of each media, in the order of their first diffusion.
+// The kept diffusion is the one expiring the latest, or the soonest with DiffusionSoonest.
+// Diffusions of unknown expiry are kept only when no other is known.
+func selectDiffusions(ms []*providers.Media, preference string) []*providers.Media {
+	selected := []*providers.Media{}
+	index := map[string]int{}
+	for _, m := range ms {
+		key := diffusionKey(m)
+		i, ok := index[key]
+		if !ok {
+			if len(key) > 0 {
+				index[key] = len(selected)
+			}
+			selected = append(selected, m)
+			continue
+		}
+		if betterDiffusion(m, selected[i], preference) {
+			selected[i] = m
+		}
+	}
+	return selected
+}
+
+// betterDiffusion tells if m is preferred to current
+func betterDiffusion(m, current *providers.Media, preference string) bool {
+	switch {
+	case m.Expires.IsZero():
+		return false
+	case current.Expires.IsZero():
+		return true
+	case preference == DiffusionSoonest:
+		return m.Expires.Before(current.Expires)
+	}
+	return m.Expires.After(current.Expires)
+}
@@ -0,0 +1,53 @@
+package francetv
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/simulot/aspiratv/metadata/nfo"
+	"github.com/simulot/aspiratv/providers"
+)
+
+func Test_selectDiffusions(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2020, 6, d, 0, 0, 0, 0, time.UTC) }
+	media := func(id, title string, expires time.Time) *providers.Media {
+		return &providers.Media{
+			ID:       id,
+			Kind:     providers.KindEpisode,
+			Expires:  expires,
+			Metadata: &nfo.Movie{MediaInfo: nfo.MediaInfo{Title: title, Plot: "Le film"}},
+		}
+	}
+	ms := []*providers.Media{
+		media("1", "Cyrano de Bergerac", day(10)),
+		media("2", "Cyrano et Roxane", day(5)),
+		media("3", "Cyrano de Bergerac", day(20)),
+		media("4", "Cyrano de Bergerac", time.Time{}),
+		media("5", "Cyrano de Bergerac", day(2)),
+		media("6", "", day(1)),
+		media("7", "", day(30)),
+	}
+	ids := func(ms []*providers.Media) []string {
+		r := []string{}
+		for _, m := range ms {
+			r = append(r, m.ID)
+		}
+		return r
+	}
+	tests := []struct {
+		preference string
+		want       []string
+	}{
+		{"", []string{"3", "2", "6", "7"}},
+		{DiffusionLatest, []string{"3", "2", "6", "7"}},
+		{DiffusionSoonest, []string{"5", "2", "6", "7"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.preference, func(t *testing.T) {
+			if got := ids(selectDiffusions(ms, tt.preference)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("selectDiffusions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package francetv
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/simulot/aspiratv/providers"
+)
+
+// catalogGetter serves a fake home page and the same search results for every request
+type catalogGetter struct {
+	results string
+}
+
+func (g catalogGetter) Get(ctx context.Context, uri string) (io.ReadCloser, error) {
+	return ioutil.NopCloser(strings.NewReader(`getAppConfig() { return {"algolia_app_id":"app","algolia_api_key":"key"}; }`)), nil
+}
+
+func (g catalogGetter) DoWithContext(ctx context.Context, method string, theURL string, headers http.Header, body io.Reader) (io.ReadCloser, error) {
+	return os.Open(g.results)
+}
+
+func TestMediaListDuplicates(t *testing.T) {
+	p, _ := New(WithGetter(catalogGetter{results: filepath.Join("testdata", "duplicates.json")}))
+	mm := []*providers.MatchRequest{
+		{Provider: "francetv", Show: "cyrano"},
+		{Provider: "francetv", Show: "cyrano de bergerac"},
+	}
+	medias, errc := p.MediaList(context.Background(), mm)
+	got := map[string]string{}
+	n := 0
+	for m := range medias {
+		n++
+		got[m.Match.Show+"/"+m.ID] = m.Metadata.GetMediaInfo().Plot
+	}
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+	// Each request gives its media once, 1001 is listed by both requests
+	if n != 3 || len(got) != 3 {
+		t.Errorf("Expecting 3 media, got %d: %v", n, got)
+	}
+	for _, k := range []string{"cyrano/1001", "cyrano de bergerac/1001"} {
+		if got[k] != "Première diffusion" {
+			t.Errorf("Expecting first occurrence to be kept for %s, got %q", k, got[k])
+		}
+	}
+}
+
+func TestMediaListKinds(t *testing.T) {
+	p, _ := New(WithGetter(catalogGetter{results: filepath.Join("testdata", "kinds.json")}))
+	medias, errc := p.MediaList(context.Background(), []*providers.MatchRequest{{Provider: "francetv", Show: "cyrano"}})
+	got := map[string]string{}
+	for m := range medias {
+		got[m.ID] = m.Kind
+	}
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"2001": providers.KindEpisode, "2002": providers.KindExtract, "2003": providers.KindBonus}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expecting kinds %v, got %v", want, got)
+	}
+}
+
+// bodyRecorder records the bodies of the catalog requests
+type bodyRecorder struct {
+	catalogGetter
+	bodies *[]string
+}
+
+func (g bodyRecorder) DoWithContext(ctx context.Context, method string, theURL string, headers http.Header, body io.Reader) (io.ReadCloser, error) {
+	b, _ := ioutil.ReadAll(body)
+	*g.bodies = append(*g.bodies, string(b))
+	return g.catalogGetter.DoWithContext(ctx, method, theURL, headers, body)
+}
+
+func TestUpcoming(t *testing.T) {
+	bodies := []string{}
+	p, _ := New(WithGetter(bodyRecorder{catalogGetter{results: filepath.Join("testdata", "duplicates.json")}, &bodies}))
+	medias, errc := p.Upcoming(context.Background())
+	ids := map[string]bool{}
+	for m := range medias {
+		ids[m.ID] = true
+		if len(m.Metadata.GetMediaInfo().URL) != 0 {
+			t.Errorf("Expecting no stream url for %q", m.ID)
+		}
+	}
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) == 0 {
+		t.Errorf("Expecting upcoming media")
+	}
+	if len(bodies) == 0 {
+		t.Fatal("Expecting a catalog request")
+	}
+	if !strings.Contains(bodies[0], "ranges.replay.web.begin_date%20%3E%20") {
+		t.Errorf("Expecting a filter on replay opening date, got %s", bodies[0])
+	}
+}
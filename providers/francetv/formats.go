@@ -0,0 +1,96 @@
+package francetv
+
+import (
+	"net/url"
+	"path"
+	"strings"
+)
+
+// playerVideo is one of the formats listed by the player, when it gives several of them
+type playerVideo struct {
+	Format    string `json:"format"`
+	URL       string `json:"url"`
+	URLSecure string `json:"url_secure"`
+	Statut    string `json:"statut"`
+	DRM       bool   `json:"drm"`
+}
+
+// Formats of the player in preference order. hls_v5_os is always available and is the safe default,
+// higher definitions are only tried when the best quality is requested.
+var (
+	defaultFormats = []string{"hls_v5_os", "m3u8-download"}
+	hdFormats      = []string{"hls_v5_fhd", "hls_v5_hd"}
+)
+
+// WithProgressive tells if progressive MP4 files are preferred to streams when the player gives one, the default
+func WithProgressive(enabled bool) func(ftv *FranceTV) {
+	return func(ftv *FranceTV) {
+		ftv.progressive = enabled
+	}
+}
+
+// formatPreferences gives the acceptable formats for the quality, the preferred first
+func formatPreferences(quality string) []string {
+	if quality == "best" {
+		return append(append([]string{}, hdFormats...), defaultFormats...)
+	}
+	return defaultFormats
+}
+
+// selectFormat gives the url of the preferred format that is online and without DRM.
+// ok is false when none of the acceptable formats is available.
+func selectFormat(videos []playerVideo, quality string) (u string, ok bool) {
+	for _, f := range formatPreferences(quality) {
+		for _, v := range videos {
+			if v.Format != f || v.DRM || (len(v.Statut) > 0 && v.Statut != "ONLINE") {
+				continue
+			}
+			if len(v.URLSecure) > 0 {
+				return v.URLSecure, true
+			}
+			if len(v.URL) > 0 {
+				return v.URL, true
+			}
+		}
+	}
+	return "", false
+}
+
+// selectProgressive gives the url of a progressive MP4 file that is online and without DRM.
+// ok is false when the player gives only streams.
+func selectProgressive(videos []playerVideo) (u string, ok bool) {
+	for _, v := range videos {
+		if v.DRM || (len(v.Statut) > 0 && v.Statut != "ONLINE") {
+			continue
+		}
+		link := v.URLSecure
+		if len(link) == 0 {
+			link = v.URL
+		}
+		if len(link) > 0 && (strings.HasPrefix(strings.ToLower(v.Format), "mp4") || isMP4(link)) {
+			return link, true
+		}
+	}
+	return "", false
+}
+
+// isMP4 tells if the url's path is a MP4 file
+func isMP4(u string) bool {
+	pu, err := url.Parse(u)
+	if err != nil {
+		return false
+	}
+	return strings.ToLower(path.Ext(pu.Path)) == ".mp4"
+}
+
+// tokenFor gives the url of the token service signing the stream u, instead of the player's default stream
+func tokenFor(token, u string) string {
+	tu, err := url.Parse(token)
+	if err != nil {
+		return token
+	}
+	q := tu.Query()
+	q.Set("url", u)
+	tu.RawQuery = q.Encode()
+	return tu.String()
+}
@@ -0,0 +1,200 @@
+package francetv
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/simulot/aspiratv/metadata/nfo"
+	"github.com/simulot/aspiratv/providers"
+)
+
+func TestSelectFormat(t *testing.T) {
+	videos := []playerVideo{
+		{Format: "hds_akamai", URL: "http://example.com/manifest.f4m"},
+		{Format: "m3u8-download", URL: "http://example.com/download.m3u8"},
+		{Format: "hls_v5_os", URL: "http://example.com/os.m3u8", URLSecure: "https://example.com/os.m3u8", Statut: "ONLINE"},
+		{Format: "hls_v5_hd", URL: "http://example.com/hd.m3u8", Statut: "ONLINE"},
+	}
+	tests := []struct {
+		name    string
+		videos  []playerVideo
+		quality string
+		want    string
+		wantOK  bool
+	}{
+		{"no videos", nil, "best", "", false},
+		{"default", videos, "", "https://example.com/os.m3u8", true},
+		{"resolution", videos, "720p", "https://example.com/os.m3u8", true},
+		{"best", videos, "best", "http://example.com/hd.m3u8", true},
+		{"hd offline", []playerVideo{{Format: "hls_v5_hd", URL: "http://example.com/hd.m3u8", Statut: "OFFLINE"}, videos[2]}, "best", "https://example.com/os.m3u8", true},
+		{"hd with drm", []playerVideo{{Format: "hls_v5_hd", URL: "http://example.com/hd.m3u8", DRM: true}, videos[1]}, "best", "http://example.com/download.m3u8", true},
+		{"unknown formats", videos[:1], "best", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := selectFormat(tt.videos, tt.quality)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("selectFormat() = %q, %v, want %q, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestGetMediaDetailsFormats(t *testing.T) {
+	body := `{"video":{"url":"http://example.com/master.m3u8"},"videos":[` +
+		`{"format":"hls_v5_os","url":"http://example.com/os.m3u8","statut":"ONLINE"},` +
+		`{"format":"hls_v5_hd","url":"http://example.com/hd.m3u8","statut":"ONLINE"}]}`
+	p, _ := New(WithGetter(playerGetter{body: body}))
+	m := &providers.Media{ID: "1", Metadata: &nfo.Movie{}}
+	if err := p.GetMediaDetails(context.TODO(), m); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := m.Metadata.GetMediaInfo().URL, "http://example.com/os.m3u8"; got != want {
+		t.Errorf("Expecting url %q, got %q", want, got)
+	}
+}
+
+// tokenGetter serves the player, signs the url given to the token service, and serves signed urls as media playlists
+type tokenGetter struct {
+	playerGetter
+}
+
+func (g tokenGetter) Get(ctx context.Context, uri string) (io.ReadCloser, error) {
+	if strings.HasSuffix(uri, "?signed") {
+		return ioutil.NopCloser(strings.NewReader("#EXTM3U\n#EXT-X-TARGETDURATION:10\n#EXTINF:10,\nsegment.ts\n#EXT-X-ENDLIST\n")), nil
+	}
+	if !strings.HasPrefix(uri, "http://token.example.com/") {
+		return g.playerGetter.Get(ctx, uri)
+	}
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(strings.NewReader(`{"url":"` + u.Query().Get("url") + `?signed"}`)), nil
+}
+
+func (g tokenGetter) DoWithContext(ctx context.Context, method string, theURL string, headers http.Header, body io.Reader) (io.ReadCloser, error) {
+	return g.Get(ctx, theURL)
+}
+
+func TestGetMediaDetailsFormatsToken(t *testing.T) {
+	tests := []struct {
+		name    string
+		quality string
+		want    string
+	}{
+		{"default", "", "https://example.com/os.m3u8?signed"},
+		{"best", "best", "http://example.com/hd.m3u8?signed"},
+	}
+	body := `{"video":{"url":"http://example.com/master.m3u8","token":"http://token.example.com/esi/TA?format=json&url=http%3A%2F%2Fexample.com%2Fmaster.m3u8"},"videos":[` +
+		`{"format":"hls_v5_os","url":"http://example.com/os.m3u8","url_secure":"https://example.com/os.m3u8","statut":"ONLINE"},` +
+		`{"format":"hls_v5_hd","url":"http://example.com/hd.m3u8","statut":"ONLINE"}]}`
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, _ := New(WithGetter(tokenGetter{playerGetter{body: body}}))
+			m := &providers.Media{ID: "1", Metadata: &nfo.Movie{}, Match: &providers.MatchRequest{Quality: tt.quality}}
+			if err := p.GetMediaDetails(context.TODO(), m); err != nil {
+				t.Fatal(err)
+			}
+			if got := m.Metadata.GetMediaInfo().URL; got != tt.want {
+				t.Errorf("Expecting url %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSelectProgressive(t *testing.T) {
+	tests := []struct {
+		name   string
+		videos []playerVideo
+		want   string
+		wantOK bool
+	}{
+		{"streams only", []playerVideo{{Format: "hls_v5_os", URL: "http://example.com/os.m3u8"}}, "", false},
+		{"mp4 format", []playerVideo{{Format: "hls_v5_os", URL: "http://example.com/os.m3u8"}, {Format: "mp4-dp", URL: "http://example.com/video", URLSecure: "https://example.com/video"}}, "https://example.com/video", true},
+		{"mp4 file", []playerVideo{{Format: "download", URL: "http://example.com/video.MP4?token=1"}}, "http://example.com/video.MP4?token=1", true},
+		{"offline", []playerVideo{{Format: "mp4-dp", URL: "http://example.com/video.mp4", Statut: "OFFLINE"}}, "", false},
+		{"drm", []playerVideo{{Format: "mp4-dp", URL: "http://example.com/video.mp4", DRM: true}}, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := selectProgressive(tt.videos)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("selectProgressive() = %q, %v, want %q, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestGetMediaDetailsProgressive(t *testing.T) {
+	body := `{"video":{"url":"http://example.com/master.m3u8"},"videos":[` +
+		`{"format":"hls_v5_os","url":"http://example.com/os.m3u8","statut":"ONLINE"},` +
+		`{"format":"mp4-dp","url":"http://example.com/video.mp4","statut":"ONLINE"}]}`
+	media := "#EXTM3U\n#EXT-X-TARGETDURATION:10\n#EXTINF:10,\nsegment.ts\n#EXT-X-ENDLIST\n"
+	tests := []struct {
+		name       string
+		conf       []func(*FranceTV)
+		match      providers.MatchRequest
+		wantURL    string
+		wantStream string
+	}{
+		{"preferred", nil, providers.MatchRequest{}, "http://example.com/video.mp4", providers.StreamMP4},
+		{"disabled", []func(*FranceTV){WithProgressive(false)}, providers.MatchRequest{}, "http://example.com/os.m3u8", providers.StreamHLS},
+		{"ts container", nil, providers.MatchRequest{Container: "ts"}, "http://example.com/os.m3u8", providers.StreamHLS},
+		{"requested quality", nil, providers.MatchRequest{Quality: "best"}, "http://example.com/os.m3u8", providers.StreamHLS},
+		{"default quality", []func(*FranceTV){func(p *FranceTV) { p.quality = "best" }}, providers.MatchRequest{}, "http://example.com/os.m3u8", providers.StreamHLS},
+		{"audio language", nil, providers.MatchRequest{AudioLanguage: "fr"}, "http://example.com/os.m3u8", providers.StreamHLS},
+		{"audio description", nil, providers.MatchRequest{AudioDescription: true}, "http://example.com/os.m3u8", providers.StreamHLS},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := providers.StaticGetter(map[string]string{"": body, "http://example.com/os.m3u8": media})
+			p, _ := New(append([]func(*FranceTV){WithGetter(g)}, tt.conf...)...)
+			match := tt.match
+			m := &providers.Media{ID: "1", Metadata: &nfo.Movie{}, Match: &match}
+			if err := p.GetMediaDetails(context.TODO(), m); err != nil {
+				t.Fatal(err)
+			}
+			if got := m.Metadata.GetMediaInfo().URL; got != tt.wantURL {
+				t.Errorf("Expecting url %q, got %q", tt.wantURL, got)
+			}
+			if m.StreamType != tt.wantStream {
+				t.Errorf("Expecting stream type %q, got %q", tt.wantStream, m.StreamType)
+			}
+		})
+	}
+}
+
+func TestDownloadProgressive(t *testing.T) {
+	body := `{"video":{"url":"http://example.com/master.m3u8"},"videos":[` +
+		`{"format":"mp4-dp","url":"http://example.com/video.mp4","statut":"ONLINE"}]}`
+	g := providers.StaticGetter(map[string]string{"": body, "http://example.com/video.mp4": "mp4 content"})
+	p, _ := New(WithGetter(g))
+	dir, err := ioutil.TempDir("", "francetv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	dest := filepath.Join(dir, "video.mp4")
+	m := &providers.Media{ID: "1", Metadata: &nfo.Movie{}}
+	if err := p.Download(context.TODO(), m, dest, nil); err != nil {
+		t.Fatal(err)
+	}
+	if m.StreamType != providers.StreamMP4 {
+		t.Errorf("Expecting stream type %q, got %q", providers.StreamMP4, m.StreamType)
+	}
+	b, err := ioutil.ReadFile(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "mp4 content" {
+		t.Errorf("Expecting the progressive file content, got %q", string(b))
+	}
+}
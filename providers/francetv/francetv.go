@@ -3,19 +3,24 @@ package francetv
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
 	"net/url"
+	"path/filepath"
 	"regexp"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/simulot/aspiratv/download"
+	"github.com/simulot/aspiratv/metadata/nfo"
 	"github.com/simulot/aspiratv/net/myhttp/httptest"
 
 	"github.com/simulot/aspiratv/net/myhttp"
+	"github.com/simulot/aspiratv/playlists/m3u8"
 	"github.com/simulot/aspiratv/providers"
 )
 
@@ -41,75 +46,248 @@ type getter interface {
 // FranceTV structure handles france-tv catalog of shows
 type FranceTV struct {
 	getter      getter
+	base        getter            // Getter wrapped into getter with the headers
+	headers     map[string]string // Headers sent with each request
 	debug       bool
 	deadline    time.Duration
 	algolia     *AlgoliaConfig
 	seasons     sync.Map
 	shows       sync.Map
 	keepBonuses bool
+	limit       int // Maximum number of search results per request, 0 for no limit
+	logger      providers.Logger
+	location    *time.Location // Time zone of broadcast dates
+	quality     string         // Stream quality used when the request doesn't give one
+	diffusion   string         // DiffusionLatest or DiffusionSoonest
+	progressive bool           // Progressive MP4 files are preferred to streams
+	guide       bool           // Missing episode numbers are read from the show page
+	guides      sync.Map       // Episodes of the show pages, by url
+	auth        *auth          // Session token for premium content, nil when not authenticated
 }
 
+// paris is the default time zone, it's initialized before the provider is registered
+var paris = func() *time.Location {
+	loc, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}()
+
 // WithGetter inject a getter in FranceTV object instead of normal one
 func WithGetter(g getter) func(ftv *FranceTV) {
 	return func(ftv *FranceTV) {
-		ftv.getter = g
+		ftv.base = g
+		ftv.wrapGetter()
+	}
+}
+
+// WithHeaders wraps the getter to send the given headers with each request, like User-Agent or Referer.
+// The headers replace the ones given before.
+func WithHeaders(headers map[string]string) func(ftv *FranceTV) {
+	return func(ftv *FranceTV) {
+		ftv.headers = headers
+		ftv.wrapGetter()
+	}
+}
+
+// wrapGetter builds the getter from the base getter, so headers aren't stacked when configured again
+func (p *FranceTV) wrapGetter() {
+	p.getter = p.base
+	if len(p.headers) > 0 {
+		p.getter = myhttp.NewHeadersClient(p.base, p.headers)
+	}
+}
+
+// WithCatalogLimit limits the number of search results retrieved for each request. 0 means no limit.
+func WithCatalogLimit(n int) func(ftv *FranceTV) {
+	return func(ftv *FranceTV) {
+		ftv.limit = n
+	}
+}
+
+// WithTimezone set the time zone used for broadcast dates, Europe/Paris by default
+func WithTimezone(loc *time.Location) func(ftv *FranceTV) {
+	return func(ftv *FranceTV) {
+		if loc != nil {
+			ftv.location = loc
+		}
 	}
 }
 
 // New setup a Show provider for France Télévisions
-func New() (*FranceTV, error) {
+func New(conf ...func(ftv *FranceTV)) (*FranceTV, error) {
 	p := &FranceTV{
-		getter:      myhttp.DefaultClient,
+		base:        myhttp.RecorderFromEnv(myhttp.DefaultClient),
 		deadline:    30 * time.Second,
 		keepBonuses: true,
+		location:    paris,
+		progressive: true,
+	}
+	p.getter = p.base
+	p.logger = providers.NewStdLogger(p.Name(), false)
+	for _, c := range conf {
+		c(p)
 	}
 
 	return p, nil
 }
 
 // Name return the name of the provider
-func (FranceTV) Name() string { return "francetv" }
+func (*FranceTV) Name() string { return "francetv" }
+
+// Subfolder is the preferred folder of the media in the destinations, when sources are kept apart
+func (*FranceTV) Subfolder() string { return "FranceTV" }
+
+// Capabilities gives the features supported by the provider
+func (*FranceTV) Capabilities() providers.Capabilities {
+	return providers.Capabilities{Search: true, HealthCheck: true, HLS: true, DASH: true, Subtitles: true, Thumbnails: true, Chapters: true}
+}
 
 func (p *FranceTV) Configure(c providers.Config) {
 	p.keepBonuses = c.KeepBonus
 	p.debug = c.Debug
+	p.logger = c.GetLogger(p.Name())
 	if p.debug {
 		p.deadline = time.Hour
 	} else {
 		p.deadline = 30 * time.Second
 	}
-
+	err := p.ApplyConfig(FranceTVConfig{Settings: c.Settings})
+	if err != nil {
+		p.logger.Errorf("%s", err)
+	}
 }
 
 // MediaList return media that match with matching list.
-func (p *FranceTV) MediaList(ctx context.Context, mm []*providers.MatchRequest) chan *providers.Media {
-	err := p.getAlgoliaConfig(ctx)
+// A media listed several times in the catalog is emitted once with its first occurrence.
+// Among the diffusions of a media under different IDs, like re-broadcasts, the one selected by the
+// Diffusion setting is emitted, see WithDiffusion.
+// Catalog fetch and decode errors are sent on the error channel.
+func (p *FranceTV) MediaList(ctx context.Context, mm []*providers.MatchRequest) (chan *providers.Media, <-chan error) {
+	shows := make(chan *providers.Media)
+	errc := make(chan error, 1)
 
+	err := p.getAlgoliaConfig(ctx)
 	if err != nil {
-		return nil
+		providers.CloseMediaList(shows, errc, err)
+		return shows, errc
 	}
-	shows := make(chan *providers.Media)
 
 	go func() {
-		defer close(shows)
+		var errs providers.Errors
+		defer func() {
+			providers.CloseMediaList(shows, errc, errs.Err())
+		}()
 		for _, m := range mm {
 			if m.Provider != "francetv" {
 				continue
 			}
-			for s := range p.queryAlgolia(ctx, m) {
-				shows <- s
+			// Media are deduplicated by request: a media listed by several requests is given
+			// with each of them, since the watch list accepts it when one of them matches
+			seen := map[string]bool{}
+			medias, qerrc := p.queryAlgolia(ctx, m)
+			list := []*providers.Media{}
+			for c := range medias {
+				children, err := p.ExpandCollection(ctx, c)
+				if err != nil {
+					errs = append(errs, err)
+					continue
+				}
+				for _, s := range children {
+					if seen[s.ID] {
+						p.logger.Debugf("Skip duplicate media %q", s.ID)
+						continue
+					}
+					seen[s.ID] = true
+					list = append(list, s)
+				}
+			}
+			if err := <-qerrc; err != nil {
+				errs = append(errs, fmt.Errorf("Can't search %q: %w", m.Show, err))
+			}
+			for _, s := range selectDiffusions(list, p.diffusion) {
+				select {
+				case shows <- s:
+				case <-ctx.Done():
+					return
+				}
 			}
 		}
 	}()
-	return shows
+	return shows, errc
+}
+
+// Search finds the media of the shows whose name contains the keywords, using the
+// france.tv search engine instead of scanning the whole catalog.
+// No result gives an empty slice.
+func (p *FranceTV) Search(ctx context.Context, keywords string) ([]*providers.Media, error) {
+	result := []*providers.Media{}
+	if len(strings.TrimSpace(keywords)) == 0 {
+		return result, nil
+	}
+	err := p.getAlgoliaConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	mr := &providers.MatchRequest{Provider: p.Name(), Show: keywords, Extracts: true}
+	medias, errc := p.queryAlgolia(ctx, mr)
+	seen := map[string]bool{}
+	var expandErr error
+	for c := range medias {
+		children, err := p.ExpandCollection(ctx, c)
+		if err != nil {
+			expandErr = err
+			continue
+		}
+		for _, m := range children {
+			if seen[m.ID] {
+				continue
+			}
+			seen[m.ID] = true
+			result = append(result, m)
+		}
+	}
+	if err := <-errc; err != nil {
+		return nil, fmt.Errorf("Can't search %q: %w", keywords, err)
+	}
+	if expandErr != nil {
+		return nil, fmt.Errorf("Can't search %q: %w", keywords, expandErr)
+	}
+	return result, nil
+}
+
+// Upcoming lists the media that will be available in replay later, like shows broadcasted tomorrow.
+// Their stream url is left empty, GetMediaDetails returns ErrShowExpired until the replay is open.
+// Catalog fetch and decode errors are sent on the error channel.
+func (p *FranceTV) Upcoming(ctx context.Context) (chan *providers.Media, <-chan error) {
+	err := p.getAlgoliaConfig(ctx)
+	if err != nil {
+		shows := make(chan *providers.Media)
+		errc := make(chan error, 1)
+		providers.CloseMediaList(shows, errc, err)
+		return shows, errc
+	}
+	return p.queryCatalog(ctx, &providers.MatchRequest{Provider: p.Name()}, upcomingFilter)
 }
 
 type player struct {
 	Video struct {
-		URL   string `json:"url"`
-		Token string `json:"token"`
+		URL       string `json:"url"`
+		Token     string `json:"token"`
+		Format    string `json:"format"` // hls or dash
+		DRM       bool   `json:"drm"`
+		IsLive    bool   `json:"is_live"`
+		Subtitles []struct {
+			Type   string `json:"type"`
+			URL    string `json:"url"`
+			Format string `json:"format"`
+			Lang   string `json:"lang"` // Language of the track, French when not given
+		} `json:"subtitles"`
 	} `json:video`
-	Meta struct {
+	Videos  []playerVideo  `json:"videos"`  // Formats of the video, when the player lists several of them
+	Markers []playerMarker `json:"markers"` // Chapter markers, when known
+	Meta    struct {
 		ID              string    `json:"id"`
 		PlurimediaID    string    `json:"plurimedia_id"`
 		Title           string    `json:"title"`
@@ -137,11 +315,13 @@ func (p *FranceTV) GetMediaDetails(ctx context.Context, m *providers.Media) erro
 
 	u := "https://player.webservices.francetelevisions.fr/v1/videos/" + m.ID + "?" + v.Encode()
 
-	if p.debug {
-		log.Printf("[%s] Player url %q", p.Name(), u)
-	}
+	p.logger.Debugf("Player url %q", u)
 
-	r, err := p.getter.Get(ctx, u)
+	r, err := p.authGet(ctx, u)
+	var httpErr *myhttp.HTTPError
+	if errors.As(err, &httpErr) && (httpErr.StatusCode == http.StatusNotFound || httpErr.StatusCode == http.StatusGone) {
+		return providers.ErrShowExpired
+	}
 	if err != nil {
 		return fmt.Errorf("Can't get player: %w", err)
 	}
@@ -155,8 +335,50 @@ func (p *FranceTV) GetMediaDetails(ctx context.Context, m *providers.Media) erro
 	if err != nil {
 		return fmt.Errorf("Can't decode player: %w", err)
 	}
+	if len(info.Title) == 0 {
+		// Media built by GetShowByID, without catalog information
+		info = p.setMetadataFromPlayer(m, &pl)
+	}
+
+	m.DRM = pl.Video.DRM
+	if m.DRM {
+		return providers.ErrDRMProtected
+	}
+	m.IsLive = m.IsLive || pl.Video.IsLive
+	if m.IsLive {
+		return providers.ErrLiveStream
+	}
+	quality := p.quality
+	if m.Match != nil && len(m.Match.Quality) > 0 {
+		quality = m.Match.Quality
+	}
+	selected := false
+	if u, ok := selectFormat(pl.Videos, quality); ok {
+		pl.Video.URL = u
+		selected = true
+	}
+	if len(pl.Video.URL) == 0 && len(pl.Video.Token) == 0 {
+		// The replay window is closed
+		return providers.ErrShowExpired
+	}
 
 	info.URL = pl.Video.URL
+	m.Chapters = chapters(pl.Markers)
+	m.Subtitles = nil
+	for _, s := range pl.Video.Subtitles {
+		if s.Format != "vtt" && s.Format != "srt" {
+			continue
+		}
+		lang := s.Lang
+		if len(lang) == 0 {
+			lang = "fr"
+		}
+		m.Subtitles = append(m.Subtitles, providers.SubtitleTrack{
+			Language: lang,
+			URL:      s.URL,
+			Format:   s.Format,
+		})
+	}
 
 	episodeRegexp := regexp.MustCompile(`S(\d+)\sE(\d+)`)
 	expr := episodeRegexp.FindAllStringSubmatch(pl.Meta.PreTitle, -1)
@@ -164,16 +386,32 @@ func (p *FranceTV) GetMediaDetails(ctx context.Context, m *providers.Media) erro
 		info.Season, _ = strconv.Atoi(expr[0][1])
 		info.Episode, _ = strconv.Atoi(expr[0][2])
 	}
+	p.backfillFromGuide(ctx, m, info)
+
+	m.AudioVersions = nil
+	ts := m.Match != nil && strings.ToLower(m.Match.Container) == "ts"
+	audio := m.Match != nil && (len(m.Match.AudioLanguage) > 0 || m.Match.AudioDescription)
+	if u, ok := selectProgressive(pl.Videos); ok && p.progressive && !ts && len(quality) == 0 && !audio {
+		// A single file is simpler and faster to get than the stream's segments,
+		// but it gives no choice of quality nor audio
+		info.URL = u
+		m.StreamType = providers.StreamMP4
+		p.logger.Debugf("Progressive url %q", info.URL)
+		return nil
+	}
 
 	// Get Token
 	if len(pl.Video.Token) > 0 {
-		if p.debug {
-			log.Printf("[%s] Player token %q", p.Name(), pl.Video.Token)
+		token := pl.Video.Token
+		if selected {
+			// The token is given for the player's default url
+			token = tokenFor(token, pl.Video.URL)
 		}
+		p.logger.Debugf("Player token %q", token)
 
-		r2, err := p.getter.Get(ctx, pl.Video.Token)
+		r2, err := p.authGet(ctx, token)
 		if err != nil {
-			return fmt.Errorf("Can't get token %s: %w", pl.Video.Token, err)
+			return fmt.Errorf("Can't get token %s: %w", token, err)
 		}
 		if p.debug {
 			r2 = httptest.DumpReaderToFile(r2, "francetv-token-"+m.ID+"-")
@@ -189,9 +427,128 @@ func (p *FranceTV) GetMediaDetails(ctx context.Context, m *providers.Media) erro
 		info.URL = pl.URL
 	}
 
-	if p.debug {
-		log.Printf("[%s] Stream url %q", p.Name(), info.URL)
+	// Some videos are only given as a DASH manifest
+	m.StreamType = providers.StreamHLS
+	if pl.Video.Format == "dash" || strings.Contains(strings.ToLower(info.URL), ".mpd") {
+		m.StreamType = providers.StreamDASH
+	}
+
+	if (len(quality) > 0 || audio) && m.StreamType == providers.StreamHLS {
+		master, err := m3u8.NewMaster(ctx, info.URL, p.streamGetter())
+		if err != nil {
+			return fmt.Errorf("Can't get master playlist: %w", err)
+		}
+		if master.IsMaster() && len(quality) > 0 {
+			info.URL, err = master.Quality(quality)
+			if err != nil {
+				return fmt.Errorf("Can't select stream quality: %w", err)
+			}
+		}
+		for _, r := range master.AudioRenditions(info.URL) {
+			m.AudioVersions = append(m.AudioVersions, providers.AudioVersion{
+				Language:         r.Language,
+				Name:             r.Name,
+				Default:          r.Default,
+				AudioDescription: r.IsAudioDescription(),
+				URL:              r.URL,
+			})
+		}
 	}
 
+	p.logger.Debugf("Stream url %q (%s)", info.URL, m.StreamType)
+
 	return nil
 }
+
+// GetShowByID gets the media with the given francetv ID, without searching it into the catalog
+func (p *FranceTV) GetShowByID(ctx context.Context, id string) (*providers.Media, error) {
+	m := &providers.Media{
+		ID:       id,
+		ShowType: providers.Movie,
+		Kind:     providers.KindEpisode,
+		Match:    &providers.MatchRequest{Provider: p.Name()},
+	}
+	m.SetMetaData(&nfo.Movie{})
+	err := p.GetMediaDetails(ctx, m)
+	if err != nil {
+		return nil, fmt.Errorf("Can't get media %q: %w", id, err)
+	}
+	return m, nil
+}
+
+// setMetadataFromPlayer replaces the metadata of the media by those given by the player.
+// A media with an additional title is an episode of the show.
+func (p *FranceTV) setMetadataFromPlayer(m *providers.Media, pl *player) *nfo.MediaInfo {
+	var info *nfo.MediaInfo
+	if len(pl.Meta.AdditionalTitle) > 0 {
+		meta := nfo.EpisodeDetails{}
+		info = &meta.MediaInfo
+		m.SetMetaData(&meta)
+		m.ShowType = providers.Series
+		info.Showtitle = pl.Meta.Title
+		info.Title = pl.Meta.AdditionalTitle
+	} else {
+		meta := nfo.Movie{}
+		info = &meta.MediaInfo
+		m.SetMetaData(&meta)
+		m.ShowType = providers.Movie
+		info.Title = pl.Meta.Title
+	}
+	if !pl.Meta.BroadcastedAt.IsZero() {
+		info.Aired = nfo.Aired(pl.Meta.BroadcastedAt.In(p.location))
+		if m.ShowType == providers.Movie {
+			info.Year = pl.Meta.BroadcastedAt.Year()
+		}
+	}
+	if len(pl.Meta.ImageURL) > 0 {
+		info.Thumb = []nfo.Thumb{{Aspect: "thumb", URL: pl.Meta.ImageURL}}
+	}
+	info.UniqueID = []nfo.ID{{ID: m.ID, Type: "FRANCETV:ID"}}
+	return info
+}
+
+// Download resolves the media's stream and downloads it into destPath. Progressive MP4 files are
+// saved as they are into .mp4 files, and muxed by ffmpeg otherwise.
+func (p *FranceTV) Download(ctx context.Context, m *providers.Media, destPath string, progress providers.ProgressFunc) error {
+	info := m.Metadata.GetMediaInfo()
+	if m.IsLive {
+		return fmt.Errorf("Can't download %q: %w", m.ID, providers.ErrLiveStream)
+	}
+	if len(info.URL) == 0 {
+		err := p.GetMediaDetails(ctx, m)
+		if err != nil {
+			return err
+		}
+		// The details may have replaced the metadata
+		info = m.Metadata.GetMediaInfo()
+	}
+	if len(info.URL) == 0 {
+		return fmt.Errorf("Can't get stream url for %q", m.ID)
+	}
+	meta := download.Metadata{
+		Title:   info.Title,
+		Show:    info.Showtitle,
+		Comment: info.Plot,
+		Channel: info.Studio,
+		Season:  info.Season,
+		Episode: info.Episode,
+	}
+	switch m.StreamType {
+	case providers.StreamDASH:
+		return download.MuxDASH(ctx, info.URL, destPath, meta, download.FFMepgWithDebug(p.debug))
+	case providers.StreamMP4:
+		if strings.ToLower(filepath.Ext(destPath)) != ".mp4" {
+			return download.MuxFile(ctx, info.URL, destPath, meta, download.FFMepgWithDebug(p.debug))
+		}
+		return download.HTTP(ctx, info.URL, destPath,
+			download.WithGetter(p.streamGetter()),
+			download.WithProgress(progress),
+			download.WithDebug(p.debug),
+		)
+	}
+	return download.HLS(ctx, info.URL, destPath,
+		download.WithGetter(p.streamGetter()),
+		download.WithProgress(progress),
+		download.WithDebug(p.debug),
+	)
+}
@@ -0,0 +1,161 @@
+package francetv
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/simulot/aspiratv/providers"
+	"github.com/simulot/aspiratv/providers/francetv/query"
+)
+
+func TestBroadcastDateTimezone(t *testing.T) {
+	// 2020-05-01 23:30 UTC is 2020-05-02 01:30 in Paris
+	var ts query.UnixTimeStamp
+	if err := json.Unmarshal([]byte("1588375800"), &ts); err != nil {
+		t.Fatal(err)
+	}
+	if ts.Time().Location() != time.UTC {
+		t.Errorf("Expecting timestamp to be parsed as UTC, got %s", ts.Time().Location())
+	}
+
+	// The first diffusion of 1001 in the catalog is broadcasted at this timestamp
+	tests := []struct {
+		name string
+		conf []func(*FranceTV)
+		want string
+	}{
+		{"default", nil, "2020-05-02 01:30"},
+		{"utc", []func(*FranceTV){WithTimezone(time.UTC)}, "2020-05-01 23:30"},
+		{"nil location", []func(*FranceTV){WithTimezone(nil)}, "2020-05-02 01:30"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := append([]func(*FranceTV){WithGetter(catalogGetter{results: filepath.Join("testdata", "duplicates.json")})}, tt.conf...)
+			p, _ := New(conf...)
+			if err := p.getAlgoliaConfig(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			medias, errc := p.queryAlgolia(context.Background(), &providers.MatchRequest{Provider: "francetv", Show: "cyrano de bergerac"})
+			got := ""
+			for m := range medias {
+				if info := m.Metadata.GetMediaInfo(); m.ID == "1001" && info.Plot == "Première diffusion" {
+					got = info.Aired.Time().Format("2006-01-02 15:04")
+				}
+			}
+			if err := <-errc; err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Expecting aired date %s, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestApplyConfig(t *testing.T) {
+	p, err := NewWithConfig(FranceTVConfig{Settings: providers.Settings{
+		Quality:         "720p",
+		Timezone:        "UTC",
+		CatalogLimit:    10,
+		SkipProgressive: true,
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.quality != "720p" || p.location != time.UTC || p.limit != 10 || p.progressive {
+		t.Errorf("Settings not applied: quality %q, location %s, limit %d, progressive %v", p.quality, p.location, p.limit, p.progressive)
+	}
+
+	err = p.ApplyConfig(FranceTVConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.quality != "720p" || p.location != time.UTC || p.limit != 10 {
+		t.Errorf("Expecting empty settings to keep current values")
+	}
+
+	_, err = NewWithConfig(FranceTVConfig{Settings: providers.Settings{Timezone: "Nowhere/Land"}})
+	if err == nil {
+		t.Errorf("Expecting an error for an unknown time zone")
+	}
+}
+
+// headersGetter records the headers of the last request
+type headersGetter struct {
+	headers http.Header
+}
+
+func (g *headersGetter) Get(ctx context.Context, uri string) (io.ReadCloser, error) {
+	return g.DoWithContext(ctx, "GET", uri, nil, nil)
+}
+
+func (g *headersGetter) DoWithContext(ctx context.Context, method string, theURL string, headers http.Header, body io.Reader) (io.ReadCloser, error) {
+	g.headers = headers
+	return ioutil.NopCloser(strings.NewReader("")), nil
+}
+
+func TestApplyConfigHeaders(t *testing.T) {
+	g := &headersGetter{}
+	p, err := NewWithConfig(FranceTVConfig{Settings: providers.Settings{
+		Headers: map[string]string{"Referer": "https://www.france.tv/"},
+	}}, WithGetter(g))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := p.getter.Get(context.TODO(), "http://example.com/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Close()
+	if g.headers.Get("Referer") != "https://www.france.tv/" {
+		t.Errorf("Expecting the headers to be sent by the given getter, got %v", g.headers)
+	}
+
+	// Configuring again replaces the headers
+	err = p.ApplyConfig(FranceTVConfig{Settings: providers.Settings{
+		Headers: map[string]string{"User-Agent": "Firefox"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err = p.getter.Get(context.TODO(), "http://example.com/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Close()
+	if g.headers.Get("User-Agent") != "Firefox" || len(g.headers.Get("Referer")) > 0 {
+		t.Errorf("Expecting only the last configured headers, got %v", g.headers)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	p, _ := New(WithGetter(catalogGetter{results: filepath.Join("testdata", "duplicates.json")}))
+	tests := []struct {
+		keywords string
+		want     int
+	}{
+		{"cyrano", 2},
+		{"Cyrano de Bergerac", 1},
+		{"bergerac", 1},
+		{"dalton", 0},
+		{"", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.keywords, func(t *testing.T) {
+			got, err := p.Search(context.Background(), tt.keywords)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got == nil || len(got) != tt.want {
+				t.Errorf("Expecting %d media, got %v", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,187 @@
+package francetv
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/simulot/aspiratv/metadata/nfo"
+	"github.com/simulot/aspiratv/providers"
+)
+
+// WithEpisodeGuide enables the episode guide: missing season, episode and title are read from the show page.
+// It's an extra request for each show, and the page may change without notice.
+func WithEpisodeGuide(enabled bool) func(ftv *FranceTV) {
+	return func(ftv *FranceTV) {
+		ftv.guide = enabled
+	}
+}
+
+// guideEpisode is an episode described by the show page
+type guideEpisode struct {
+	Title   string
+	Season  int
+	Episode int
+	URL     string
+}
+
+// backfillFromGuide completes the season, episode and title of the media with the guide of its show page.
+// Errors are only logged: the media is still usable without them.
+func (p *FranceTV) backfillFromGuide(ctx context.Context, m *providers.Media, info *nfo.MediaInfo) {
+	if !p.guide || len(m.ShowURL) == 0 || (info.Episode > 0 && len(info.Title) > 0) {
+		return
+	}
+	episodes, err := p.episodeGuideOf(ctx, m.ShowURL)
+	if err != nil {
+		p.logger.Debugf("Can't read the episode guide of %q: %s", m.ShowURL, err)
+		return
+	}
+	e, ok := findGuideEpisode(episodes, info)
+	if !ok {
+		return
+	}
+	if info.Episode == 0 && e.Episode > 0 {
+		info.Episode = e.Episode
+		if e.Season > 0 {
+			// The season may be the broadcast year, used when the catalog doesn't give one
+			info.Season = e.Season
+		}
+	}
+	if len(info.Title) == 0 {
+		info.Title = e.Title
+	}
+}
+
+// episodeGuideOf gives the episodes of the show page, read once per page
+func (p *FranceTV) episodeGuideOf(ctx context.Context, u string) ([]guideEpisode, error) {
+	if episodes, ok := p.guides.Load(u); ok {
+		return episodes.([]guideEpisode), nil
+	}
+	r, err := p.getter.Get(ctx, u)
+	if err != nil {
+		return nil, fmt.Errorf("Can't get show page: %w", err)
+	}
+	defer r.Close()
+	episodes, err := parseGuide(r)
+	if err != nil {
+		return nil, err
+	}
+	p.guides.Store(u, episodes)
+	return episodes, nil
+}
+
+// findGuideEpisode gives the episode of the guide having the media's id in its url, or the media's title
+func findGuideEpisode(episodes []guideEpisode, info *nfo.MediaInfo) (guideEpisode, bool) {
+	for _, id := range info.UniqueID {
+		if id.Type != "FRANCETV:ID" || len(id.ID) == 0 {
+			continue
+		}
+		for _, e := range episodes {
+			if strings.Contains(e.URL, "/"+id.ID+"-") {
+				return e, true
+			}
+		}
+	}
+	if len(info.Title) == 0 {
+		return guideEpisode{}, false
+	}
+	title := providers.NormalizeForMatch(info.Title)
+	for _, e := range episodes {
+		if providers.NormalizeForMatch(e.Title) == title {
+			return e, true
+		}
+	}
+	return guideEpisode{}, false
+}
+
+// parseGuide reads the episodes given by the JSON-LD scripts of the show page.
+// Scripts that can't be decoded are ignored.
+func parseGuide(r io.Reader) ([]guideEpisode, error) {
+	doc, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("Can't parse show page: %w", err)
+	}
+	episodes := []guideEpisode{}
+	doc.Find(`script[type="application/ld+json"]`).Each(func(_ int, s *goquery.Selection) {
+		var v interface{}
+		if json.Unmarshal([]byte(s.Text()), &v) != nil {
+			return
+		}
+		episodes = walkGuide(v, 0, episodes)
+	})
+	return episodes, nil
+}
+
+// walkGuide collects the TVEpisode objects found into v. season is the number of the enclosing season, if any.
+func walkGuide(v interface{}, season int, episodes []guideEpisode) []guideEpisode {
+	switch v := v.(type) {
+	case []interface{}:
+		for _, i := range v {
+			episodes = walkGuide(i, season, episodes)
+		}
+	case map[string]interface{}:
+		switch ldType(v) {
+		case "TVSeason":
+			if n := ldNumber(v["seasonNumber"]); n > 0 {
+				season = n
+			}
+		case "TVEpisode":
+			e := guideEpisode{
+				Title:   ldString(v["name"]),
+				Season:  season,
+				Episode: ldNumber(v["episodeNumber"]),
+				URL:     ldString(v["url"]),
+			}
+			if s, ok := v["partOfSeason"].(map[string]interface{}); ok {
+				if n := ldNumber(s["seasonNumber"]); n > 0 {
+					e.Season = n
+				}
+			}
+			return append(episodes, e)
+		}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys) // Same order at each run
+		for _, k := range keys {
+			episodes = walkGuide(v[k], season, episodes)
+		}
+	}
+	return episodes
+}
+
+// ldType gives the type of the JSON-LD object, the first one when there are several
+func ldType(v map[string]interface{}) string {
+	switch t := v["@type"].(type) {
+	case string:
+		return t
+	case []interface{}:
+		if len(t) > 0 {
+			return ldString(t[0])
+		}
+	}
+	return ""
+}
+
+func ldString(v interface{}) string {
+	s, _ := v.(string)
+	return strings.TrimSpace(s)
+}
+
+// ldNumber reads a number given as a number or as a string, 0 when it isn't a number
+func ldNumber(v interface{}) int {
+	switch n := v.(type) {
+	case float64:
+		return int(n)
+	case string:
+		i, _ := strconv.Atoi(strings.TrimSpace(n))
+		return i
+	}
+	return 0
+}
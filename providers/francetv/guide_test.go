@@ -0,0 +1,71 @@
+package francetv
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/simulot/aspiratv/metadata/nfo"
+	"github.com/simulot/aspiratv/providers"
+)
+
+func TestParseGuide(t *testing.T) {
+	f, err := os.Open("testdata/showpage.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	episodes, err := parseGuide(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []guideEpisode{
+		{Title: "Joe", Season: 2, Episode: 3, URL: "https://www.france.tv/france-3/les-dalton/saison-2/1234567-joe.html"},
+		{Title: "La fièvre de l'or", Season: 2, Episode: 4, URL: "https://www.france.tv/france-3/les-dalton/saison-2/1234568-la-fievre-de-l-or.html"},
+		{Title: "Le shérif", Season: 3, Episode: 1},
+	}
+	if len(episodes) != len(want) {
+		t.Fatalf("Expecting %+v, got %+v", want, episodes)
+	}
+	for i := range want {
+		if episodes[i] != want[i] {
+			t.Errorf("Expecting %+v, got %+v", want[i], episodes[i])
+		}
+	}
+}
+
+func TestBackfillFromGuide(t *testing.T) {
+	page, err := ioutil.ReadFile("testdata/showpage.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const showURL = "https://www.france.tv/france-3/les-dalton/"
+	tests := []struct {
+		name        string
+		guide       bool
+		showURL     string
+		info        nfo.MediaInfo
+		wantSeason  int
+		wantEpisode int
+		wantTitle   string
+	}{
+		{"by id", true, showURL, nfo.MediaInfo{Title: "Joe et les autres", Season: 2020, UniqueID: []nfo.ID{{ID: "1234567", Type: "FRANCETV:ID"}}}, 2, 3, "Joe et les autres"},
+		{"by title", true, showURL, nfo.MediaInfo{Title: "La Fievre de l'or", Season: 2020}, 2, 4, "La Fievre de l'or"},
+		{"numbers kept", true, showURL, nfo.MediaInfo{Title: "Joe", Season: 1, Episode: 7}, 1, 7, "Joe"},
+		{"unknown episode", true, showURL, nfo.MediaInfo{Title: "Inconnu", Season: 2020}, 2020, 0, "Inconnu"},
+		{"disabled", false, showURL, nfo.MediaInfo{Title: "Joe", Season: 2020}, 2020, 0, "Joe"},
+		{"page not found", true, "https://www.france.tv/france-3/inconnu/", nfo.MediaInfo{Title: "Joe", Season: 2020}, 2020, 0, "Joe"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := providers.StaticGetter(map[string]string{showURL: string(page)})
+			p, _ := New(WithGetter(g), WithEpisodeGuide(tt.guide))
+			info := tt.info
+			p.backfillFromGuide(context.TODO(), &providers.Media{ID: "1", ShowURL: tt.showURL}, &info)
+			if info.Season != tt.wantSeason || info.Episode != tt.wantEpisode || info.Title != tt.wantTitle {
+				t.Errorf("Expecting S%d E%d %q, got S%d E%d %q", tt.wantSeason, tt.wantEpisode, tt.wantTitle, info.Season, info.Episode, info.Title)
+			}
+		})
+	}
+}
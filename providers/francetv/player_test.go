@@ -0,0 +1,198 @@
+package francetv
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/simulot/aspiratv/metadata/nfo"
+	"github.com/simulot/aspiratv/net/myhttp"
+	"github.com/simulot/aspiratv/providers"
+)
+
+type playerGetter struct {
+	body string
+	err  error
+}
+
+func (g playerGetter) Get(ctx context.Context, uri string) (io.ReadCloser, error) {
+	if g.err != nil {
+		return nil, g.err
+	}
+	return ioutil.NopCloser(strings.NewReader(g.body)), nil
+}
+
+func (g playerGetter) DoWithContext(ctx context.Context, method string, theURL string, headers http.Header, body io.Reader) (io.ReadCloser, error) {
+	return g.Get(ctx, theURL)
+}
+
+func TestGetMediaDetailsExpired(t *testing.T) {
+	tests := []struct {
+		name   string
+		getter playerGetter
+		want   error
+	}{
+		{"no video", playerGetter{body: `{"video":{"url":"","token":""},"meta":{"id":"1"}}`}, providers.ErrShowExpired},
+		{"not found", playerGetter{err: &myhttp.HTTPError{StatusCode: http.StatusNotFound, Status: "404 Not Found"}}, providers.ErrShowExpired},
+		{"drm", playerGetter{body: `{"video":{"url":"http://example.com/master.m3u8","drm":true}}`}, providers.ErrDRMProtected},
+		{"live", playerGetter{body: `{"video":{"url":"http://example.com/master.m3u8","is_live":true}}`}, providers.ErrLiveStream},
+		{"available", playerGetter{body: `{"video":{"url":"http://example.com/master.m3u8"}}`}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, _ := New(WithGetter(tt.getter))
+			m := &providers.Media{ID: "1", Metadata: &nfo.Movie{}}
+			if err := p.GetMediaDetails(context.TODO(), m); err != tt.want {
+				t.Errorf("GetMediaDetails() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetMediaDetailsStreamType(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"hls", `{"video":{"url":"http://example.com/master.m3u8","format":"hls"}}`, providers.StreamHLS},
+		{"dash format", `{"video":{"url":"http://example.com/manifest","format":"dash"}}`, providers.StreamDASH},
+		{"dash manifest", `{"video":{"url":"http://example.com/manifest.mpd"}}`, providers.StreamDASH},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, _ := New(WithGetter(playerGetter{body: tt.body}))
+			m := &providers.Media{ID: "1", Metadata: &nfo.Movie{}, Match: &providers.MatchRequest{Quality: "720p"}}
+			if tt.want == providers.StreamHLS {
+				m.Match = nil // The master playlist can't be served by playerGetter
+			}
+			if err := p.GetMediaDetails(context.TODO(), m); err != nil {
+				t.Fatal(err)
+			}
+			if m.StreamType != tt.want {
+				t.Errorf("Expecting stream type %q, got %q", tt.want, m.StreamType)
+			}
+		})
+	}
+}
+
+func TestGetShowByID(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		wantType  providers.ShowType
+		wantShow  string
+		wantTitle string
+		wantErr   error
+	}{
+		{
+			"episode",
+			`{"video":{"url":"http://example.com/master.m3u8"},"meta":{"id":"123","title":"Les Dalton","additional_title":"Joe","pre_title":"S2 E3","broadcasted_at":"2020-05-01T18:00:00Z"}}`,
+			providers.Series, "Les Dalton", "Joe", nil,
+		},
+		{
+			"movie",
+			`{"video":{"url":"http://example.com/master.m3u8"},"meta":{"id":"123","title":"Cyrano de Bergerac","broadcasted_at":"2020-05-01T18:00:00Z"}}`,
+			providers.Movie, "", "Cyrano de Bergerac", nil,
+		},
+		{
+			"expired",
+			`{"video":{"url":"","token":""},"meta":{"id":"123","title":"Les Dalton"}}`,
+			providers.Movie, "", "", providers.ErrShowExpired,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, _ := New(WithGetter(playerGetter{body: tt.body}))
+			m, err := p.GetShowByID(context.TODO(), "123")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("GetShowByID() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			info := m.Metadata.GetMediaInfo()
+			if m.ShowType != tt.wantType || info.Showtitle != tt.wantShow || info.Title != tt.wantTitle {
+				t.Errorf("Unexpected media %v %q %q", m.ShowType, info.Showtitle, info.Title)
+			}
+			if info.URL != "http://example.com/master.m3u8" || m.Match == nil || m.Match.Provider != "francetv" {
+				t.Errorf("Expecting a resolved media, got %+v", m)
+			}
+			if tt.wantType == providers.Series && (info.Season != 2 || info.Episode != 3) {
+				t.Errorf("Expecting s02e03, got s%02de%02d", info.Season, info.Episode)
+			}
+		})
+	}
+}
+
+func TestDownloadLive(t *testing.T) {
+	p, _ := New(WithGetter(playerGetter{body: `{"video":{"url":"http://example.com/live.m3u8","is_live":true}}`}))
+	m := &providers.Media{ID: "1", Metadata: &nfo.Movie{}}
+	err := p.Download(context.TODO(), m, "live.mp4", nil)
+	if !errors.Is(err, providers.ErrLiveStream) {
+		t.Errorf("Expecting error %v, got %v", providers.ErrLiveStream, err)
+	}
+	if !m.IsLive {
+		t.Errorf("Expecting media to be flagged as live")
+	}
+}
+
+func TestGetMediaDetailsAudioVersions(t *testing.T) {
+	g := providers.StaticGetter(map[string]string{
+		"https://player.webservices.francetelevisions.fr/v1/videos/1": `{"video":{"url":"http://example.com/master.m3u8","format":"hls"}}`,
+		"http://example.com/master.m3u8": `#EXTM3U
+#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="audio",LANGUAGE="fr",NAME="Français",DEFAULT=YES,URI="audio_fr.m3u8"
+#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="audio",LANGUAGE="fr",NAME="Audiodescription",CHARACTERISTICS="public.accessibility.describes-video",URI="audio_ad.m3u8"
+#EXT-X-STREAM-INF:BANDWIDTH=2500000,RESOLUTION=1280x720,AUDIO="audio"
+video_720.m3u8
+`,
+	})
+	p, _ := New(WithGetter(g))
+	m := &providers.Media{ID: "1", Metadata: &nfo.Movie{}, Match: &providers.MatchRequest{AudioDescription: true}}
+	if err := p.GetMediaDetails(context.TODO(), m); err != nil {
+		t.Fatal(err)
+	}
+	want := []providers.AudioVersion{
+		{Language: "fr", Name: "Français", Default: true, URL: "http://example.com/audio_fr.m3u8"},
+		{Language: "fr", Name: "Audiodescription", AudioDescription: true, URL: "http://example.com/audio_ad.m3u8"},
+	}
+	if len(m.AudioVersions) != len(want) {
+		t.Fatalf("Expecting %+v, got %+v", want, m.AudioVersions)
+	}
+	for i := range want {
+		if m.AudioVersions[i] != want[i] {
+			t.Errorf("Expecting %+v, got %+v", want[i], m.AudioVersions[i])
+		}
+	}
+}
+
+func TestGetMediaDetailsSubtitles(t *testing.T) {
+	g := providers.StaticGetter(map[string]string{
+		"https://player.webservices.francetelevisions.fr/v1/videos/1": `{"video":{"url":"http://example.com/master.m3u8","format":"hls","subtitles":[
+			{"type":"accessibilite","url":"http://example.com/fr.vtt","format":"vtt"},
+			{"type":"vo","url":"http://example.com/en.vtt","format":"vtt","lang":"en"},
+			{"type":"accessibilite","url":"http://example.com/fr.ttml","format":"ttml"}
+		]}}`,
+	})
+	p, _ := New(WithGetter(g))
+	m := &providers.Media{ID: "1", Metadata: &nfo.Movie{}, Match: &providers.MatchRequest{}}
+	if err := p.GetMediaDetails(context.TODO(), m); err != nil {
+		t.Fatal(err)
+	}
+	want := []providers.SubtitleTrack{
+		{Language: "fr", URL: "http://example.com/fr.vtt", Format: "vtt"},
+		{Language: "en", URL: "http://example.com/en.vtt", Format: "vtt"},
+	}
+	if len(m.Subtitles) != len(want) {
+		t.Fatalf("Expecting %+v, got %+v", want, m.Subtitles)
+	}
+	for i := range want {
+		if m.Subtitles[i] != want[i] {
+			t.Errorf("Expecting %+v, got %+v", want[i], m.Subtitles[i])
+		}
+	}
+}
@@ -1,9 +1,11 @@
 package query
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -109,6 +111,7 @@ type Hits struct {
 	// Ranges                  Ranges          `json:"ranges"`
 	Image         Image         `json:"image,omitempty"`
 	Categories    []Categories  `json:"categories"`
+	Tags          labels        `json:"tags"`
 	Channels      []Channels    `json:"channels"`
 	Program       Program       `json:"program"`
 	Season        seasonWrapper `json:"season"`
@@ -121,16 +124,58 @@ type Hits struct {
 	// Parent *Hits `json:"parent"`
 }
 type Results struct {
-	Hits             []Hits `json:"hits"`
-	NbHits           int    `json:"nbHits"`
-	Page             int    `json:"page"`
-	NbPages          int    `json:"nbPages"`
-	HitsPerPage      int    `json:"hitsPerPage"`
-	ProcessingTimeMS int    `json:"processingTimeMS"`
-	ExhaustiveNbHits bool   `json:"exhaustiveNbHits"`
-	Query            string `json:"query"`
-	Params           string `json:"params"`
-	Index            string `json:"index"`
+	Hits             []Hits  `json:"hits"`
+	Skipped          []error `json:"-"` // Errors of hits that couldn't be decoded and were skipped
+	NbHits           int     `json:"nbHits"`
+	Page             int     `json:"page"`
+	NbPages          int     `json:"nbPages"`
+	HitsPerPage      int     `json:"hitsPerPage"`
+	ProcessingTimeMS int     `json:"processingTimeMS"`
+	ExhaustiveNbHits bool    `json:"exhaustiveNbHits"`
+	Query            string  `json:"query"`
+	Params           string  `json:"params"`
+	Index            string  `json:"index"`
+}
+
+// UnmarshalJSON decodes the hits one by one, so a faulty hit is skipped instead of failing the whole result
+func (v *Results) UnmarshalJSON(b []byte) error {
+	type results Results // Without the UnmarshalJSON method
+	var r struct {
+		results
+		Hits json.RawMessage `json:"hits"`
+	}
+	err := json.Unmarshal(b, &r)
+	if err != nil {
+		return err
+	}
+	*v = Results(r.results)
+	if len(r.Hits) == 0 || string(r.Hits) == "null" {
+		return nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(r.Hits))
+	t, err := dec.Token()
+	if err != nil {
+		return fmt.Errorf("Can't decode hits: %w", err)
+	}
+	if d, ok := t.(json.Delim); !ok || d != '[' {
+		return fmt.Errorf("Can't decode hits: expecting an array, got %v", t)
+	}
+	for i := 0; dec.More(); i++ {
+		var raw json.RawMessage
+		err = dec.Decode(&raw)
+		if err != nil {
+			return fmt.Errorf("Can't decode hit %d: %w", i, err)
+		}
+		var h Hits
+		err = json.Unmarshal(raw, &h)
+		if err != nil {
+			v.Skipped = append(v.Skipped, fmt.Errorf("Can't decode hit %d: %w", i, err))
+			continue
+		}
+		v.Hits = append(v.Hits, h)
+	}
+	return nil
 }
 
 type UnixTimeStamp time.Time
@@ -145,7 +190,7 @@ func (v *UnixTimeStamp) UnmarshalJSON(b []byte) error {
 	if err != nil {
 		return fmt.Errorf("Can't convert unix timestamp: %w", err)
 	}
-	*v = UnixTimeStamp(time.Unix(i, 0))
+	*v = UnixTimeStamp(time.Unix(i, 0).UTC())
 	return nil
 }
 
@@ -188,6 +233,34 @@ func (v *intOrString) UnmarshalJSON(b []byte) error {
 
 }
 
+// labels decodes a list of strings, or a list of objects having a label.
+// Malformed tags are ignored, they aren't worth losing the hit.
+type labels []string
+
+func (v *labels) UnmarshalJSON(b []byte) error {
+	*v = (*v)[:0]
+	var items []json.RawMessage
+	if json.Unmarshal(b, &items) != nil {
+		return nil
+	}
+	for _, item := range items {
+		var s string
+		if json.Unmarshal(item, &s) != nil {
+			var o struct {
+				Label string `json:"label"`
+			}
+			if json.Unmarshal(item, &o) != nil {
+				continue
+			}
+			s = o.Label
+		}
+		if s = strings.TrimSpace(s); len(s) > 0 {
+			*v = append(*v, s)
+		}
+	}
+	return nil
+}
+
 func (v intOrString) String() string {
 	return string(v)
 }
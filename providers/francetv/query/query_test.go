@@ -30,3 +30,61 @@ func Test_Structure(t *testing.T) {
 	}
 
 }
+
+func TestResultsSkipFaultyHits(t *testing.T) {
+	b := []byte(`{"results":[{"hits":[{"id":1,"title":"un"},{"id":"deux","title":"deux"},{"id":3,"title":"tr` + "\xe8" + `s"}],"nbHits":3,"page":0}]}`)
+	r := QueryResults{}
+	err := json.Unmarshal(b, &r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Results) != 1 {
+		t.Fatalf("Expecting 1 result, got %d", len(r.Results))
+	}
+	res := r.Results[0]
+	if len(res.Hits) != 2 || res.Hits[0].ID != 1 || res.Hits[1].ID != 3 {
+		t.Errorf("Expecting hits 1 and 3, got %#v", res.Hits)
+	}
+	if len(res.Skipped) != 1 {
+		t.Errorf("Expecting 1 skipped hit, got %d", len(res.Skipped))
+	}
+	if res.NbHits != 3 {
+		t.Errorf("Expecting nbHits to be decoded, got %d", res.NbHits)
+	}
+
+	err = json.Unmarshal([]byte(`{"results":[{"hits":[{"id":1},{"id":`), &r)
+	if err == nil {
+		t.Errorf("Expecting an error for a truncated response")
+	}
+}
+
+func TestHitsTags(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		want []string
+	}{
+		{"strings", `{"tags":["Policier"," Enquête ",""]}`, []string{"Policier", "Enquête"}},
+		{"objects", `{"tags":[{"id":1,"label":"Policier"},{"id":2,"label":"Thriller"}]}`, []string{"Policier", "Thriller"}},
+		{"null", `{"tags":null}`, nil},
+		{"missing", `{}`, nil},
+		{"not a list", `{"tags":"Policier"}`, nil},
+		{"malformed items", `{"tags":["Policier",42,{"label":["x"]},{"label":"Thriller"}]}`, []string{"Policier", "Thriller"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := Hits{}
+			if err := json.Unmarshal([]byte(tt.json), &h); err != nil {
+				t.Fatal(err)
+			}
+			if len(h.Tags) != len(tt.want) {
+				t.Fatalf("Expecting tags %q, got %q", tt.want, h.Tags)
+			}
+			for i := range tt.want {
+				if h.Tags[i] != tt.want[i] {
+					t.Errorf("Expecting tags %q, got %q", tt.want, h.Tags)
+				}
+			}
+		})
+	}
+}
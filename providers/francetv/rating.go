@@ -0,0 +1,29 @@
+package francetv
+
+import (
+	"strconv"
+	"strings"
+)
+
+// csaMinAge gives the minimum age of a CSA rating code: TP for all audiences, 10, 12, 16 or 18,
+// or the older codes CSA1 to CSA5. It's 0 for all audiences and unknown codes.
+func csaMinAge(code string) int {
+	code = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(code)), "-")
+	switch code {
+	case "", "TP", "CSA1":
+		return 0
+	case "CSA2":
+		return 10
+	case "CSA3":
+		return 12
+	case "CSA4":
+		return 16
+	case "CSA5":
+		return 18
+	}
+	age, err := strconv.Atoi(code)
+	if err != nil || age < 0 {
+		return 0
+	}
+	return age
+}
@@ -0,0 +1,27 @@
+package francetv
+
+import "testing"
+
+func Test_csaMinAge(t *testing.T) {
+	tests := []struct {
+		code string
+		want int
+	}{
+		{"", 0},
+		{"TP", 0},
+		{"tp", 0},
+		{"10", 10},
+		{"-12", 12},
+		{"16", 16},
+		{"18", 18},
+		{"CSA1", 0},
+		{"CSA3", 12},
+		{"CSA5", 18},
+		{"unknown", 0},
+	}
+	for _, tt := range tests {
+		if got := csaMinAge(tt.code); got != tt.want {
+			t.Errorf("csaMinAge(%q) = %d, want %d", tt.code, got, tt.want)
+		}
+	}
+}
@@ -3,18 +3,17 @@ package gulli
 import (
 	"context"
 	"fmt"
-	"log"
 	"strings"
 	"sync"
 
 	"github.com/gocolly/colly"
 	"github.com/simulot/aspiratv/metadata/nfo"
+	"github.com/simulot/aspiratv/providers"
 )
 
 var protectCartoonList sync.RWMutex
 
 func (p *Gulli) getCartoonPage(ctx context.Context, showTitle string) (string, string, error) {
-	showTitle = strings.ToLower(showTitle)
 
 	protectCartoonList.Lock()
 	if p.cartoonList == nil {
@@ -28,11 +27,11 @@ func (p *Gulli) getCartoonPage(ctx context.Context, showTitle string) (string, s
 	protectCartoonList.RLock()
 	defer protectCartoonList.RUnlock()
 	for _, c := range p.cartoonList {
-		if strings.Contains(strings.ToLower(c.Title), showTitle) {
+		if providers.ContainsForMatch(c.Title, showTitle) {
 			return c.URL, c.ThumbURL, nil
 		}
 	}
-	log.Printf("[%s] Can't find cartoon %q", p.Name(), showTitle)
+	p.logger.Warnf("Can't find cartoon %q", showTitle)
 	return "", "", fmt.Errorf("Cartoon %q not found", showTitle)
 }
 
@@ -2,14 +2,15 @@ package gulli
 
 import (
 	"context"
+	"fmt"
 	"io"
-	"log"
 	"net/http"
 	"os"
 	"path"
 	"strings"
 	"time"
 
+	"github.com/simulot/aspiratv/download"
 	"github.com/simulot/aspiratv/metadata/nfo"
 	"github.com/simulot/aspiratv/net/myhttp"
 	"github.com/simulot/aspiratv/parsers/htmlparser"
@@ -22,6 +23,7 @@ type getter interface {
 
 // Gulli provider gives access to Gulli catchup tv
 type Gulli struct {
+	providers.NoHealthCheck
 	getter            getter
 	htmlParserFactory *htmlparser.Factory
 	seenShows         map[string]bool
@@ -31,6 +33,7 @@ type Gulli struct {
 	cartoonList       []ShowEntry
 	tvshows           map[string]*nfo.TVShow
 	keepBonuses       bool
+	logger            providers.Logger
 }
 
 // init registers Gulli provider
@@ -46,12 +49,13 @@ func init() {
 func New() (*Gulli, error) {
 
 	p := &Gulli{
-		getter:            myhttp.DefaultClient,
+		getter:            myhttp.RecorderFromEnv(myhttp.DefaultClient),
 		htmlParserFactory: nil,
 		seenShows:         map[string]bool{},
 		deadline:          30 * time.Second,
 		tvshows:           map[string]*nfo.TVShow{},
 	}
+	p.logger = providers.NewStdLogger(p.Name(), false)
 	if rt, ok := p.getter.(http.RoundTripper); ok {
 		p.htmlParserFactory = htmlparser.NewFactory(htmlparser.SetTransport(rt))
 	} else {
@@ -70,6 +74,7 @@ func New() (*Gulli, error) {
 func (p *Gulli) Configure(c providers.Config) {
 	p.keepBonuses = c.KeepBonus
 	p.debug = c.Debug
+	p.logger = c.GetLogger(p.Name())
 	if p.debug {
 		p.deadline = time.Hour
 	} else {
@@ -88,35 +93,58 @@ func withGetter(g getter) func(p *Gulli) {
 // Name return the name of the provider
 func (p Gulli) Name() string { return "gulli" }
 
+// Subfolder is the preferred folder of the media in the destinations, when sources are kept apart
+func (p Gulli) Subfolder() string { return "Gulli" }
+
+// Capabilities gives the features supported by the provider
+func (p Gulli) Capabilities() providers.Capabilities {
+	return providers.Capabilities{HLS: true, Thumbnails: true}
+}
+
 // MediaList download the shows catalog from the web site.
-func (p *Gulli) MediaList(ctx context.Context, mm []*providers.MatchRequest) chan *providers.Media {
+func (p *Gulli) MediaList(ctx context.Context, mm []*providers.MatchRequest) (chan *providers.Media, <-chan error) {
 	shows := make(chan *providers.Media)
+	errc := make(chan error, 1)
 
 	go func() {
-		defer close(shows)
-		cat, err := p.downloadCatalog(ctx)
+		var err error
+		defer func() {
+			providers.CloseMediaList(shows, errc, err)
+		}()
+		var cat []ShowEntry
+		cat, err = p.downloadCatalog(ctx)
 		if err != nil {
-			log.Printf("[%s] Can't call replay catalog: %q", p.Name(), err)
+			err = fmt.Errorf("Can't call replay catalog: %w", err)
 			return
 		}
 
 		for _, s := range cat {
 			for _, m := range mm {
-				if strings.Contains(strings.ToLower(s.Title), m.Show) {
-					ID, err := p.getFirstEpisodeID(ctx, s)
-					showTitles, err := p.getPlayer(ctx, m, ID)
+				if providers.ContainsForMatch(s.Title, m.Show) {
+					var ID string
+					ID, err = p.getFirstEpisodeID(ctx, s)
+					if err != nil {
+						err = fmt.Errorf("Can't get episodes of %q: %w", s.Title, err)
+						return
+					}
+					var showTitles []*providers.Media
+					showTitles, err = p.getPlayer(ctx, m, ID)
 					if err != nil {
-						log.Printf("[%s] Can't decode replay catalog: %q", p.Name(), err)
+						err = fmt.Errorf("Can't decode replay catalog: %w", err)
 						return
 					}
 					for _, s := range showTitles {
-						shows <- s
+						select {
+						case shows <- s:
+						case <-ctx.Done():
+							return
+						}
 					}
 				}
 			}
 		}
 	}()
-	return shows
+	return shows, errc
 }
 
 // GetMediaDetails gather show information from dedicated web page.
@@ -139,3 +167,16 @@ func (p *Gulli) GetMediaDetails(ctx context.Context, m *providers.Media) error {
 	}
 	return nil
 }
+
+// Download the media's HLS stream into destPath
+func (p *Gulli) Download(ctx context.Context, m *providers.Media, destPath string, progress providers.ProgressFunc) error {
+	info := m.Metadata.GetMediaInfo()
+	if len(info.URL) == 0 {
+		return fmt.Errorf("Can't get stream url for %q", m.ID)
+	}
+	return download.HLS(ctx, info.URL, destPath,
+		download.WithGetter(p.getter),
+		download.WithProgress(progress),
+		download.WithDebug(p.debug),
+	)
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"html"
 	"io/ioutil"
-	"log"
 	"regexp"
 	"strconv"
 	"strings"
@@ -30,9 +29,7 @@ func (p *Gulli) getPlayer(ctx context.Context, mr *providers.MatchRequest, ID st
 	ctx, done := context.WithTimeout(ctx, p.deadline)
 	defer done()
 
-	if p.debug {
-		log.Printf("[%s] Player URL: %q", p.Name(), gullyPlayer+ID)
-	}
+	p.logger.Debugf("Player URL: %q", gullyPlayer+ID)
 	r, err := p.getter.Get(ctx, gullyPlayer+ID)
 	if err != nil {
 		return nil, err
@@ -108,6 +105,7 @@ func (p *Gulli) getPlayer(ctx context.Context, mr *providers.MatchRequest, ID st
 						},
 					}
 					info.Tag = []string{"Gulli"}
+					info.Studio = "Gulli"
 					info.Genre = []string{"dessins animés", "enfants"}
 				case "description":
 					info.Plot = html.UnescapeString(s)
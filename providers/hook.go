@@ -0,0 +1,55 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// hookInput is the JSON document written on the hook's standard input
+type hookInput struct {
+	File string `json:"file"`
+	CatalogEntry
+}
+
+// RunHook runs the command given with its arguments, after the media has been downloaded into file.
+// The command receives the media described with the catalog schema, and the file name,
+// as a JSON document on its standard input. Main fields are also given in environment variables:
+// ASPIRATV_FILE, ASPIRATV_ID, ASPIRATV_PROVIDER, ASPIRATV_SHOW, ASPIRATV_TITLE, ASPIRATV_SEASON,
+// ASPIRATV_EPISODE and ASPIRATV_AIRED.
+func RunHook(ctx context.Context, command []string, m *Media, file string) error {
+	if len(command) == 0 {
+		return nil
+	}
+	in := hookInput{
+		File:         file,
+		CatalogEntry: newCatalogEntry(m),
+	}
+	b, err := json.Marshal(in)
+	if err != nil {
+		return fmt.Errorf("Can't run hook for %q: %w", file, err)
+	}
+
+	cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+	cmd.Stdin = bytes.NewReader(b)
+	cmd.Env = append(os.Environ(),
+		"ASPIRATV_FILE="+file,
+		"ASPIRATV_ID="+in.ID,
+		"ASPIRATV_PROVIDER="+in.Provider,
+		"ASPIRATV_SHOW="+in.Show,
+		"ASPIRATV_TITLE="+in.Title,
+		"ASPIRATV_SEASON="+strconv.Itoa(in.Season),
+		"ASPIRATV_EPISODE="+strconv.Itoa(in.Episode),
+		"ASPIRATV_AIRED="+in.Aired,
+	)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("Hook %q failed for %q: %w\n%s", command[0], file, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
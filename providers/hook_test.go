@@ -0,0 +1,84 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/simulot/aspiratv/metadata/nfo"
+)
+
+// TestHookHelper isn't a real test, it's the hook command run by TestRunHook.
+// It writes the environment and the standard input into the file given as argument.
+func TestHookHelper(t *testing.T) {
+	if os.Getenv("ASPIRATV_HOOK_HELPER") != "1" {
+		return
+	}
+	args := os.Args
+	for len(args) > 0 && args[0] != "--" {
+		args = args[1:]
+	}
+	if len(args) < 2 {
+		os.Exit(2)
+	}
+	if args[1] == "fail" {
+		fmt.Println("hook failure")
+		os.Exit(1)
+	}
+	b, _ := ioutil.ReadAll(os.Stdin)
+	out := map[string]string{
+		"stdin":  string(b),
+		"file":   os.Getenv("ASPIRATV_FILE"),
+		"show":   os.Getenv("ASPIRATV_SHOW"),
+		"season": os.Getenv("ASPIRATV_SEASON"),
+	}
+	b, _ = json.Marshal(out)
+	ioutil.WriteFile(args[1], b, 0666)
+	os.Exit(0)
+}
+
+func TestRunHook(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aspiratv-hook-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.Setenv("ASPIRATV_HOOK_HELPER", "1")
+	defer os.Unsetenv("ASPIRATV_HOOK_HELPER")
+
+	m := &Media{
+		ID:       "123",
+		Match:    &MatchRequest{Provider: "francetv"},
+		Metadata: &nfo.EpisodeDetails{MediaInfo: nfo.MediaInfo{Showtitle: "Les Dalton", Title: "Joe", Season: 2}},
+	}
+	result := filepath.Join(dir, "result.json")
+	err = RunHook(context.TODO(), []string{os.Args[0], "-test.run=TestHookHelper", "--", result}, m, "/videos/les dalton.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]string{}
+	json.Unmarshal(b, &got)
+	if got["file"] != "/videos/les dalton.mp4" || got["show"] != "Les Dalton" || got["season"] != "2" {
+		t.Errorf("Unexpected environment %v", got)
+	}
+	in := hookInput{}
+	if err := json.Unmarshal([]byte(got["stdin"]), &in); err != nil {
+		t.Fatal(err)
+	}
+	if in.File != "/videos/les dalton.mp4" || in.Provider != "francetv" || in.Title != "Joe" {
+		t.Errorf("Unexpected standard input %+v", in)
+	}
+
+	err = RunHook(context.TODO(), []string{os.Args[0], "-test.run=TestHookHelper", "--", "fail"}, m, "x.mp4")
+	if err == nil {
+		t.Errorf("Expecting an error")
+	}
+}
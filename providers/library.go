@@ -0,0 +1,138 @@
+package providers
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/simulot/aspiratv/metadata/nfo"
+)
+
+// Episode identifiers written in file names by the default series template, see GetMediaPath:
+// Show - s01e02 - Title.mp4
+// Show - 2020-05-01 20h45 [123456] - Title.mp4
+// Show - 2020-05-01 - Title.mp4, as named before the time and the id were part of the aired tag
+var libraryEpisode = regexp.MustCompile(`^(.+?) - (s\d{2,}e\d{2,}|\d{4}-\d{2}-\d{2}(?: \d{2}h\d{2})?(?: \[[^\]]+\])?)(?: - | \(|$)`)
+
+var numberedEpisode = regexp.MustCompile(`^s(\d+)e(\d+)$`)
+
+// videoExtensions are the extensions of media files in the library
+var videoExtensions = map[string]bool{".mp4": true, ".mkv": true, ".ts": true, ".m4v": true}
+
+// ScanLibrary walks a library laid out like Plex and gives, for each show, the sorted identifiers
+// of the episodes already there. Identifiers are the season and episode numbers like s01e02,
+// or the aired tag like "2020-05-01 20h45 [123456]" for episodes without number.
+// Shows are named as in the file names. Movies and files named by a custom template are ignored.
+func ScanLibrary(libraryRoot string) (map[string][]string, error) {
+	found := map[string]map[string]bool{}
+	err := filepath.Walk(libraryRoot, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() || !videoExtensions[strings.ToLower(filepath.Ext(path))] {
+			return nil
+		}
+		show, id, ok := parseLibraryName(fi.Name())
+		if !ok {
+			return nil
+		}
+		if found[show] == nil {
+			found[show] = map[string]bool{}
+		}
+		found[show][id] = true
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Can't scan library %s: %w", libraryRoot, err)
+	}
+
+	library := make(map[string][]string, len(found))
+	for show, ids := range found {
+		l := make([]string, 0, len(ids))
+		for id := range ids {
+			l = append(l, id)
+		}
+		sort.Strings(l)
+		library[show] = l
+	}
+	return library, nil
+}
+
+func parseLibraryName(name string) (show, id string, ok bool) {
+	m := libraryEpisode.FindStringSubmatch(strings.TrimSuffix(name, filepath.Ext(name)))
+	if m == nil {
+		return "", "", false
+	}
+	return m[1], m[2], true
+}
+
+// EpisodeIdentifier gives the show and the identifier of the episode as given by ScanLibrary
+func EpisodeIdentifier(info *nfo.MediaInfo) (show, id string) {
+	show = nfo.FileNameCleaner(info.Showtitle)
+	if info.Episode > 0 {
+		return show, fmt.Sprintf("s%02de%02d", info.Season, info.Episode)
+	}
+	return show, info.AiredTag()
+}
+
+// NewerThanLibrary tells if the episode is more recent than the latest episode of its show present in the library.
+// Numbered episodes are compared by season and episode, other episodes by their aired date.
+// It's true for movies, for shows not yet in the library, and when the episode can't be compared.
+func NewerThanLibrary(m *Media, libraryRoot string) (bool, error) {
+	if m.ShowType != Series || m.Metadata == nil {
+		return true, nil
+	}
+	library, err := ScanLibrary(m.Metadata.GetSeriesPath(libraryRoot))
+	if errors.Is(err, os.ErrNotExist) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	info := m.Metadata.GetMediaInfo()
+	show, _ := EpisodeIdentifier(info)
+
+	latestSeason, latestEpisode, latestAired := 0, 0, ""
+	for _, id := range library[show] {
+		if n := numberedEpisode.FindStringSubmatch(id); n != nil {
+			season, _ := strconv.Atoi(n[1])
+			episode, _ := strconv.Atoi(n[2])
+			if season > latestSeason || season == latestSeason && episode > latestEpisode {
+				latestSeason, latestEpisode = season, episode
+			}
+			continue
+		}
+		if aired := airedPart(id); aired > latestAired {
+			latestAired = aired
+		}
+	}
+
+	if info.Episode > 0 && latestEpisode > 0 {
+		return info.Season > latestSeason || info.Season == latestSeason && info.Episode > latestEpisode, nil
+	}
+	if len(latestAired) > 0 && !info.Aired.Time().IsZero() {
+		aired := airedPart(info.AiredTag())
+		if len(latestAired) == len(dateTag) && len(aired) > len(dateTag) {
+			// Files named before the time was part of the aired tag are compared by date
+			aired = aired[:len(dateTag)]
+		}
+		return aired > latestAired, nil
+	}
+	return true, nil
+}
+
+// dateTag is the layout of the date of aired tags
+const dateTag = "2006-01-02"
+
+// airedPart removes the ID from an aired tag. Dates and times of aired tags are sorted as strings.
+func airedPart(tag string) string {
+	if i := strings.Index(tag, " ["); i >= 0 {
+		return tag[:i]
+	}
+	return tag
+}
@@ -0,0 +1,136 @@
+package providers
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/simulot/aspiratv/metadata/nfo"
+)
+
+func TestScanLibrary(t *testing.T) {
+	root, err := ioutil.TempDir("", "aspiratv-library-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	files := []string{
+		"Le Journal de 20h/Season 00/Le Journal de 20h - 2020-05-01 20h45 [123456] - Edition spéciale.mp4",
+		"Le Journal de 20h/Season 00/Le Journal de 20h - 2020-05-01 20h45 [123456] - Edition spéciale.nfo",
+		"Le Journal de 20h/Season 00/Le Journal de 20h - 2020-04-30.mp4",
+		"Astérix - Le Secret/Season 01/Astérix - Le Secret - s01e02 - Le Menhir.mkv",
+		"Astérix - Le Secret/Season 01/Astérix - Le Secret - s01e01 (France 3).mp4",
+		"Astérix - Le Secret/Season 01/Astérix - Le Secret - s01e01 (France 3).mp4.part",
+		"Cyrano de Bergerac (1990)/Cyrano de Bergerac (1990).mp4",
+	}
+	for _, f := range files {
+		p := filepath.Join(root, filepath.FromSlash(f))
+		if err := os.MkdirAll(filepath.Dir(p), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, nil, 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := ScanLibrary(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]string{
+		"Le Journal de 20h":   {"2020-04-30", "2020-05-01 20h45 [123456]"},
+		"Astérix - Le Secret": {"s01e01", "s01e02"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ScanLibrary() = %v, want %v", got, want)
+	}
+
+	if _, err := ScanLibrary(filepath.Join(root, "missing")); err == nil {
+		t.Error("ScanLibrary() expecting an error for a missing library")
+	}
+}
+
+func TestEpisodeIdentifier(t *testing.T) {
+	aired := time.Date(2020, 5, 1, 20, 45, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		info     nfo.MediaInfo
+		wantShow string
+		wantID   string
+	}{
+		{"numbered", nfo.MediaInfo{Showtitle: "Astérix", Season: 1, Episode: 2}, "Astérix", "s01e02"},
+		{"by date", nfo.MediaInfo{Showtitle: "Le Journal: 20h", Aired: nfo.Aired(aired), UniqueID: []nfo.ID{{ID: "123456"}}}, "Le Journal 20h", "2020-05-01 20h45 [123456]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			show, id := EpisodeIdentifier(&tt.info)
+			if show != tt.wantShow || id != tt.wantID {
+				t.Errorf("EpisodeIdentifier() = %q, %q, want %q, %q", show, id, tt.wantShow, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestNewerThanLibrary(t *testing.T) {
+	root, err := ioutil.TempDir("", "aspiratv-library-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	files := []string{
+		"Astérix/Season 01/Astérix - s01e09 - Le Menhir.mp4",
+		"Astérix/Season 02/Astérix - s02e03 - La Potion.mp4",
+		"Le Journal/Season 00/Le Journal - 2020-05-01 20h45 [123456].mp4",
+		"Le Journal/Season 00/Le Journal - 2020-04-30 [123455].mp4",
+		"Le Soir/Season 00/Le Soir - 2020-05-01 - Edition spéciale.mp4",
+	}
+	for _, f := range files {
+		p := filepath.Join(root, filepath.FromSlash(f))
+		if err := os.MkdirAll(filepath.Dir(p), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, nil, 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	episode := func(show string, season, episode int) *Media {
+		return &Media{ShowType: Series, Metadata: &nfo.EpisodeDetails{MediaInfo: nfo.MediaInfo{Showtitle: show, Season: season, Episode: episode}}}
+	}
+	aired := func(show string, t time.Time) *Media {
+		return &Media{ShowType: Series, Metadata: &nfo.EpisodeDetails{MediaInfo: nfo.MediaInfo{Showtitle: show, Aired: nfo.Aired(t)}}}
+	}
+	tests := []struct {
+		name string
+		m    *Media
+		want bool
+	}{
+		{"next episode", episode("Astérix", 2, 4), true},
+		{"next season", episode("Astérix", 3, 1), true},
+		{"latest episode", episode("Astérix", 2, 3), false},
+		{"older season", episode("Astérix", 1, 12), false},
+		{"not numbered", aired("Astérix", time.Date(2020, 5, 1, 0, 0, 0, 0, time.UTC)), true},
+		{"later the same day", aired("Le Journal", time.Date(2020, 5, 1, 22, 0, 0, 0, time.UTC)), true},
+		{"next day", aired("Le Journal", time.Date(2020, 5, 2, 0, 0, 0, 0, time.UTC)), true},
+		{"same broadcast", aired("Le Journal", time.Date(2020, 5, 1, 20, 45, 0, 0, time.UTC)), false},
+		{"earlier", aired("Le Journal", time.Date(2020, 5, 1, 13, 0, 0, 0, time.UTC)), false},
+		{"named by date only", aired("Le Soir", time.Date(2020, 5, 1, 20, 45, 0, 0, time.UTC)), false},
+		{"after a file named by date only", aired("Le Soir", time.Date(2020, 5, 2, 20, 45, 0, 0, time.UTC)), true},
+		{"unknown show", episode("Les Dalton", 1, 1), true},
+		{"movie", &Media{ShowType: Movie, Metadata: &nfo.Movie{MediaInfo: nfo.MediaInfo{Title: "Cyrano"}}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewerThanLibrary(tt.m, root)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("NewerThanLibrary() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package providers
+
+import (
+	"log"
+)
+
+// Logger is used by providers to report what they are doing
+type Logger interface {
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+}
+
+// StdLogger is a Logger writing into the standard log package.
+// Messages are prefixed with the name given at creation.
+type StdLogger struct {
+	name  string
+	debug bool
+}
+
+// NewStdLogger create a logger for the given name. Debug messages are written only when debug is true.
+func NewStdLogger(name string, debug bool) *StdLogger {
+	return &StdLogger{name: name, debug: debug}
+}
+
+// Debugf logs a debug message
+func (l *StdLogger) Debugf(format string, args ...interface{}) {
+	if l.debug {
+		l.printf("DEBUG", format, args...)
+	}
+}
+
+// Infof logs an information message
+func (l *StdLogger) Infof(format string, args ...interface{}) {
+	l.printf("INFO", format, args...)
+}
+
+// Warnf logs a warning
+func (l *StdLogger) Warnf(format string, args ...interface{}) {
+	l.printf("WARN", format, args...)
+}
+
+// Errorf logs an error
+func (l *StdLogger) Errorf(format string, args ...interface{}) {
+	l.printf("ERROR", format, args...)
+}
+
+func (l *StdLogger) printf(level string, format string, args ...interface{}) {
+	log.Printf("["+l.name+"] "+level+" "+format, args...)
+}
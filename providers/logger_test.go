@@ -0,0 +1,33 @@
+package providers
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestStdLogger(t *testing.T) {
+	b := bytes.NewBuffer(nil)
+	log.SetOutput(b)
+	defer log.SetOutput(os.Stderr)
+
+	l := Config{}.GetLogger("test")
+	l.Debugf("hidden %d", 1)
+	l.Errorf("Can't do it: %s", "error")
+	got := b.String()
+	if strings.Contains(got, "hidden") {
+		t.Errorf("Expecting debug message to be hidden, got %q", got)
+	}
+	if !strings.Contains(got, "[test] ERROR Can't do it: error") {
+		t.Errorf("Unexpected log %q", got)
+	}
+
+	b.Reset()
+	l = Config{Debug: true}.GetLogger("test")
+	l.Debugf("shown %d", 1)
+	if !strings.Contains(b.String(), "[test] DEBUG shown 1") {
+		t.Errorf("Unexpected log %q", b.String())
+	}
+}
@@ -1,5 +1,13 @@
 package providers
 
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
 // MatchRequest holds criterions for selecting show
 type MatchRequest struct {
 	// Fields for matching
@@ -9,61 +17,208 @@ type MatchRequest struct {
 	TitleID     string // Future use
 	Pitch       string
 	Provider    string
-	Playlist    string // Playlist search is implemented in providers.
-	MaxAgedDays int    // Retrive media younger than MaxAgedDays when not zero
+	Playlist    string       // Playlist search is implemented in providers.
+	MaxAgedDays int          // Retrive media younger than MaxAgedDays when not zero
+	Quality     string       // Preferred stream quality: best, worst, 720p... Provider's default when empty
+	TitleRegexp string       // Regular expression the show title must match when not empty
+	AiredAfter  time.Time    // Reject media aired before this date, when not zero
+	AiredBefore time.Time    // Reject media aired after this date, when not zero
+	Channels    []string     // Accepted channels when not empty, like france2 or "France 2"
+	Categories  []string     // Accepted categories when not empty, compared without case and accents
+	Container   string       // Container of downloaded files: mp4, mkv, or ts for HLS segments saved without ffmpeg. mp4 when empty
+	MinDuration TextDuration // Reject media shorter than this, when not zero
+	MaxDuration TextDuration // Reject media longer than this, when not zero
+	Extracts    bool         // Accept extracts and bonuses, only full episodes when false
+	Negate      bool         // Accept the show's media that don't match the other criteria, see IsMediaMatch
+	AudienceAge int          // Reject media advised for an audience older than this age, when not zero
+	BlockedIDs  []string     // IDs of media never downloaded, like episodes already watched elsewhere
+	Tags        []string     // Accepted tags when not empty, one of the media's tags must match regardless of case and accents
+
+	EmbedSubtitles bool // Subtitles are muxed into the video file instead of .srt files, when the container can hold them
+
+	AudioLanguage    string // Language of the kept audio track, like fr. The stream's primary audio when empty
+	AudioDescription bool   // Keep the audio description along the main audio, when available
 
 	// Destination name when found
 	Destination   string
-	RetentionDays int // Media retention time, when not zero the system will delete old files
+	RetentionDays int               // Media retention time, when not zero the system will delete old files
+	TitleAliases  map[string]string // Show titles replaced by a canonical title, compared without case and accents
+
+	titleRe *regexp.Regexp
+	blocked map[string]bool
+	filters Filters // Chain of filters built by Compile
+}
+
+// Compile prepares the regular expressions and the filters of the request.
+// It must be called before using the MatchRequest, and again when the request is changed.
+func (m *MatchRequest) Compile() error {
+	m.titleRe = nil
+	m.blocked = nil
+	m.filters = nil
+	if len(m.BlockedIDs) > 0 {
+		m.blocked = idSet(m.BlockedIDs)
+	}
+	if len(m.TitleRegexp) > 0 {
+		re, err := regexp.Compile(m.TitleRegexp)
+		if err != nil {
+			return fmt.Errorf("Can't compile TitleRegexp %q: %w", m.TitleRegexp, err)
+		}
+		m.titleRe = re
+	}
+	m.filters = m.buildFilters()
+	return nil
+}
+
+var (
+	qualityRegexp       = regexp.MustCompile(`^(best|worst|\d+p)$`)
+	audioLanguageRegexp = regexp.MustCompile(`^[a-zA-Z]{2,3}$`)
+)
+
+// Validate checks the consistency of the request. knownProviders gives
+// the list of valid provider names.
+func (m *MatchRequest) Validate(knownProviders []string) error {
+	if len(m.Provider) == 0 {
+		return errors.New("Provider is missing")
+	}
+	known := false
+	for _, p := range knownProviders {
+		if p == m.Provider {
+			known = true
+			break
+		}
+	}
+	if !known {
+		return fmt.Errorf("Unknown provider %q, possible values are %s", m.Provider, strings.Join(knownProviders, ", "))
+	}
+	if len(m.Show) == 0 && len(m.Title) == 0 && len(m.Pitch) == 0 && len(m.Playlist) == 0 && len(m.TitleRegexp) == 0 {
+		return errors.New("At least one of Show, Title, Pitch, Playlist or TitleRegexp must be given")
+	}
+	if m.MaxAgedDays < 0 {
+		return fmt.Errorf("MaxAgedDays can't be negative: %d", m.MaxAgedDays)
+	}
+	if m.RetentionDays < 0 {
+		return fmt.Errorf("RetentionDays can't be negative: %d", m.RetentionDays)
+	}
+	if !m.AiredAfter.IsZero() && !m.AiredBefore.IsZero() && m.AiredAfter.After(m.AiredBefore) {
+		return fmt.Errorf("AiredAfter (%s) is after AiredBefore (%s)", m.AiredAfter.Format("2006-01-02"), m.AiredBefore.Format("2006-01-02"))
+	}
+	if len(m.Quality) > 0 && !qualityRegexp.MatchString(m.Quality) {
+		return fmt.Errorf("Invalid quality %q, possible values are best, worst or a resolution like 720p", m.Quality)
+	}
+	if c := strings.ToLower(m.Container); len(c) > 0 && c != "mp4" && c != "mkv" && c != "ts" {
+		return fmt.Errorf("Invalid container %q, possible values are mp4, mkv or ts", m.Container)
+	}
+	if m.MinDuration < 0 || m.MaxDuration < 0 {
+		return fmt.Errorf("MinDuration (%s) and MaxDuration (%s) can't be negative", m.MinDuration.Duration(), m.MaxDuration.Duration())
+	}
+	if len(m.AudioLanguage) > 0 && !audioLanguageRegexp.MatchString(m.AudioLanguage) {
+		return fmt.Errorf("Invalid audio language %q, expecting a language code like fr", m.AudioLanguage)
+	}
+	if m.AudienceAge < 0 {
+		return fmt.Errorf("AudienceAge (%d) can't be negative", m.AudienceAge)
+	}
+	if m.MaxDuration > 0 && m.MinDuration > m.MaxDuration {
+		return fmt.Errorf("MinDuration (%s) is greater than MaxDuration (%s)", m.MinDuration.Duration(), m.MaxDuration.Duration())
+	}
+	return m.Compile()
+}
+
+// IsMediaMatch is the generic implementation of media matcher. It checks
+// the criterions that aren't handled by providers against the matched request.
+// All criteria set in the request must match:
+// The show title is tested against TitleRegexp. Movies are tested with their title.
+// The aired date must be within AiredAfter and AiredBefore.
+// The channel must be one of Channels, one of the genres must be one of Categories, and one of the tags must be one of Tags.
+// The media title must contain Title, and its plot must contain Pitch, regardless of case and accents.
+// The duration must be within MinDuration and MaxDuration, media of unknown duration are accepted.
+// When Negate is set, the media is accepted when these criteria don't all match.
+// Extracts and bonuses are rejected, unless Extracts is set.
+// Media advised for an audience older than AudienceAge, and media of BlockedIDs, are rejected, even when Negate is set.
+func IsMediaMatch(m *Media) bool {
+	if m.Match == nil || m.Metadata == nil {
+		return true
+	}
+	if kind := KindFilter(m.Match.Extracts); !kind.Match(m) {
+		logRejected(m, m.Match, kind)
+		return false
+	}
+	if m.Match.AudienceAge > 0 {
+		if age := AgeFilter(m.Match.AudienceAge); !age.Match(m) {
+			logRejected(m, m.Match, age)
+			return false
+		}
+	}
+	if blocked := m.Match.blockedFilter(); blocked != nil && !blocked.Match(m) {
+		logRejected(m, m.Match, blocked)
+		return false
+	}
+	f := m.Match.Filters().Rejecting(m)
+	if m.Match.Negate {
+		if f == nil {
+			logRejected(m, m.Match, "all criteria of the negated request")
+			return false
+		}
+		return true
+	}
+	if f != nil {
+		logRejected(m, m.Match, f)
+		return false
+	}
+	return true
+}
+
+// blockedFilter gives the filter of BlockedIDs, nil when there is none
+func (m *MatchRequest) blockedFilter() Filter {
+	if len(m.BlockedIDs) == 0 {
+		return nil
+	}
+	if m.blocked == nil {
+		// The request isn't compiled
+		return BlockedFilter(m.BlockedIDs...)
+	}
+	return blockedFilter(m.blocked)
 }
 
-// IsShowMatch is the generic implementation of show matcher.
-// Criterions are tested in following order:
-// - Provider
-// - Show
-// - Title
-// - Pitch
-// When there is a match, it adds  MatchRequest.Destination into Show record.
-// Criteria is ignored when it is empty in the MatchRequest
-// When the list of MatchRequest is nil or empty, all show will match.
-//
-// Note: Playlist match isn't handled generically, it must be implemented in the provider's implementation
-// Note: side effect show's destination is changed
-//
+var matchLogger Logger // Receives the reasons of rejections when not nil
 
-// func IsShowMatch(mm []*MatchRequest, s *Show) bool {
-// 	if mm == nil || len(mm) == 0 {
-// 		return true
-// 	}
+// SetMatchLogger sets the logger receiving, at debug level, the criterion rejecting each media
+// in IsMediaMatch and MatchAny. Nil, the default, keeps them quiet.
+// It must be called before listing media.
+func SetMatchLogger(l Logger) {
+	matchLogger = l
+}
 
-// 	for _, m := range mm {
-// 		if len(m.Playlist) > 0 {
-// 			// Skip Playlist that must have a special treatment
-// 			continue
-// 		}
+func logRejected(m *Media, mr *MatchRequest, reason interface{}) {
+	if matchLogger == nil {
+		return
+	}
+	info := m.Metadata.GetMediaInfo()
+	matchLogger.Debugf("Media %q (%s) of %q rejected by request %q: %v", info.Title, m.ID, info.Showtitle, mr.Show, reason)
+}
 
-// 		if len(m.Provider) > 0 {
-// 			if m.Provider != strings.ToLower(s.Provider) {
-// 				continue
-// 			}
-// 		}
-// 		if len(m.Show) > 0 {
-// 			if f := strings.Contains(strings.ToLower(s.Show), m.Show); !f {
-// 				continue
-// 			}
-// 		}
-// 		if len(m.Title) > 0 {
-// 			if f := strings.Contains(strings.ToLower(s.Title), m.Title); !f {
-// 				continue
-// 			}
-// 		}
-// 		if len(m.Pitch) > 0 {
-// 			if f := strings.Contains(strings.ToLower(s.Pitch), m.Pitch); !f {
-// 				continue
-// 			}
-// 		}
-// 		s.Destination = m.Destination
-// 		return true
-// 	}
-// 	return false
-// }
+// MatchAny applies the requests of a watch list to the media: it's accepted when it matches its own request,
+// see IsMediaMatch, and isn't excluded by a negated request of the same provider whose Show is in the show title.
+// Requests are OR'd: a media rejected by its own request can still be found with an other one.
+func MatchAny(m *Media, mm []*MatchRequest) bool {
+	if !IsMediaMatch(m) {
+		return false
+	}
+	if m.Match == nil || m.Metadata == nil {
+		return true
+	}
+	info := m.Metadata.GetMediaInfo()
+	title := info.Showtitle
+	if len(title) == 0 {
+		title = info.Title
+	}
+	for _, r := range mm {
+		if !r.Negate || r == m.Match || r.Provider != m.Match.Provider || !ContainsForMatch(title, r.Show) {
+			continue
+		}
+		if r.Filters().All(m) {
+			logRejected(m, r, "negated request")
+			return false
+		}
+	}
+	return true
+}
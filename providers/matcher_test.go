@@ -1,85 +1,346 @@
 package providers
 
-// import "testing"
+import (
+	"encoding/json"
+	"testing"
+	"time"
 
-// func TestIsShowMatch(t *testing.T) {
-// 	ml := []*MatchRequest{
-// 		{
-// 			Show:        "searched1",
-// 			Provider:    "p1",
-// 			Destination: "dest1",
-// 		},
-// 		{
-// 			Show:        "searched2",
-// 			Provider:    "p2",
-// 			Destination: "dest1",
-// 		},
-// 		{
-// 			Show:        "searched3",
-// 			Provider:    "p1",
-// 			Destination: "dest2",
-// 		},
-// 	}
-// 	type args struct {
-// 		mm []*MatchRequest
-// 		s  *Show
-// 	}
-// 	tests := []struct {
-// 		name string
-// 		args args
-// 		want bool
-// 	}{
-// 		{
-// 			"Show not found",
-// 			args{
-// 				ml,
-// 				&Show{
-// 					Show:     "I'm not here",
-// 					Provider: "p1",
-// 				},
-// 			},
-// 			false,
-// 		},
-// 		{
-// 			"Wrong provider",
-// 			args{
-// 				ml,
-// 				&Show{
-// 					Show:     "Searched1",
-// 					Provider: "p2",
-// 				},
-// 			},
-// 			false,
-// 		},
-// 		{
-// 			"found Searched1",
-// 			args{
-// 				ml,
-// 				&Show{
-// 					Show:     "Searched1",
-// 					Provider: "p1",
-// 				},
-// 			},
-// 			true,
-// 		},
-// 		{
-// 			"found Searched3",
-// 			args{
-// 				ml,
-// 				&Show{
-// 					Show:     "Searched3",
-// 					Provider: "p1",
-// 				},
-// 			},
-// 			true,
-// 		},
-// 	}
+	"github.com/simulot/aspiratv/metadata/nfo"
+)
 
-// 	for _, tt := range tests {
-// 		t.Run(tt.name, func(t *testing.T) {
-// 			if got := IsShowMatch(tt.args.mm, tt.args.s); got != tt.want {
-// 				t.Errorf("IsShowMatch() = %v, want %v", got, tt.want)
-// 			}
-// 		})
-// 	}
-// }
+func TestMatchRequestCompile(t *testing.T) {
+	tests := []struct {
+		name    string
+		re      string
+		wantErr bool
+	}{
+		{"empty", "", false},
+		{"valid", "^Le Journal", false},
+		{"invalid", "^Le (Journal", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &MatchRequest{TitleRegexp: tt.re}
+			if err := m.Compile(); (err != nil) != tt.wantErr {
+				t.Errorf("Compile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestIsMediaMatch(t *testing.T) {
+	episode := func(show, title string) MetaDataHandler {
+		return &nfo.EpisodeDetails{MediaInfo: nfo.MediaInfo{Showtitle: show, Title: title}}
+	}
+	movie := func(title string) MetaDataHandler {
+		return &nfo.Movie{MediaInfo: nfo.MediaInfo{Title: title}}
+	}
+	tests := []struct {
+		name string
+		mr   *MatchRequest
+		meta MetaDataHandler
+		want bool
+	}{
+		{"no criterion", &MatchRequest{}, episode("Le Journal de 20h", "Edition du 1er mai"), true},
+		{"show match", &MatchRequest{TitleRegexp: "^Le Journal"}, episode("Le Journal de 20h", "Edition du 1er mai"), true},
+		{"show doesn't match", &MatchRequest{TitleRegexp: "^Le Journal"}, episode("Journal de 13h", "Le Journal"), false},
+		{"movie match", &MatchRequest{TitleRegexp: "(?i)cyrano"}, movie("Cyrano de Bergerac"), true},
+		{"invalid regexp", &MatchRequest{TitleRegexp: "(("}, movie("Cyrano de Bergerac"), false},
+		{"title without accents", &MatchRequest{Title: "edition speciale"}, episode("Télématin", "Édition spéciale"), true},
+		{"title doesn't match", &MatchRequest{Title: "edition speciale"}, episode("Télématin", "Edition du 1er mai"), false},
+		{"pitch match", &MatchRequest{Pitch: "ÉLYSÉE"}, &nfo.EpisodeDetails{MediaInfo: nfo.MediaInfo{Title: "Noël", Plot: "Le réveillon à l'Elysée"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Media{Match: tt.mr, Metadata: tt.meta}
+			if got := IsMediaMatch(m); got != tt.want {
+				t.Errorf("IsMediaMatch() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsMediaMatchAired(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2020, 5, d, 0, 0, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		name  string
+		mr    *MatchRequest
+		aired time.Time
+		want  bool
+	}{
+		{"unbounded", &MatchRequest{}, day(10), true},
+		{"after ok", &MatchRequest{AiredAfter: day(5)}, day(10), true},
+		{"after ko", &MatchRequest{AiredAfter: day(15)}, day(10), false},
+		{"before ok", &MatchRequest{AiredBefore: day(15)}, day(10), true},
+		{"before ko", &MatchRequest{AiredBefore: day(5)}, day(10), false},
+		{"within range", &MatchRequest{AiredAfter: day(5), AiredBefore: day(15)}, day(10), true},
+		{"same day as bound", &MatchRequest{AiredAfter: day(10), AiredBefore: day(10)}, day(10), true},
+		{"out of range", &MatchRequest{AiredAfter: day(1), AiredBefore: day(5)}, day(10), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Media{
+				Match:    tt.mr,
+				Metadata: &nfo.EpisodeDetails{MediaInfo: nfo.MediaInfo{Aired: nfo.Aired(tt.aired)}},
+			}
+			if got := IsMediaMatch(m); got != tt.want {
+				t.Errorf("IsMediaMatch() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsMediaMatchKind(t *testing.T) {
+	tests := []struct {
+		name string
+		mr   *MatchRequest
+		kind string
+		want bool
+	}{
+		{"unknown kind", &MatchRequest{}, "", true},
+		{"episode", &MatchRequest{}, KindEpisode, true},
+		{"extract", &MatchRequest{}, KindExtract, false},
+		{"bonus", &MatchRequest{}, KindBonus, false},
+		{"extract accepted", &MatchRequest{Extracts: true}, KindExtract, true},
+		{"episode with extracts", &MatchRequest{Extracts: true}, KindEpisode, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Media{
+				Kind:     tt.kind,
+				Match:    tt.mr,
+				Metadata: &nfo.EpisodeDetails{},
+			}
+			if got := IsMediaMatch(m); got != tt.want {
+				t.Errorf("IsMediaMatch() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsMediaMatchAge(t *testing.T) {
+	tests := []struct {
+		name   string
+		mr     *MatchRequest
+		minAge int
+		want   bool
+	}{
+		{"no filtering", &MatchRequest{}, 16, true},
+		{"all audiences", &MatchRequest{AudienceAge: 10}, 0, true},
+		{"same age", &MatchRequest{AudienceAge: 12}, 12, true},
+		{"too young", &MatchRequest{AudienceAge: 12}, 16, false},
+		{"negated request", &MatchRequest{AudienceAge: 12, Negate: true, Title: "other"}, 16, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Media{
+				MinAge:   tt.minAge,
+				Match:    tt.mr,
+				Metadata: &nfo.EpisodeDetails{},
+			}
+			if got := IsMediaMatch(m); got != tt.want {
+				t.Errorf("IsMediaMatch() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsMediaMatchBlocked(t *testing.T) {
+	compiled := &MatchRequest{BlockedIDs: []string{"123", " 456 "}}
+	if err := compiled.Compile(); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		mr   *MatchRequest
+		id   string
+		want bool
+	}{
+		{"no blocked ids", &MatchRequest{}, "123", true},
+		{"blocked", &MatchRequest{BlockedIDs: []string{"123"}}, "123", false},
+		{"other id", &MatchRequest{BlockedIDs: []string{"123"}}, "1234", true},
+		{"compiled", compiled, "456", false},
+		{"negated request", &MatchRequest{BlockedIDs: []string{"123"}, Negate: true, Title: "other"}, "123", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Media{
+				ID:       tt.id,
+				Match:    tt.mr,
+				Metadata: &nfo.EpisodeDetails{},
+			}
+			if got := IsMediaMatch(m); got != tt.want {
+				t.Errorf("IsMediaMatch() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsMediaMatchDuration(t *testing.T) {
+	min := func(m int) TextDuration { return TextDuration(time.Duration(m) * time.Minute) }
+	tests := []struct {
+		name     string
+		mr       *MatchRequest
+		duration time.Duration
+		want     bool
+	}{
+		{"unbounded", &MatchRequest{}, time.Minute, true},
+		{"teaser", &MatchRequest{MinDuration: min(2)}, time.Minute, false},
+		{"episode", &MatchRequest{MinDuration: min(2), MaxDuration: min(180)}, 52 * time.Minute, true},
+		{"too long", &MatchRequest{MaxDuration: min(180)}, 4 * time.Hour, false},
+		{"unknown duration", &MatchRequest{MinDuration: min(2), MaxDuration: min(180)}, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Media{
+				Match:    tt.mr,
+				Metadata: &nfo.EpisodeDetails{MediaInfo: nfo.MediaInfo{Duration: tt.duration}},
+			}
+			if got := IsMediaMatch(m); got != tt.want {
+				t.Errorf("IsMediaMatch() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTextDurationJSON(t *testing.T) {
+	var mr MatchRequest
+	err := json.Unmarshal([]byte(`{"MinDuration":"2m","MaxDuration":"3h"}`), &mr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mr.MinDuration.Duration() != 2*time.Minute || mr.MaxDuration.Duration() != 3*time.Hour {
+		t.Errorf("Unexpected durations %s and %s", mr.MinDuration.Duration(), mr.MaxDuration.Duration())
+	}
+	if err := json.Unmarshal([]byte(`{"MinDuration":"2 minutes"}`), &mr); err == nil {
+		t.Errorf("Expecting an error")
+	}
+}
+
+func TestMatchRequestValidate(t *testing.T) {
+	known := []string{"artetv", "francetv"}
+	day := func(d int) time.Time {
+		return time.Date(2020, 5, d, 0, 0, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		name    string
+		mr      MatchRequest
+		wantErr bool
+	}{
+		{"valid", MatchRequest{Provider: "francetv", Show: "doctor who"}, false},
+		{"valid regexp", MatchRequest{Provider: "francetv", TitleRegexp: "^Le Journal"}, false},
+		{"missing provider", MatchRequest{Show: "doctor who"}, true},
+		{"unknown provider", MatchRequest{Provider: "francetv2", Show: "doctor who"}, true},
+		{"matches everything", MatchRequest{Provider: "francetv"}, true},
+		{"invalid regexp", MatchRequest{Provider: "francetv", TitleRegexp: "(("}, true},
+		{"negative age", MatchRequest{Provider: "francetv", Show: "doctor who", MaxAgedDays: -1}, true},
+		{"inverted dates", MatchRequest{Provider: "francetv", Show: "doctor who", AiredAfter: day(10), AiredBefore: day(1)}, true},
+		{"valid dates", MatchRequest{Provider: "francetv", Show: "doctor who", AiredAfter: day(1), AiredBefore: day(10)}, false},
+		{"valid quality", MatchRequest{Provider: "francetv", Show: "doctor who", Quality: "720p"}, false},
+		{"invalid quality", MatchRequest{Provider: "francetv", Show: "doctor who", Quality: "HD"}, true},
+		{"raw container", MatchRequest{Provider: "francetv", Show: "doctor who", Container: "TS"}, false},
+		{"invalid container", MatchRequest{Provider: "francetv", Show: "doctor who", Container: "avi"}, true},
+		{"valid durations", MatchRequest{Provider: "francetv", Show: "doctor who", MinDuration: TextDuration(2 * time.Minute), MaxDuration: TextDuration(2 * time.Hour)}, false},
+		{"inverted durations", MatchRequest{Provider: "francetv", Show: "doctor who", MinDuration: TextDuration(2 * time.Hour), MaxDuration: TextDuration(2 * time.Minute)}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.mr.Validate(known); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestIsMediaMatchChannels(t *testing.T) {
+	tests := []struct {
+		name     string
+		channels []string
+		studio   string
+		want     bool
+	}{
+		{"no filter", nil, "France 2", true},
+		{"code", []string{"france2"}, "France 2", true},
+		{"friendly name", []string{"France 3"}, "france-3", true},
+		{"alias", []string{"F5", "f4"}, "France 4", true},
+		{"accent", []string{"France Ô"}, "franceo", true},
+		{"franceinfo label", []string{"France Info"}, "franceinfo:", true},
+		{"franceinfo alias", []string{"fi"}, "franceinfo", true},
+		{"other channel", []string{"france2"}, "France 5", false},
+		{"unknown channel", []string{"france2"}, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Media{
+				Match:    &MatchRequest{Channels: tt.channels},
+				Metadata: &nfo.EpisodeDetails{MediaInfo: nfo.MediaInfo{Studio: tt.studio}},
+			}
+			if got := IsMediaMatch(m); got != tt.want {
+				t.Errorf("IsMediaMatch() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsMediaMatchCombined(t *testing.T) {
+	// All criteria of a request must match
+	and := &MatchRequest{Title: "dalton", Channels: []string{"france2"}}
+	negate := &MatchRequest{Title: "dalton", Channels: []string{"france2"}, Negate: true}
+	tests := []struct {
+		name   string
+		match  *MatchRequest
+		title  string
+		studio string
+		want   bool
+	}{
+		{"both", and, "Les Dalton", "France 2", true},
+		{"title only", and, "Les Dalton", "France 3", false},
+		{"channel only", and, "Lucky Luke", "France 2", false},
+		{"negate both", negate, "Les Dalton", "France 2", false},
+		{"negate title only", negate, "Les Dalton", "France 3", true},
+		{"negate none", negate, "Lucky Luke", "France 3", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Media{
+				Match:    tt.match,
+				Metadata: &nfo.EpisodeDetails{MediaInfo: nfo.MediaInfo{Title: tt.title, Studio: tt.studio}},
+			}
+			if got := IsMediaMatch(m); got != tt.want {
+				t.Errorf("IsMediaMatch() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchAny(t *testing.T) {
+	all := &MatchRequest{Provider: "francetv", Show: "dalton"}
+	exceptF2 := &MatchRequest{Provider: "francetv", Show: "dalton", Channels: []string{"france2"}, Negate: true}
+	otherProvider := &MatchRequest{Provider: "artetv", Show: "dalton", Negate: true}
+	mm := []*MatchRequest{all, exceptF2, otherProvider}
+
+	tests := []struct {
+		name   string
+		match  *MatchRequest
+		studio string
+		want   bool
+	}{
+		{"excluded channel", all, "France 2", false},
+		{"other channel", all, "France 3", true},
+		{"found by the negated request", exceptF2, "France 3", true},
+		{"rejected by the negated request", exceptF2, "France 2", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Media{
+				Match:    tt.match,
+				Metadata: &nfo.EpisodeDetails{MediaInfo: nfo.MediaInfo{Showtitle: "Les Dalton", Title: "Joe", Studio: tt.studio}},
+			}
+			if got := MatchAny(m, mm); got != tt.want {
+				t.Errorf("MatchAny() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package providers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// LoadMatchRequests reads a JSON array of match requests, like the WatchList of the configuration file.
+// Unknown fields are rejected, and each request is checked by Validate against the registered providers.
+// Errors give the index and the line of the offending entry.
+func LoadMatchRequests(r io.Reader) ([]*MatchRequest, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("Can't read match requests: %w", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	t, err := dec.Token()
+	if err != nil {
+		return nil, fmt.Errorf("Can't decode match requests at line %d: %w", lineAt(data, dec.InputOffset()), err)
+	}
+	if d, ok := t.(json.Delim); !ok || d != '[' {
+		return nil, fmt.Errorf("Can't decode match requests: expecting an array, got %v", t)
+	}
+
+	names := registeredNames()
+	mm := []*MatchRequest{}
+	for i := 0; dec.More(); i++ {
+		line := lineAt(data, dec.InputOffset())
+		raw := json.RawMessage{}
+		if err := dec.Decode(&raw); err != nil {
+			return nil, fmt.Errorf("Can't decode match request #%d at line %d: %w", i, line, err)
+		}
+		m := &MatchRequest{}
+		d := json.NewDecoder(bytes.NewReader(raw))
+		d.DisallowUnknownFields()
+		if err := d.Decode(m); err != nil {
+			return nil, fmt.Errorf("Can't decode match request #%d at line %d: %w", i, line, err)
+		}
+		if err := m.Validate(names); err != nil {
+			return nil, fmt.Errorf("Invalid match request #%d %q at line %d: %w", i, m.Show, line, err)
+		}
+		mm = append(mm, m)
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, fmt.Errorf("Can't decode match requests at line %d: %w", lineAt(data, dec.InputOffset()), err)
+	}
+	return mm, nil
+}
+
+// LoadBlockedIDs reads media IDs, one per line, see MatchRequest.BlockedIDs.
+// Blank lines and lines starting with # are ignored.
+func LoadBlockedIDs(r io.Reader) ([]string, error) {
+	ids := []string{}
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		l := strings.TrimSpace(s.Text())
+		if len(l) == 0 || strings.HasPrefix(l, "#") {
+			continue
+		}
+		ids = append(ids, l)
+	}
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("Can't read blocked IDs: %w", err)
+	}
+	return ids, nil
+}
+
+// SaveMatchRequests writes the match requests as an indented JSON array, read back by LoadMatchRequests
+func SaveMatchRequests(w io.Writer, mm []*MatchRequest) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(mm); err != nil {
+		return fmt.Errorf("Can't encode match requests: %w", err)
+	}
+	return nil
+}
+
+// lineAt gives the line of the first significant character after offset
+func lineAt(data []byte, offset int64) int {
+	o := int(offset)
+	for o < len(data) && bytes.IndexByte([]byte(" \t\r\n,"), data[o]) >= 0 {
+		o++
+	}
+	if o > len(data) {
+		o = len(data)
+	}
+	return bytes.Count(data[:o], []byte("\n")) + 1
+}
+
+func registeredNames() []string {
+	l := []string{}
+	for _, p := range List() {
+		l = append(l, p.Name())
+	}
+	return l
+}
@@ -0,0 +1,95 @@
+package providers
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadMatchRequests(t *testing.T) {
+	Register(fakeProvider{name: "requests-fake"})
+	tests := []struct {
+		name    string
+		json    string
+		want    int
+		wantErr string
+	}{
+		{"empty", `[]`, 0, ""},
+		{"valid", `[
+  {"Show": "le journal", "Provider": "requests-fake", "Destination": "news"},
+  {"Title": "cyrano", "Provider": "requests-fake", "MaxDuration": "2h", "Channels": ["france2"]}
+]`, 2, ""},
+		{"not an array", `{"Show": "le journal"}`, 0, "expecting an array"},
+		{"unknown field", `[
+  {"Show": "le journal", "Provider": "requests-fake"},
+  {"Show": "cyrano", "Provder": "requests-fake"}
+]`, 0, "#1 at line 3"},
+		{"wrong type", `[
+  {"Show": "le journal", "Provider": "requests-fake", "MaxAgedDays": "7"}
+]`, 0, "#0 at line 2"},
+		{"invalid request", `[
+  {"Show": "le journal", "Provider": "requests-fake"},
+
+  {"Show": "cyrano", "Provider": "requests-fake", "Quality": "superb"}
+]`, 0, `#1 "cyrano" at line 4`},
+		{"unknown provider", `[{"Show": "le journal", "Provider": "unknown"}]`, 0, "#0"},
+		{"syntax error", `[
+  {"Show": "le journal", "Provider": "requests-fake"},
+  {"Show": "cyrano" "Provider": "requests-fake"}
+]`, 0, "#1 at line 3"},
+		{"missing bracket", `[{"Show": "le journal", "Provider": "requests-fake"}`, 0, "end of JSON input"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LoadMatchRequests(strings.NewReader(tt.json))
+			if len(tt.wantErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("LoadMatchRequests() error = %v, want an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadMatchRequests() error = %v", err)
+			}
+			if len(got) != tt.want {
+				t.Errorf("LoadMatchRequests() got %d requests, want %d", len(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestSaveMatchRequests(t *testing.T) {
+	Register(fakeProvider{name: "requests-fake"})
+	mm := []*MatchRequest{
+		{Show: "le journal", Provider: "requests-fake", Destination: "news", Channels: []string{"france2"}, MaxDuration: TextDuration(time.Hour)},
+		{Title: "cyrano", Provider: "requests-fake", TitleRegexp: "(?i)^cyrano", AiredAfter: time.Date(2020, 5, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	b := bytes.Buffer{}
+	if err := SaveMatchRequests(&b, mm); err != nil {
+		t.Fatal(err)
+	}
+	got, err := LoadMatchRequests(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, m := range mm {
+		m.Compile()
+		// Filters hold functions, that can't be compared
+		m.filters, got[i].filters = nil, nil
+	}
+	if !reflect.DeepEqual(got, mm) {
+		t.Errorf("LoadMatchRequests() = %+v, want %+v", got, mm)
+	}
+}
+
+func TestLoadBlockedIDs(t *testing.T) {
+	ids, err := LoadBlockedIDs(strings.NewReader("# Already watched\n123\n\n  456  \n#789\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"123", "456"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("LoadBlockedIDs() = %v, want %v", ids, want)
+	}
+}
@@ -1,6 +1,15 @@
 package providers
 
-import "github.com/simulot/aspiratv/metadata/nfo"
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/simulot/aspiratv/metadata/nfo"
+)
 
 // MetaDataHandler represents a struct for managing media's metadata
 type MetaDataHandler interface {
@@ -15,6 +24,15 @@ type MetaDataHandler interface {
 	WriteNFO(destination string) error
 }
 
+// ErrDRMProtected is returned when the media's stream is protected by a DRM
+var ErrDRMProtected = errors.New("Media is DRM protected")
+
+// ErrShowExpired is returned when the media is still listed in the catalog, but can't be played anymore
+var ErrShowExpired = errors.New("Media is expired or unavailable")
+
+// ErrLiveStream is returned when the media is a live stream, that never ends and can't be downloaded
+var ErrLiveStream = errors.New("Media is a live stream")
+
 // ShowType says if the media is a movie (one time broadcast), TVShows (recurring show) or a series (with seasons and episodes)
 type ShowType int
 
@@ -25,14 +43,131 @@ const (
 
 )
 
+// Media kinds
+const (
+	KindEpisode = "episode" // Full episode or movie
+	KindExtract = "extract" // Extract or summary of an episode
+	KindBonus   = "bonus"   // Bonus content, like making of or promos
+)
+
+// Stream types
+const (
+	StreamHLS  = "hls"  // HLS playlist
+	StreamDASH = "dash" // MPEG-DASH manifest
+	StreamMP4  = "mp4"  // Progressive MP4 file, downloaded as a whole
+)
+
 // Media represents a media to be handled.
 type Media struct {
 	ID       string          // Show ID
 	ShowType ShowType        // Movie or Series?
+	Kind     string          // KindEpisode, KindExtract or KindBonus. Empty is a full episode
 	Metadata MetaDataHandler // Carry metadata scrapped online
 	Match    *MatchRequest   // Matched request
+	ShowURL  string          // Web page of the show, when known
+
+	StreamType    string          // StreamHLS, StreamDASH or StreamMP4, HLS when empty
+	Subtitles     []SubtitleTrack // Available subtitles
+	AudioVersions []AudioVersion  // Audio tracks having their own playlist, when known. See SelectAudio
+	DRM           bool            // True when the stream is protected and can't be downloaded
+	IsLive        bool            // True when the stream is a live or continuous stream, that can't be downloaded
+	MinAge        int             // Minimum age advised for the media, 0 when it's for all audiences or unknown
+	Chapters      []Chapter       // Chapter markers, like the opening and the end credits, in start order
+	Expires       time.Time       // End of the media availability, zero when unknown
+	Tags          []string        // Genre and keyword tags of the show, when known
+}
+
+// Chapter is a part of the media, given by its offsets from the beginning of the stream
+type Chapter struct {
+	Title string
+	Start time.Duration
+	End   time.Duration
+}
+
+// IsHLS tells if the media is given by an HLS playlist
+func (m *Media) IsHLS() bool {
+	return m.StreamType == StreamHLS || len(m.StreamType) == 0
 }
 
 func (m *Media) SetMetaData(info MetaDataHandler) {
 	m.Metadata = info
 }
+
+// ApplyMatch copies the output preferences of the matched request, like the container, into the media's metadata.
+// The show title is replaced by its canonical title given by the request's TitleAliases, so all episodes
+// of a program listed under varying titles go into the same folder.
+func (m *Media) ApplyMatch() {
+	if m.Match == nil || m.Metadata == nil {
+		return
+	}
+	info := m.Metadata.GetMediaInfo()
+	info.Container = strings.ToLower(m.Match.Container)
+	if canonical, ok := CanonicalTitle(m.Match.TitleAliases, info.Showtitle); ok {
+		info.Showtitle = canonical
+		if info.TVShow != nil {
+			info.TVShow.Title = canonical
+		}
+	}
+}
+
+// CanonicalTitle gives the canonical title of the title, found into aliases regardless of case and accents
+func CanonicalTitle(aliases map[string]string, title string) (string, bool) {
+	if len(aliases) == 0 || len(title) == 0 {
+		return title, false
+	}
+	t := NormalizeForMatch(title)
+	for alias, canonical := range aliases {
+		if NormalizeForMatch(alias) == t {
+			return canonical, true
+		}
+	}
+	return title, false
+}
+
+// WriteNFO writes the media's NFO file next to the video file, with the same base name.
+func WriteNFO(m *Media, videoPath string) error {
+	if m.Metadata == nil {
+		return fmt.Errorf("Can't write NFO for %q: no metadata", m.ID)
+	}
+	nfoPath := strings.TrimSuffix(videoPath, filepath.Ext(videoPath)) + ".nfo"
+	return m.Metadata.WriteNFO(nfoPath)
+}
+
+// WriteSeriesNFO writes the tvshow.nfo file of the media's series into showDir, with the show's title,
+// plot and poster. An existing file is kept, unless force is true. Other media than series are ignored.
+func WriteSeriesNFO(m *Media, showDir string, force bool) error {
+	if m.ShowType != Series || m.Metadata == nil {
+		return nil
+	}
+	nfoPath := filepath.Join(showDir, "tvshow.nfo")
+	if _, err := os.Stat(nfoPath); err == nil && !force {
+		return nil
+	}
+
+	info := m.Metadata.GetMediaInfo()
+	show := nfo.TVShow{}
+	if info.TVShow != nil {
+		show = *info.TVShow
+	}
+	if len(show.Title) == 0 {
+		show.Title = info.Showtitle
+	}
+	if len(show.Plot) == 0 {
+		show.Plot = info.Plot
+	}
+	if len(show.Studio) == 0 {
+		show.Studio = info.Studio
+	}
+	poster := findThumb(show.Thumb, "poster")
+	if poster == nil {
+		poster = findThumb(info.Thumb, "poster")
+	}
+	show.Art = nil
+	if poster != nil {
+		show.Art = []nfo.Thumb{{Aspect: "poster", URL: poster.URL}}
+	}
+	if err := show.WriteNFO(nfoPath); err != nil {
+		return fmt.Errorf("Can't write series NFO for %q: %w", show.Title, err)
+	}
+	return nil
+}
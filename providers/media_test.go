@@ -0,0 +1,151 @@
+package providers
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/simulot/aspiratv/metadata/nfo"
+)
+
+func TestWriteNFO(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aspiratv-nfo-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	m := &Media{
+		ID: "1234",
+		Metadata: &nfo.EpisodeDetails{
+			MediaInfo: nfo.MediaInfo{
+				Showtitle: "Tom & Jerry",
+				Title:     "Le <retour>",
+				Season:    2,
+				Episode:   5,
+				Plot:      "Une souris et un chat",
+				Aired:     nfo.Aired(time.Date(2020, 3, 14, 20, 30, 0, 0, time.UTC)),
+				Thumb:     []nfo.Thumb{{URL: "http://example.com/thumb.jpg"}},
+			},
+		},
+	}
+	video := filepath.Join(dir, "show.mp4")
+	err = WriteNFO(m, video)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(filepath.Join(dir, "show.nfo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(b)
+	for _, want := range []string{
+		"<episodedetails>",
+		"<title>Le &lt;retour&gt;</title>",
+		"<showtitle>Tom &amp; Jerry</showtitle>",
+		"<season>2</season>",
+		"<episode>5</episode>",
+		"<plot>Une souris et un chat</plot>",
+		"<thumb>http://example.com/thumb.jpg</thumb>",
+		"<aired>2020-03-14</aired>",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expecting NFO to contain %q, got %s", want, got)
+		}
+	}
+}
+
+func TestWriteSeriesNFO(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aspiratv-nfo-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	m := &Media{
+		ID:       "1234",
+		ShowType: Series,
+		Metadata: &nfo.EpisodeDetails{
+			MediaInfo: nfo.MediaInfo{
+				Showtitle: "Tom & Jerry",
+				Title:     "Le retour",
+				Plot:      "Une souris et un chat",
+				Thumb:     []nfo.Thumb{{Aspect: "poster", URL: "http://example.com/poster.jpg"}},
+			},
+		},
+	}
+	showDir := filepath.Join(dir, "Tom & Jerry")
+	nfoPath := filepath.Join(showDir, "tvshow.nfo")
+	if err = WriteSeriesNFO(m, showDir, false); err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(nfoPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(b)
+	for _, want := range []string{
+		"<tvshow>",
+		"<title>Tom &amp; Jerry</title>",
+		"<plot>Une souris et un chat</plot>",
+		`<thumb aspect="poster">http://example.com/poster.jpg</thumb>`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expecting NFO to contain %q, got %s", want, got)
+		}
+	}
+
+	// An existing file is kept, unless forced
+	m.Metadata.GetMediaInfo().Plot = "Un chat et une souris"
+	if err = WriteSeriesNFO(m, showDir, false); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ = ioutil.ReadFile(nfoPath); strings.Contains(string(b), "Un chat et une souris") {
+		t.Errorf("Expecting tvshow.nfo to be kept")
+	}
+	if err = WriteSeriesNFO(m, showDir, true); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ = ioutil.ReadFile(nfoPath); !strings.Contains(string(b), "Un chat et une souris") {
+		t.Errorf("Expecting tvshow.nfo to be overwritten")
+	}
+}
+
+func TestApplyMatchTitleAliases(t *testing.T) {
+	aliases := map[string]string{
+		"Le 20h":        "Journal de 20 heures",
+		"Journal 20h00": "Journal de 20 heures",
+	}
+	tests := []struct {
+		showtitle string
+		want      string
+	}{
+		{"Le 20h", "Journal de 20 heures"},
+		{"le 20H", "Journal de 20 heures"},
+		{"Journal 20h00", "Journal de 20 heures"},
+		{"Télématin", "Télématin"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.showtitle, func(t *testing.T) {
+			m := &Media{
+				Match: &MatchRequest{TitleAliases: aliases, Container: "MKV"},
+				Metadata: &nfo.EpisodeDetails{MediaInfo: nfo.MediaInfo{
+					Showtitle: tt.showtitle,
+					TVShow:    &nfo.TVShow{Title: tt.showtitle},
+				}},
+			}
+			m.ApplyMatch()
+			info := m.Metadata.GetMediaInfo()
+			if info.Showtitle != tt.want || info.TVShow.Title != tt.want {
+				t.Errorf("Expecting show title %q, got %q and %q", tt.want, info.Showtitle, info.TVShow.Title)
+			}
+			if info.Container != "mkv" {
+				t.Errorf("Expecting container mkv, got %q", info.Container)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package providers
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/simulot/aspiratv/net/myhttp"
+)
+
+// MockGetter answers requests with canned responses, to inject into providers under test with their WithGetter option.
+// It implements the Get and DoWithContext methods of the getters used by providers.
+type MockGetter struct {
+	Responses map[string]string // Response bodies by URL or URL prefix
+	Err       error             // Error returned to all requests when not nil
+
+	mu       sync.Mutex
+	requests []string
+}
+
+// StaticGetter gives the response of the longest key of responses that is the requested URL or one of its prefixes,
+// so query string variations still resolve. URLs without response get a 404 myhttp.HTTPError.
+func StaticGetter(responses map[string]string) *MockGetter {
+	return &MockGetter{Responses: responses}
+}
+
+// ErrorGetter returns err to all requests
+func ErrorGetter(err error) *MockGetter {
+	return &MockGetter{Err: err}
+}
+
+// Get returns the response of the uri
+func (g *MockGetter) Get(ctx context.Context, uri string) (io.ReadCloser, error) {
+	g.mu.Lock()
+	g.requests = append(g.requests, uri)
+	g.mu.Unlock()
+	if g.Err != nil {
+		return nil, g.Err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	body, ok := g.response(uri)
+	if !ok {
+		return nil, &myhttp.HTTPError{StatusCode: http.StatusNotFound, Status: "404 Not Found"}
+	}
+	return ioutil.NopCloser(strings.NewReader(body)), nil
+}
+
+// DoWithContext returns the response of theURL, whatever the method, headers and body
+func (g *MockGetter) DoWithContext(ctx context.Context, method string, theURL string, headers http.Header, body io.Reader) (io.ReadCloser, error) {
+	return g.Get(ctx, theURL)
+}
+
+// Requests gives the requested URLs, in request order
+func (g *MockGetter) Requests() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]string{}, g.requests...)
+}
+
+func (g *MockGetter) response(uri string) (string, bool) {
+	if body, ok := g.Responses[uri]; ok {
+		return body, true
+	}
+	best := ""
+	found := false
+	for k := range g.Responses {
+		if strings.HasPrefix(uri, k) && (!found || len(k) > len(best)) {
+			best, found = k, true
+		}
+	}
+	return g.Responses[best], found
+}
@@ -0,0 +1,56 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/simulot/aspiratv/net/myhttp"
+)
+
+func TestStaticGetter(t *testing.T) {
+	g := StaticGetter(map[string]string{
+		"http://example.com/api":         "api",
+		"http://example.com/api/shows":   "shows",
+		"http://example.com/api/shows/1": "show 1",
+	})
+	tests := []struct {
+		uri  string
+		want string
+	}{
+		{"http://example.com/api/shows/1", "show 1"},
+		{"http://example.com/api/shows?page=2", "shows"},
+		{"http://example.com/api/other", "api"},
+	}
+	for _, tt := range tests {
+		r, err := g.Get(context.Background(), tt.uri)
+		if err != nil {
+			t.Errorf("Get(%q) error: %s", tt.uri, err)
+			continue
+		}
+		b, _ := ioutil.ReadAll(r)
+		r.Close()
+		if string(b) != tt.want {
+			t.Errorf("Get(%q) = %q, want %q", tt.uri, b, tt.want)
+		}
+	}
+
+	_, err := g.Get(context.Background(), "http://example.org/")
+	var httpErr *myhttp.HTTPError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusNotFound {
+		t.Errorf("Expecting a 404 error, got %v", err)
+	}
+	if got := len(g.Requests()); got != len(tests)+1 {
+		t.Errorf("Expecting %d requests, got %d", len(tests)+1, got)
+	}
+}
+
+func TestErrorGetter(t *testing.T) {
+	want := errors.New("network down")
+	_, err := ErrorGetter(want).DoWithContext(context.Background(), "POST", "http://example.com/", nil, nil)
+	if !errors.Is(err, want) {
+		t.Errorf("Expecting %v, got %v", want, err)
+	}
+}
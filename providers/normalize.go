@@ -0,0 +1,26 @@
+package providers
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// NormalizeForMatch lowers the string and removes its diacritics: "Télématin" gives "telematin".
+// Both sides of a comparison between a request and a media must be normalized.
+func NormalizeForMatch(s string) string {
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	r, _, err := transform.String(t, s)
+	if err != nil {
+		r = s
+	}
+	return strings.ToLower(strings.TrimSpace(r))
+}
+
+// ContainsForMatch tells if s contains substr, regardless of case and accents
+func ContainsForMatch(s, substr string) bool {
+	return strings.Contains(NormalizeForMatch(s), NormalizeForMatch(substr))
+}
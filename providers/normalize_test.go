@@ -0,0 +1,42 @@
+package providers
+
+import "testing"
+
+func TestNormalizeForMatch(t *testing.T) {
+	tests := []struct {
+		s    string
+		want string
+	}{
+		{"Télématin", "telematin"},
+		{"  Ça Roule ", "ca roule"},
+		{"Noël à l'Élysée", "noel a l'elysee"},
+		{"plain", "plain"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			if got := NormalizeForMatch(tt.s); got != tt.want {
+				t.Errorf("NormalizeForMatch() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContainsForMatch(t *testing.T) {
+	tests := []struct {
+		s      string
+		substr string
+		want   bool
+	}{
+		{"Télématin", "telematin", true},
+		{"telematin", "Télématin", true},
+		{"Les aventures de Tintin", "AVENTURES", true},
+		{"Télématin", "journal", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s+"/"+tt.substr, func(t *testing.T) {
+			if got := ContainsForMatch(tt.s, tt.substr); got != tt.want {
+				t.Errorf("ContainsForMatch() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
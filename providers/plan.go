@@ -0,0 +1,57 @@
+package providers
+
+import (
+	"context"
+)
+
+// DownloadPlan describes what would be downloaded for a media
+type DownloadPlan struct {
+	Media *Media
+	Path  string // Media file name
+	URL   string // Stream URL, when resolved
+}
+
+// PlanDownloads lists the media matching the requests and computes where they would be written.
+// Destinations maps MatchRequest's destination onto the actual path.
+// When resolve is true, the stream URL is resolved with GetMediaDetails.
+// Nothing is written on disk.
+func PlanDownloads(ctx context.Context, p Provider, mm []*MatchRequest, destinations map[string]string, resolve bool) ([]DownloadPlan, error) {
+	plans := []DownloadPlan{}
+	var errs Errors
+	seen := map[string]bool{}
+
+	medias, errc := p.MediaList(ctx, mm)
+	for m := range medias {
+		if seen[m.ID] {
+			continue
+		}
+		// Requests are matched against the canonical show title
+		m.ApplyMatch()
+		if !MatchAny(m, mm) {
+			continue
+		}
+		seen[m.ID] = true
+		if resolve {
+			err := p.GetMediaDetails(ctx, m)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+		}
+		plans = append(plans, DownloadPlan{
+			Media: m,
+			Path:  m.Metadata.GetMediaPath(destinations[m.Match.Destination]),
+			URL:   m.Metadata.GetMediaInfo().URL,
+		})
+	}
+	if err := <-errc; err != nil {
+		errs = append(errs, err)
+	}
+	if ctx.Err() != nil {
+		return plans, ctx.Err()
+	}
+	if len(errs) > 0 {
+		return plans, errs
+	}
+	return plans, nil
+}
@@ -0,0 +1,103 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/simulot/aspiratv/metadata/nfo"
+)
+
+type listProvider struct {
+	fakeProvider
+	mm  []*Media
+	err error // Returned once media are listed
+}
+
+func (p *listProvider) MediaList(ctx context.Context, mr []*MatchRequest) (chan *Media, <-chan error) {
+	c := make(chan *Media)
+	errc := make(chan error, 1)
+	go func() {
+		defer CloseMediaList(c, errc, p.err)
+		for _, m := range p.mm {
+			c <- m
+		}
+	}()
+	return c, errc
+}
+
+func (p *listProvider) GetMediaDetails(ctx context.Context, m *Media) error {
+	if m.ID == "bad" {
+		return errors.New("no stream")
+	}
+	m.Metadata.GetMediaInfo().URL = "http://example.com/" + m.ID + ".m3u8"
+	return nil
+}
+
+func TestPlanDownloads(t *testing.T) {
+	mr := &MatchRequest{Destination: "DL", TitleRegexp: "^Le Journal"}
+	movie := func(id, title string) *Media {
+		return &Media{ID: id, Match: mr, ShowType: Movie, Metadata: &nfo.Movie{MediaInfo: nfo.MediaInfo{Title: title}}}
+	}
+	p := &listProvider{
+		mm: []*Media{
+			movie("1", "Le Journal"),
+			movie("1", "Le Journal"),
+			movie("2", "Autre chose"),
+			movie("bad", "Le Journal du soir"),
+		},
+	}
+	dest := map[string]string{"DL": "/media"}
+
+	plans, err := PlanDownloads(context.TODO(), p, []*MatchRequest{mr}, dest, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plans) != 2 {
+		t.Fatalf("Expecting 2 plans, got %d", len(plans))
+	}
+	if want := filepath.Join("/media", "Le Journal", "Le Journal.mp4"); plans[0].Path != want {
+		t.Errorf("Expecting path %q, got %q", want, plans[0].Path)
+	}
+	if plans[0].URL != "" {
+		t.Errorf("Expecting URL not to be resolved, got %q", plans[0].URL)
+	}
+
+	plans, err = PlanDownloads(context.TODO(), p, []*MatchRequest{mr}, dest, true)
+	if err == nil {
+		t.Errorf("Expecting an error for media %q", "bad")
+	}
+	if len(plans) != 1 || plans[0].URL != "http://example.com/1.m3u8" {
+		t.Errorf("Expecting resolved URL, got %+v", plans)
+	}
+
+	p.err = errors.New("catalog unavailable")
+	plans, err = PlanDownloads(context.TODO(), p, []*MatchRequest{mr}, dest, false)
+	if err == nil || !strings.Contains(err.Error(), "catalog unavailable") {
+		t.Errorf("Expecting the listing error, got %v", err)
+	}
+	if len(plans) != 2 {
+		t.Errorf("Expecting media listed before the error to be planned, got %d", len(plans))
+	}
+}
+
+func TestPlanDownloadsTitleAliases(t *testing.T) {
+	mr := &MatchRequest{Destination: "DL", TitleRegexp: "^Le Journal$", TitleAliases: map[string]string{"JT de 20h": "Le Journal"}}
+	p := &listProvider{
+		mm: []*Media{
+			{ID: "1", Match: mr, ShowType: Series, Metadata: &nfo.EpisodeDetails{MediaInfo: nfo.MediaInfo{Showtitle: "JT de 20h", Title: "Édition du soir"}}},
+		},
+	}
+	plans, err := PlanDownloads(context.TODO(), p, []*MatchRequest{mr}, map[string]string{"DL": "/media"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plans) != 1 {
+		t.Fatalf("Expecting the alias to be matched with its canonical title, got %d plans", len(plans))
+	}
+	if got := plans[0].Media.Metadata.GetMediaInfo().Showtitle; got != "Le Journal" {
+		t.Errorf("Expecting canonical show title, got %q", got)
+	}
+}
@@ -0,0 +1,176 @@
+package providers
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// ProgressReporter is notified of the progress of a batch of downloads.
+// Its methods are called concurrently by the downloads of the batch.
+type ProgressReporter interface {
+	Start(m *Media)           // The download of the media starts
+	Bytes(m *Media, n int64)  // n more bytes of the media have been downloaded
+	Done(m *Media, err error) // The download of the media is ended, err is nil on success
+}
+
+// SkipReporter is a ProgressReporter also told of media that aren't downloaded, because they are already present
+type SkipReporter interface {
+	ProgressReporter
+	Skipped(m *Media)
+}
+
+// TextReporter is a ProgressReporter that writes a line for the media progress and the batch total
+type TextReporter struct {
+	w        io.Writer
+	interval time.Duration // Minimum time between two lines of the same media
+
+	mu      sync.Mutex
+	started int
+	done    int
+	failed  int
+	total   int64
+	medias  map[*Media]*mediaProgress
+}
+
+type mediaProgress struct {
+	bytes     int64
+	lastPrint time.Time
+}
+
+// NewTextReporter create a reporter writing into w
+func NewTextReporter(w io.Writer) *TextReporter {
+	return &TextReporter{
+		w:        w,
+		interval: time.Second,
+		medias:   map[*Media]*mediaProgress{},
+	}
+}
+
+// NewStdoutReporter create a reporter writing on the standard output
+func NewStdoutReporter() *TextReporter {
+	return NewTextReporter(os.Stdout)
+}
+
+// Start implements ProgressReporter
+func (r *TextReporter) Start(m *Media) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.started++
+	r.medias[m] = &mediaProgress{lastPrint: time.Now()}
+	fmt.Fprintf(r.w, "%s: started, %s\n", mediaName(m), r.totalLine())
+}
+
+// Bytes implements ProgressReporter
+func (r *TextReporter) Bytes(m *Media, n int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.total += n
+	p, ok := r.medias[m]
+	if !ok {
+		return
+	}
+	p.bytes += n
+	if time.Since(p.lastPrint) < r.interval {
+		return
+	}
+	p.lastPrint = time.Now()
+	fmt.Fprintf(r.w, "%s: %s, %s\n", mediaName(m), byteSize(p.bytes), r.totalLine())
+}
+
+// Done implements ProgressReporter
+func (r *TextReporter) Done(m *Media, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var size int64
+	if p, ok := r.medias[m]; ok {
+		size = p.bytes
+		delete(r.medias, m)
+	}
+	r.done++
+	if err != nil {
+		r.failed++
+		fmt.Fprintf(r.w, "%s: failed, %s, %s\n", mediaName(m), err, r.totalLine())
+		return
+	}
+	fmt.Fprintf(r.w, "%s: done, %s, %s\n", mediaName(m), byteSize(size), r.totalLine())
+}
+
+func (r *TextReporter) totalLine() string {
+	s := fmt.Sprintf("total %d/%d done, %s", r.done, r.started, byteSize(r.total))
+	if r.failed > 0 {
+		s += fmt.Sprintf(", %d failed", r.failed)
+	}
+	return s
+}
+
+func mediaName(m *Media) string {
+	if m.Metadata == nil {
+		return m.ID
+	}
+	info := m.Metadata.GetMediaInfo()
+	if info.Showtitle != "" && info.Showtitle != info.Title {
+		return info.Showtitle + " - " + info.Title
+	}
+	return info.Title
+}
+
+func byteSize(n int64) string {
+	switch {
+	case n >= 1024*1024*1024:
+		return fmt.Sprintf("%.1f GB", float64(n)/(1024*1024*1024))
+	case n >= 1024*1024:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1024*1024))
+	case n >= 1024:
+		return fmt.Sprintf("%.1f KB", float64(n)/1024)
+	}
+	return fmt.Sprintf("%d B", n)
+}
+
+type multiReporter []ProgressReporter
+
+// MultiReporter notifies all the given reporters. Nil reporters are ignored, and nil is returned when none is left.
+func MultiReporter(reporters ...ProgressReporter) ProgressReporter {
+	rs := multiReporter{}
+	for _, r := range reporters {
+		if r != nil {
+			rs = append(rs, r)
+		}
+	}
+	switch len(rs) {
+	case 0:
+		return nil
+	case 1:
+		return rs[0]
+	}
+	return rs
+}
+
+func (rs multiReporter) Start(m *Media) {
+	for _, r := range rs {
+		r.Start(m)
+	}
+}
+
+func (rs multiReporter) Bytes(m *Media, n int64) {
+	for _, r := range rs {
+		r.Bytes(m, n)
+	}
+}
+
+func (rs multiReporter) Done(m *Media, err error) {
+	for _, r := range rs {
+		r.Done(m, err)
+	}
+}
+
+// Skipped notifies the reporters that are SkipReporters
+func (rs multiReporter) Skipped(m *Media) {
+	for _, r := range rs {
+		if s, ok := r.(SkipReporter); ok {
+			s.Skipped(m)
+		}
+	}
+}
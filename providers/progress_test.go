@@ -0,0 +1,44 @@
+package providers
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/simulot/aspiratv/metadata/nfo"
+)
+
+func TestTextReporter(t *testing.T) {
+	b := bytes.Buffer{}
+	r := NewTextReporter(&b)
+	r.interval = 0
+
+	m1 := &Media{ID: "1", Metadata: &nfo.EpisodeDetails{MediaInfo: nfo.MediaInfo{Showtitle: "Les Dalton", Title: "Joe"}}}
+	m2 := &Media{ID: "2", Metadata: &nfo.Movie{MediaInfo: nfo.MediaInfo{Title: "Cyrano de Bergerac"}}}
+
+	r.Start(m1)
+	r.Start(m2)
+	r.Bytes(m1, 2048)
+	r.Bytes(m2, 1024*1024)
+	r.Done(m1, nil)
+	r.Done(m2, errors.New("ffmpeg error"))
+
+	expected := []string{
+		"Les Dalton - Joe: started, total 0/1 done, 0 B",
+		"Cyrano de Bergerac: started, total 0/2 done, 0 B",
+		"Les Dalton - Joe: 2.0 KB, total 0/2 done, 2.0 KB",
+		"Cyrano de Bergerac: 1.0 MB, total 0/2 done, 1.0 MB",
+		"Les Dalton - Joe: done, 2.0 KB, total 1/2 done, 1.0 MB",
+		"Cyrano de Bergerac: failed, ffmpeg error, total 2/2 done, 1.0 MB, 1 failed",
+	}
+	got := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if len(got) != len(expected) {
+		t.Fatalf("Expecting %d lines, got %d:\n%s", len(expected), len(got), b.String())
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Errorf("Line %d: expecting %q, got %q", i, expected[i], got[i])
+		}
+	}
+}
@@ -2,16 +2,40 @@ package providers
 
 import (
 	"context"
+	"sort"
 )
 
 // Provider is the interface for a provider
 type Provider interface {
-	Configure(c Config)                                     // Pass general configuration
-	Name() string                                           // Provider's name
-	MediaList(context.Context, []*MatchRequest) chan *Media // List of available shows that match one of MatchRequest
-	GetMediaDetails(context.Context, *Media) error          // Download more details when available
+	Configure(c Config)                                                     // Pass general configuration
+	Name() string                                                           // Provider's name
+	MediaList(context.Context, []*MatchRequest) (chan *Media, <-chan error) // List of available shows that match one of MatchRequest, and listing errors
+	GetMediaDetails(context.Context, *Media) error                          // Download more details when available
+	Download(context.Context, *Media, string, ProgressFunc) error           // Download the media into the given file
+	HealthCheck(context.Context) error                                      // Check if the provider's backend is reachable
+	Capabilities() Capabilities                                             // Features supported by the provider
 }
 
+// NoHealthCheck is embedded by providers without health check
+type NoHealthCheck struct{}
+
+// HealthCheck always succeeds
+func (NoHealthCheck) HealthCheck(context.Context) error { return nil }
+
+// CloseMediaList ends a MediaList. The media channel is closed first, then err, when not nil,
+// is sent on the error channel before it is closed. The error channel must have a buffer of one
+// so the provider never waits for the caller.
+func CloseMediaList(media chan *Media, errc chan error, err error) {
+	close(media)
+	if err != nil {
+		errc <- err
+	}
+	close(errc)
+}
+
+// ProgressFunc receives the download progression
+type ProgressFunc func(current, total int64)
+
 var providers = map[string]Provider{}
 
 // Register is called by provider's init to register the provider
@@ -19,12 +43,35 @@ func Register(p Provider) {
 	providers[p.Name()] = p
 }
 
-// List of registered providers
-func List() map[string]Provider {
-	return providers
+// List returns registered providers sorted by name
+func List() []Provider {
+	l := make([]Provider, 0, len(providers))
+	for _, p := range providers {
+		l = append(l, p)
+	}
+	sort.Slice(l, func(i, j int) bool {
+		return l[i].Name() < l[j].Name()
+	})
+	return l
+}
+
+// Get returns the registered provider with the given name
+func Get(name string) (Provider, bool) {
+	p, ok := providers[name]
+	return p, ok
 }
 
 type Config struct {
 	Debug     bool
 	KeepBonus bool
+	Logger    Logger   // Logger to be used, a StdLogger when nil
+	Settings  Settings // Provider's own settings
+}
+
+// GetLogger returns the configured logger, or a StdLogger named after the provider
+func (c Config) GetLogger(name string) Logger {
+	if c.Logger != nil {
+		return c.Logger
+	}
+	return NewStdLogger(name, c.Debug)
 }
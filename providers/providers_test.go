@@ -0,0 +1,56 @@
+package providers
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+type fakeProvider struct {
+	NoHealthCheck
+	name string
+}
+
+func (f fakeProvider) Configure(c Config) {}
+func (f fakeProvider) Name() string       { return f.name }
+func (f fakeProvider) MediaList(context.Context, []*MatchRequest) (chan *Media, <-chan error) {
+	return nil, nil
+}
+func (f fakeProvider) GetMediaDetails(context.Context, *Media) error { return nil }
+func (f fakeProvider) Capabilities() Capabilities                    { return Capabilities{} }
+func (f fakeProvider) Download(context.Context, *Media, string, ProgressFunc) error {
+	return nil
+}
+
+func TestRegister(t *testing.T) {
+	Register(fakeProvider{name: "zz-fake"})
+	Register(fakeProvider{name: "aa-fake"})
+
+	p, ok := Get("zz-fake")
+	if !ok || p.Name() != "zz-fake" {
+		t.Errorf("Expecting to get provider %q", "zz-fake")
+	}
+	if _, ok := Get("unknown"); ok {
+		t.Errorf("Expecting unknown provider not to be found")
+	}
+
+	l := List()
+	if len(l) < 2 {
+		t.Fatalf("Expecting at least 2 providers, got %d", len(l))
+	}
+	for i := 1; i < len(l); i++ {
+		if l[i-1].Name() > l[i].Name() {
+			t.Errorf("Expecting providers to be sorted, got %q before %q", l[i-1].Name(), l[i].Name())
+		}
+	}
+}
+
+func TestCapabilitiesFeatures(t *testing.T) {
+	got := strings.Join(Capabilities{HLS: true, Subtitles: true, Chapters: true}.Features(), ",")
+	if want := "hls,subtitles,chapters"; got != want {
+		t.Errorf("Features() = %q, want %q", got, want)
+	}
+	if got := (Capabilities{}).Features(); len(got) != 0 {
+		t.Errorf("Expecting no features, got %v", got)
+	}
+}
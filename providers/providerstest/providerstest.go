@@ -0,0 +1,170 @@
+// Package providerstest provides helpers to build media and match requests in tests.
+package providerstest
+
+import (
+	"time"
+
+	"github.com/simulot/aspiratv/metadata/nfo"
+	"github.com/simulot/aspiratv/providers"
+)
+
+// MediaBuilder builds a fully populated media with fluent setters. A movie by default.
+type MediaBuilder struct {
+	m    *providers.Media
+	info *nfo.MediaInfo
+}
+
+// NewMediaBuilder starts building a movie media with the given ID
+func NewMediaBuilder(id string) *MediaBuilder {
+	b := &MediaBuilder{m: &providers.Media{ID: id}}
+	b.Movie()
+	return b
+}
+
+// NewTestMedia builds a media with the given ID and configures it with opts
+func NewTestMedia(id string, opts ...func(b *MediaBuilder)) *providers.Media {
+	b := NewMediaBuilder(id)
+	for _, o := range opts {
+		o(b)
+	}
+	return b.Build()
+}
+
+// Movie makes the media a movie, keeping its information
+func (b *MediaBuilder) Movie() *MediaBuilder {
+	meta := &nfo.Movie{}
+	b.setMetadata(meta, &meta.MediaInfo, providers.Movie)
+	return b
+}
+
+// Series makes the media an episode of the show, keeping its information
+func (b *MediaBuilder) Series(show string) *MediaBuilder {
+	meta := &nfo.EpisodeDetails{}
+	b.setMetadata(meta, &meta.MediaInfo, providers.Series)
+	b.info.Showtitle = show
+	return b
+}
+
+func (b *MediaBuilder) setMetadata(meta providers.MetaDataHandler, info *nfo.MediaInfo, t providers.ShowType) {
+	if b.info != nil {
+		*info = *b.info
+	}
+	b.info = info
+	b.m.SetMetaData(meta)
+	b.m.ShowType = t
+}
+
+// Title sets the title of the media
+func (b *MediaBuilder) Title(title string) *MediaBuilder {
+	b.info.Title = title
+	return b
+}
+
+// Episode sets the season and episode numbers
+func (b *MediaBuilder) Episode(season, episode int) *MediaBuilder {
+	b.info.Season = season
+	b.info.Episode = episode
+	return b
+}
+
+// Aired sets the broadcast date, and the year of movies
+func (b *MediaBuilder) Aired(t time.Time) *MediaBuilder {
+	b.info.Aired = nfo.Aired(t)
+	if b.m.ShowType == providers.Movie {
+		b.info.Year = t.Year()
+	}
+	return b
+}
+
+// Channel sets the channel broadcasting the media
+func (b *MediaBuilder) Channel(channel string) *MediaBuilder {
+	b.info.Studio = channel
+	return b
+}
+
+// Duration sets the duration of the media
+func (b *MediaBuilder) Duration(d time.Duration) *MediaBuilder {
+	b.info.Duration = d
+	return b
+}
+
+// Plot sets the description of the media
+func (b *MediaBuilder) Plot(plot string) *MediaBuilder {
+	b.info.Plot = plot
+	return b
+}
+
+// Tags sets the tags, like categories, of the media
+func (b *MediaBuilder) Tags(tags ...string) *MediaBuilder {
+	b.info.Tag = tags
+	return b
+}
+
+// Kind sets the kind of the media: providers.KindEpisode, KindExtract or KindBonus
+func (b *MediaBuilder) Kind(kind string) *MediaBuilder {
+	b.m.Kind = kind
+	return b
+}
+
+// Thumb adds a thumbnail of the given aspect, like thumb or poster
+func (b *MediaBuilder) Thumb(aspect, url string) *MediaBuilder {
+	b.info.Thumb = append(b.info.Thumb, nfo.Thumb{Aspect: aspect, URL: url})
+	return b
+}
+
+// Match sets the request matched by the media
+func (b *MediaBuilder) Match(mr *providers.MatchRequest) *MediaBuilder {
+	b.m.Match = mr
+	return b
+}
+
+// Build returns the media
+func (b *MediaBuilder) Build() *providers.Media {
+	return b.m
+}
+
+// NewMatchRequest builds a compiled request for the show of the provider, configured with opts.
+// It panics when the request can't be compiled.
+func NewMatchRequest(provider, show string, opts ...func(mr *providers.MatchRequest)) *providers.MatchRequest {
+	mr := &providers.MatchRequest{
+		Provider:    provider,
+		Show:        show,
+		Destination: "default",
+	}
+	for _, o := range opts {
+		o(mr)
+	}
+	if err := mr.Compile(); err != nil {
+		panic(err)
+	}
+	return mr
+}
+
+// WithChannels sets the accepted channels of the request
+func WithChannels(channels ...string) func(mr *providers.MatchRequest) {
+	return func(mr *providers.MatchRequest) {
+		mr.Channels = channels
+	}
+}
+
+// WithTitleRegexp sets the regular expression the title must match
+func WithTitleRegexp(re string) func(mr *providers.MatchRequest) {
+	return func(mr *providers.MatchRequest) {
+		mr.TitleRegexp = re
+	}
+}
+
+// WithDurations sets the accepted durations of the media, a zero value means no limit
+func WithDurations(min, max time.Duration) func(mr *providers.MatchRequest) {
+	return func(mr *providers.MatchRequest) {
+		mr.MinDuration = providers.TextDuration(min)
+		mr.MaxDuration = providers.TextDuration(max)
+	}
+}
+
+// WithExtracts accepts extracts and bonuses
+func WithExtracts() func(mr *providers.MatchRequest) {
+	return func(mr *providers.MatchRequest) {
+		mr.Extracts = true
+	}
+}
@@ -0,0 +1,42 @@
+package providerstest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/simulot/aspiratv/providers"
+)
+
+func TestNewTestMedia(t *testing.T) {
+	aired := time.Date(2020, 5, 1, 20, 0, 0, 0, time.UTC)
+	m := NewTestMedia("1", func(b *MediaBuilder) {
+		b.Title("Le grand départ").Aired(aired).Series("Les Dalton").Episode(2, 3).Channel("France 3")
+	})
+	info := m.Metadata.GetMediaInfo()
+	if m.ShowType != providers.Series || info.Showtitle != "Les Dalton" || info.Title != "Le grand départ" {
+		t.Errorf("Unexpected media %v %q %q", m.ShowType, info.Showtitle, info.Title)
+	}
+	if info.Season != 2 || info.Episode != 3 || info.Year != 2020 {
+		t.Errorf("Unexpected numbers s%de%d %d", info.Season, info.Episode, info.Year)
+	}
+}
+
+func TestNewMatchRequest(t *testing.T) {
+	mr := NewMatchRequest("francetv", "les dalton", WithChannels("france3"), WithDurations(5*time.Minute, 0))
+	tests := []struct {
+		name string
+		m    *providers.Media
+		want bool
+	}{
+		{"match", NewMediaBuilder("1").Series("Les Dalton").Channel("France 3").Duration(7 * time.Minute).Match(mr).Build(), true},
+		{"other channel", NewMediaBuilder("2").Series("Les Dalton").Channel("France 2").Duration(7 * time.Minute).Match(mr).Build(), false},
+		{"too short", NewMediaBuilder("3").Series("Les Dalton").Channel("France 3").Duration(time.Minute).Match(mr).Build(), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := providers.IsMediaMatch(tt.m); got != tt.want {
+				t.Errorf("IsMediaMatch() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package providers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Settings are the options of a provider that can be given in a configuration file
+type Settings struct {
+	Quality      string            `json:"Quality,omitempty"`      // Default stream quality, like "720p", used when the request doesn't give one
+	Timezone     string            `json:"Timezone,omitempty"`     // Time zone of broadcast dates, like "Europe/Paris"
+	CatalogLimit int               `json:"CatalogLimit,omitempty"` // Maximum number of search results per request, 0 for no limit
+	Headers      map[string]string `json:"Headers,omitempty"`      // Headers sent with each request, like User-Agent or Referer
+	Diffusion    string            `json:"Diffusion,omitempty"`    // Diffusion kept among the re-airings of a media: latest expiring, the default, or soonest
+
+	SkipProgressive bool `json:"SkipProgressive,omitempty"` // Progressive MP4 files are ignored, the stream is always downloaded
+	EpisodeGuide    bool `json:"EpisodeGuide,omitempty"`    // Missing episode numbers are read from the show page, an extra request per show
+
+	Token     string `json:"Token,omitempty"`     // Session token sent with the requests, for premium content
+	TokenFile string `json:"TokenFile,omitempty"` // File holding the session token, read again when the token is rejected
+}
+
+// UnmarshalJSON reads the settings. Numbers and booleans can also be given as strings, as in
+// the map[string]string settings of the previous versions, and unknown settings are ignored.
+func (s *Settings) UnmarshalJSON(b []byte) error {
+	fields := map[string]json.RawMessage{}
+	err := json.Unmarshal(b, &fields)
+	if err != nil {
+		return err
+	}
+	type plain Settings
+	p := plain(*s)
+	for k, v := range fields {
+		err = unmarshalField(&p, k, v)
+		if err == nil {
+			continue
+		}
+		var text string
+		if json.Unmarshal(v, &text) != nil || unmarshalField(&p, k, json.RawMessage(text)) != nil {
+			return fmt.Errorf("Can't decode setting %q: %w", k, err)
+		}
+	}
+	*s = Settings(p)
+	return nil
+}
+
+// unmarshalField decodes the value v of the field k into p
+func unmarshalField(p interface{}, k string, v json.RawMessage) error {
+	b, err := json.Marshal(map[string]json.RawMessage{k: v})
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, p)
+}
+
+// Location returns the time zone of the settings, nil when not set
+func (s Settings) Location() (*time.Location, error) {
+	if len(s.Timezone) == 0 {
+		return nil, nil
+	}
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("Can't load time zone %q: %w", s.Timezone, err)
+	}
+	return loc, nil
+}
+
+// LoadSettings reads the settings of each provider from a JSON object keyed by provider name
+func LoadSettings(r io.Reader) (map[string]Settings, error) {
+	s := map[string]Settings{}
+	err := json.NewDecoder(r).Decode(&s)
+	if err != nil {
+		return nil, fmt.Errorf("Can't decode provider settings: %w", err)
+	}
+	for name, ps := range s {
+		if _, err := ps.Location(); err != nil {
+			return nil, fmt.Errorf("Can't load settings of %q: %w", name, err)
+		}
+	}
+	return s, nil
+}
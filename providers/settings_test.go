@@ -0,0 +1,45 @@
+package providers
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoadSettings(t *testing.T) {
+	s, err := LoadSettings(strings.NewReader(`{
+		"francetv": {"Quality": "720p", "Timezone": "Europe/Paris", "CatalogLimit": 50, "Headers": {"Referer": "https://www.france.tv/"}},
+		"artetv": {}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ftv := s["francetv"]
+	if ftv.Quality != "720p" || ftv.CatalogLimit != 50 || ftv.Headers["Referer"] != "https://www.france.tv/" {
+		t.Errorf("Unexpected francetv settings %+v", ftv)
+	}
+	if loc, _ := ftv.Location(); loc == nil || loc.String() != "Europe/Paris" {
+		t.Errorf("Expecting Europe/Paris time zone, got %v", loc)
+	}
+	if loc, _ := s["artetv"].Location(); loc != nil {
+		t.Errorf("Expecting no time zone, got %v", loc)
+	}
+
+	// Settings of previous versions are strings
+	s, err = LoadSettings(strings.NewReader(`{"francetv": {"Quality": "720p", "CatalogLimit": "50", "SkipProgressive": "true", "Unknown": "value"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ftv := s["francetv"]; ftv.Quality != "720p" || ftv.CatalogLimit != 50 || !ftv.SkipProgressive {
+		t.Errorf("Unexpected settings given as strings %+v", ftv)
+	}
+
+	_, err = LoadSettings(strings.NewReader(`{"francetv": {"CatalogLimit": "many"}}`))
+	if err == nil {
+		t.Errorf("Expecting an error for a malformed catalog limit")
+	}
+
+	_, err = LoadSettings(strings.NewReader(`{"francetv": {"Timezone": "Nowhere/Land"}}`))
+	if err == nil {
+		t.Errorf("Expecting an error for an unknown time zone")
+	}
+}
@@ -0,0 +1,87 @@
+package sixplay
+
+import (
+	"strings"
+	"time"
+)
+
+// program is an entry of the programs catalog
+type program struct {
+	ID          int    `json:"id"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Images      []struct {
+		Role        string `json:"role"`
+		ExternalKey string `json:"external_key"`
+	} `json:"images"`
+}
+
+// video is an entry of a program's video list
+type video struct {
+	ID              string `json:"id"`
+	Title           string `json:"title"`
+	Description     string `json:"description"`
+	PublicationDate string `json:"publication_date"` // 2020-05-01 20:55:00
+	Season          int    `json:"season"`
+	Episode         int    `json:"episode"`
+	Duration        int    `json:"duration"` // Seconds
+	Images          []struct {
+		Role        string `json:"role"`
+		ExternalKey string `json:"external_key"`
+	} `json:"images"`
+}
+
+// clipID turns the video id c_12345 into clip_12345 used by the clip API
+func (v video) clipID() string {
+	return "clip_" + strings.TrimPrefix(v.ID, "c_")
+}
+
+func (v video) aired() time.Time {
+	t, err := time.ParseInLocation("2006-01-02 15:04:05", v.PublicationDate, paris)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// clip gives details and assets of a video
+type clip struct {
+	Title       string  `json:"title"`
+	Description string  `json:"description"`
+	Duration    int     `json:"duration"`
+	Assets      []asset `json:"assets"`
+	Program     struct {
+		Title string `json:"title"`
+	} `json:"program"`
+}
+
+// asset is a rendition of a clip
+type asset struct {
+	FullPhysicalPath string `json:"full_physical_path"`
+	Protocol         string `json:"protocol"`
+	Type             string `json:"type"`
+	VideoQuality     string `json:"video_quality"`
+}
+
+// isDRM tells if the asset is encrypted
+func (a asset) isDRM() bool {
+	return a.Protocol == "primetime" || strings.Contains(a.Type, "drm") || strings.Contains(a.Type, "widevine") || a.Type == "usp_hlsfp_h264"
+}
+
+func (a asset) isHLS() bool {
+	return a.Type == "usp_hls_h264" || strings.Contains(a.FullPhysicalPath, ".m3u8")
+}
+
+func (a asset) isSubtitle() bool {
+	return a.Protocol == "http_subtitle" || strings.HasSuffix(a.FullPhysicalPath, ".vtt")
+}
+
+var paris *time.Location
+
+func init() {
+	var err error
+	paris, err = time.LoadLocation("Europe/Paris")
+	if err != nil {
+		paris = time.UTC
+	}
+}
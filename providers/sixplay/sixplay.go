@@ -0,0 +1,303 @@
+package sixplay
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"time"
+
+	"github.com/simulot/aspiratv/download"
+	"github.com/simulot/aspiratv/metadata/nfo"
+	"github.com/simulot/aspiratv/net/myhttp"
+	"github.com/simulot/aspiratv/net/myhttp/httptest"
+	"github.com/simulot/aspiratv/playlists/m3u8"
+	"github.com/simulot/aspiratv/providers"
+)
+
+// init registers 6play provider
+func init() {
+	p, err := New()
+	if err != nil {
+		panic(err)
+	}
+	providers.Register(p)
+}
+
+const (
+	middlewareURL = "https://pc.middleware.6play.fr/6play/v2/platforms/m6group_web/services/6play"
+	imagesURL     = "https://images.6play.fr/v1/images/"
+	pageSize      = 50
+)
+
+type getter interface {
+	Get(ctx context.Context, uri string) (io.ReadCloser, error)
+}
+
+// SixPlay provider gives access to M6 group replays.
+// Only free content is available, as no account is used.
+type SixPlay struct {
+	providers.NoHealthCheck
+	getter      getter
+	baseURL     string
+	debug       bool
+	deadline    time.Duration
+	keepBonuses bool
+	logger      providers.Logger
+}
+
+// WithGetter inject a getter in SixPlay object instead of normal one
+func WithGetter(g getter) func(p *SixPlay) {
+	return func(p *SixPlay) {
+		p.getter = g
+	}
+}
+
+// withBaseURL set the API end point, for testing purpose
+func withBaseURL(u string) func(p *SixPlay) {
+	return func(p *SixPlay) {
+		p.baseURL = u
+	}
+}
+
+// New setup a Show provider for 6play
+func New(conf ...func(p *SixPlay)) (*SixPlay, error) {
+	p := &SixPlay{
+		getter:      myhttp.RecorderFromEnv(myhttp.DefaultClient),
+		baseURL:     middlewareURL,
+		deadline:    30 * time.Second,
+		keepBonuses: true,
+	}
+	p.logger = providers.NewStdLogger(p.Name(), false)
+	for _, c := range conf {
+		c(p)
+	}
+	return p, nil
+}
+
+// Name return the name of the provider
+func (SixPlay) Name() string { return "sixplay" }
+
+// Subfolder is the preferred folder of the media in the destinations, when sources are kept apart
+func (SixPlay) Subfolder() string { return "6play" }
+
+// Capabilities gives the features supported by the provider
+func (SixPlay) Capabilities() providers.Capabilities {
+	return providers.Capabilities{HLS: true, Subtitles: true, Thumbnails: true}
+}
+
+// Configure the provider
+func (p *SixPlay) Configure(c providers.Config) {
+	p.keepBonuses = c.KeepBonus
+	p.debug = c.Debug
+	p.logger = c.GetLogger(p.Name())
+	if p.debug {
+		p.deadline = time.Hour
+	} else {
+		p.deadline = 30 * time.Second
+	}
+}
+
+// MediaList return media that match with matching list.
+func (p *SixPlay) MediaList(ctx context.Context, mm []*providers.MatchRequest) (chan *providers.Media, <-chan error) {
+	shows := make(chan *providers.Media)
+	errc := make(chan error, 1)
+
+	go func() {
+		var errs providers.Errors
+		defer func() {
+			providers.CloseMediaList(shows, errc, errs.Err())
+		}()
+		programs, err := p.getPrograms(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("Can't get programs catalog: %w", err))
+			return
+		}
+		for _, m := range mm {
+			if m.Provider != p.Name() {
+				continue
+			}
+			for _, pg := range programs {
+				if !providers.ContainsForMatch(pg.Title, m.Show) {
+					continue
+				}
+				videos, err := p.getVideos(ctx, pg.ID)
+				if err != nil {
+					errs = append(errs, fmt.Errorf("Can't get videos of %q: %w", pg.Title, err))
+					continue
+				}
+				for _, v := range videos {
+					select {
+					case shows <- p.newMedia(m, pg, v):
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+		}
+	}()
+	return shows, errc
+}
+
+func (p *SixPlay) newMedia(mr *providers.MatchRequest, pg program, v video) *providers.Media {
+	media := &providers.Media{
+		ID:       v.clipID(),
+		Match:    mr,
+		ShowType: providers.Series,
+	}
+	meta := &nfo.EpisodeDetails{
+		MediaInfo: nfo.MediaInfo{
+			Title:     v.Title,
+			Showtitle: pg.Title,
+			Plot:      v.Description,
+			Season:    v.Season,
+			Episode:   v.Episode,
+			Aired:     nfo.Aired(v.aired()),
+			Duration:  time.Duration(v.Duration) * time.Second,
+			Studio:    "M6",
+			UniqueID: []nfo.ID{
+				{
+					ID:   v.ID,
+					Type: "SIXPLAY:ID",
+				},
+			},
+			TVShow: &nfo.TVShow{
+				Title: pg.Title,
+				Plot:  pg.Description,
+			},
+		},
+	}
+	for _, i := range v.Images {
+		if i.Role == "vignette" {
+			meta.Thumb = append(meta.Thumb, nfo.Thumb{Aspect: "thumb", URL: imagesURL + i.ExternalKey + "/raw"})
+		}
+	}
+	for _, i := range pg.Images {
+		if i.Role == "portrait" {
+			meta.TVShow.Thumb = append(meta.TVShow.Thumb, nfo.Thumb{Aspect: "poster", URL: imagesURL + i.ExternalKey + "/raw"})
+		}
+	}
+	if meta.Season == 0 {
+		meta.Season = meta.Aired.Time().Year()
+	}
+	media.SetMetaData(meta)
+	return media
+}
+
+// getPrograms pulls the full programs catalog, page by page
+func (p *SixPlay) getPrograms(ctx context.Context) ([]program, error) {
+	programs := []program{}
+	for offset := 0; ; offset += pageSize {
+		page := []program{}
+		err := p.getJSON(ctx, fmt.Sprintf("%s/programs?limit=%d&offset=%d&csa=6", p.baseURL, pageSize, offset), &page)
+		if err != nil {
+			return nil, err
+		}
+		programs = append(programs, page...)
+		if len(page) < pageSize {
+			return programs, nil
+		}
+	}
+}
+
+// getVideos pulls episodes of the program
+func (p *SixPlay) getVideos(ctx context.Context, programID int) ([]video, error) {
+	videos := []video{}
+	for offset := 0; ; offset += pageSize {
+		page := []video{}
+		u := fmt.Sprintf("%s/programs/%d/videos?csa=6&with=clips&type=vi&limit=%d&offset=%d", p.baseURL, programID, pageSize, offset)
+		err := p.getJSON(ctx, u, &page)
+		if err != nil {
+			return nil, err
+		}
+		videos = append(videos, page...)
+		if len(page) < pageSize {
+			return videos, nil
+		}
+	}
+}
+
+func (p *SixPlay) getJSON(ctx context.Context, u string, v interface{}) error {
+	ctx, done := context.WithTimeout(ctx, p.deadline)
+	defer done()
+
+	p.logger.Debugf("Get %q", u)
+	r, err := p.getter.Get(ctx, u)
+	if err != nil {
+		return err
+	}
+	if p.debug {
+		r = httptest.DumpReaderToFile(r, "sixplay-")
+	}
+	defer r.Close()
+	return json.NewDecoder(r).Decode(v)
+}
+
+// GetMediaDetails gets the clip's assets and selects the HLS stream
+func (p *SixPlay) GetMediaDetails(ctx context.Context, m *providers.Media) error {
+	info := m.Metadata.GetMediaInfo()
+	v := url.Values{}
+	v.Set("csa", "5")
+	v.Set("with", "clips")
+	c := clip{}
+	err := p.getJSON(ctx, p.baseURL+"/videos/"+m.ID+"?"+v.Encode(), &c)
+	if err != nil {
+		return fmt.Errorf("Can't get clip: %w", err)
+	}
+	if len(info.Plot) == 0 {
+		info.Plot = c.Description
+	}
+
+	info.URL = ""
+	m.Subtitles = nil
+	drm := false
+	for _, a := range c.Assets {
+		switch {
+		case a.isSubtitle():
+			m.Subtitles = append(m.Subtitles, providers.SubtitleTrack{Language: "fr", URL: a.FullPhysicalPath, Format: "vtt"})
+		case a.isDRM():
+			drm = true
+		case a.isHLS() && len(info.URL) == 0:
+			info.URL = a.FullPhysicalPath
+		}
+	}
+	if len(info.URL) == 0 {
+		if drm {
+			m.DRM = true
+			return providers.ErrDRMProtected
+		}
+		return fmt.Errorf("Can't find HLS stream for %q", m.ID)
+	}
+
+	if m.Match != nil && len(m.Match.Quality) > 0 {
+		master, err := m3u8.NewMaster(ctx, info.URL, p.getter)
+		if err != nil {
+			return fmt.Errorf("Can't get master playlist: %w", err)
+		}
+		if master.IsMaster() {
+			info.URL, err = master.Quality(m.Match.Quality)
+			if err != nil {
+				return fmt.Errorf("Can't select stream quality: %w", err)
+			}
+		}
+	}
+	p.logger.Debugf("Stream url %q (%ds)", info.URL, c.Duration)
+	return nil
+}
+
+// Download the media's HLS stream into destPath
+func (p *SixPlay) Download(ctx context.Context, m *providers.Media, destPath string, progress providers.ProgressFunc) error {
+	info := m.Metadata.GetMediaInfo()
+	if len(info.URL) == 0 {
+		err := p.GetMediaDetails(ctx, m)
+		if err != nil {
+			return err
+		}
+	}
+	return download.HLS(ctx, info.URL, destPath,
+		download.WithGetter(p.getter),
+		download.WithProgress(progress),
+		download.WithDebug(p.debug),
+	)
+}
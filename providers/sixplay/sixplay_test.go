@@ -0,0 +1,113 @@
+package sixplay
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/simulot/aspiratv/net/myhttp"
+	"github.com/simulot/aspiratv/providers"
+)
+
+func newTestServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var file string
+		switch {
+		case r.URL.Path == "/programs":
+			if r.URL.Query().Get("offset") != "0" {
+				w.Write([]byte("[]"))
+				return
+			}
+			file = "programs.json"
+		case r.URL.Path == "/programs/1234/videos":
+			file = "videos-1234.json"
+		case strings.HasPrefix(r.URL.Path, "/videos/"):
+			file = strings.TrimPrefix(r.URL.Path, "/videos/") + ".json"
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeFile(w, r, filepath.Join("testdata", file))
+	}))
+}
+
+func TestMediaList(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+
+	p, _ := New(WithGetter(myhttp.DefaultClient), withBaseURL(ts.URL))
+	mr := &providers.MatchRequest{Provider: "sixplay", Show: "kaamelott"}
+	mm := []*providers.Media{}
+	list, errc := p.MediaList(context.TODO(), []*providers.MatchRequest{mr})
+	for m := range list {
+		mm = append(mm, m)
+	}
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+	if len(mm) != 2 {
+		t.Fatalf("Expecting %d media, got %d", 2, len(mm))
+	}
+
+	m := mm[0]
+	info := m.Metadata.GetMediaInfo()
+	if m.ID != "clip_111" || info.Showtitle != "Kaamelott" || info.Title != "Le Dernier Empereur" || info.Season != 1 || info.Episode != 3 {
+		t.Errorf("Unexpected media %q: %+v", m.ID, info)
+	}
+	if got := info.Aired.Time().Format("2006-01-02"); got != "2020-05-01" {
+		t.Errorf("Expecting aired date %q, got %q", "2020-05-01", got)
+	}
+	if len(info.Thumb) != 1 || len(info.TVShow.Thumb) != 1 {
+		t.Errorf("Expecting thumbnail and poster")
+	}
+}
+
+func TestMediaListError(t *testing.T) {
+	ts := httptest.NewServer(http.NotFoundHandler())
+	defer ts.Close()
+
+	p, _ := New(WithGetter(myhttp.DefaultClient), withBaseURL(ts.URL))
+	mr := &providers.MatchRequest{Provider: "sixplay", Show: "kaamelott"}
+	list, errc := p.MediaList(context.TODO(), []*providers.MatchRequest{mr})
+	for range list {
+		t.Errorf("Expecting no media")
+	}
+	if err := <-errc; err == nil {
+		t.Errorf("Expecting an error when the catalog can't be fetched")
+	}
+}
+
+func TestGetMediaDetails(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+
+	p, _ := New(WithGetter(myhttp.DefaultClient), withBaseURL(ts.URL))
+	mr := &providers.MatchRequest{Provider: "sixplay", Show: "kaamelott"}
+	mm := []*providers.Media{}
+	list, _ := p.MediaList(context.TODO(), []*providers.MatchRequest{mr})
+	for m := range list {
+		mm = append(mm, m)
+	}
+	if len(mm) != 2 {
+		t.Fatalf("Expecting %d media, got %d", 2, len(mm))
+	}
+
+	err := p.GetMediaDetails(context.TODO(), mm[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, got := "https://lb.cdn.m6web.fr/s/su/111.ism/master.m3u8", mm[0].Metadata.GetMediaInfo().URL; got != want {
+		t.Errorf("Expecting stream %q, got %q", want, got)
+	}
+	if len(mm[0].Subtitles) != 1 {
+		t.Errorf("Expecting subtitles, got %d", len(mm[0].Subtitles))
+	}
+
+	err = p.GetMediaDetails(context.TODO(), mm[1])
+	if err != providers.ErrDRMProtected || !mm[1].DRM {
+		t.Errorf("Expecting %v, got %v", providers.ErrDRMProtected, err)
+	}
+}
@@ -0,0 +1,140 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ScanState records media seen during previous runs, to scan only new media.
+type ScanState struct {
+	LastRun time.Time            `json:"last_run"`
+	Seen    map[string]time.Time `json:"seen"`              // Media IDs with the time they were seen first
+	Cursors map[string]string    `json:"cursors,omitempty"` // Last media processed by interrupted scans, by provider
+
+	mu sync.Mutex
+}
+
+// NewScanState create an empty state
+func NewScanState() *ScanState {
+	return &ScanState{
+		Seen: map[string]time.Time{},
+	}
+}
+
+// LoadScanState reads the state file. An empty state is returned when the file doesn't exist yet.
+func LoadScanState(statePath string) (*ScanState, error) {
+	s := NewScanState()
+	b, err := ioutil.ReadFile(statePath)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Can't read scan state: %w", err)
+	}
+	err = json.Unmarshal(b, s)
+	if err != nil {
+		return nil, fmt.Errorf("Can't decode scan state %q: %w", statePath, err)
+	}
+	if s.Seen == nil {
+		s.Seen = map[string]time.Time{}
+	}
+	return s, nil
+}
+
+// Save writes the state file. The file is replaced only when completely written.
+func (s *ScanState) Save(statePath string) error {
+	s.mu.Lock()
+	b, err := json.MarshalIndent(s, "", "  ")
+	s.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("Can't encode scan state: %w", err)
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(statePath), filepath.Base(statePath)+".*")
+	if err != nil {
+		return fmt.Errorf("Can't save scan state: %w", err)
+	}
+	_, err = tmp.Write(b)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), statePath)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("Can't save scan state: %w", err)
+	}
+	return nil
+}
+
+// IsSeen tells if the media ID has been recorded in the state
+func (s *ScanState) IsSeen(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.Seen[id]
+	return ok
+}
+
+// MarkSeen records the media ID in the state, so it won't be emitted again by Filter
+func (s *ScanState) MarkSeen(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.Seen[id]; !ok {
+		s.Seen[id] = time.Now()
+	}
+}
+
+// SetCursor records the last media processed by the provider's scan, see StartAfter
+func (s *ScanState) SetCursor(provider, id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Cursors == nil {
+		s.Cursors = map[string]string{}
+	}
+	s.Cursors[provider] = id
+}
+
+// Cursor gives the last media processed by the provider's interrupted scan, empty when the last scan was complete
+func (s *ScanState) Cursor(provider string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.Cursors[provider]
+}
+
+// ClearCursor forgets the provider's cursor, once its scan is complete
+func (s *ScanState) ClearCursor(provider string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.Cursors, provider)
+}
+
+// Filter emits only media that weren't seen before. A media is emitted once, even
+// when it appears several times in the input channel.
+func (s *ScanState) Filter(ctx context.Context, in <-chan *Media) chan *Media {
+	out := make(chan *Media)
+	go func() {
+		defer close(out)
+		emitted := map[string]bool{}
+		for m := range in {
+			if emitted[m.ID] || s.IsSeen(m.ID) {
+				continue
+			}
+			emitted[m.ID] = true
+			select {
+			case <-ctx.Done():
+				// Drain the input to release the producer
+				for range in {
+				}
+				return
+			case out <- m:
+			}
+		}
+	}()
+	return out
+}
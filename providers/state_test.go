@@ -0,0 +1,77 @@
+package providers
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestScanStateFilter(t *testing.T) {
+	s := NewScanState()
+	s.MarkSeen("1")
+
+	in := make(chan *Media)
+	go func() {
+		for _, id := range []string{"1", "2", "3", "2", "1", "4"} {
+			in <- &Media{ID: id}
+		}
+		close(in)
+	}()
+
+	got := []string{}
+	for m := range s.Filter(context.TODO(), in) {
+		got = append(got, m.ID)
+	}
+	want := []string{"2", "3", "4"}
+	if len(got) != len(want) {
+		t.Fatalf("Expecting %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Expecting %v, got %v", want, got)
+		}
+	}
+}
+
+func TestScanStateLoadSave(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aspiratv-state-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	statePath := filepath.Join(dir, "state.json")
+
+	s, err := LoadScanState(statePath)
+	if err != nil {
+		t.Fatalf("Expecting no error for a missing state file, got %s", err)
+	}
+	if len(s.Seen) != 0 {
+		t.Errorf("Expecting an empty state, got %v", s.Seen)
+	}
+	s.MarkSeen("a")
+	s.MarkSeen("b")
+	s.MarkSeen("a")
+	s.SetCursor("francetv", "b")
+	s.SetCursor("artetv", "x")
+	s.ClearCursor("artetv")
+	err = s.Save(statePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = LoadScanState(statePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Seen) != 2 || !s.IsSeen("a") || !s.IsSeen("b") {
+		t.Errorf("Expecting a and b to be seen, got %v", s.Seen)
+	}
+	if c := s.Cursor("francetv"); c != "b" {
+		t.Errorf("Expecting francetv cursor b, got %q", c)
+	}
+	if c := s.Cursor("artetv"); c != "" {
+		t.Errorf("Expecting no artetv cursor, got %q", c)
+	}
+}
@@ -0,0 +1,29 @@
+package providers
+
+import "path/filepath"
+
+// Subfolderer is implemented by providers giving a preferred subfolder of the destinations,
+// for users keeping the media of each source apart.
+type Subfolderer interface {
+	Subfolder() string
+}
+
+// Subfolder gives the subfolder of the destinations receiving the provider's media. It's, in this order:
+// configured when not empty, the provider's preferred subfolder when preferred is true, none otherwise.
+func Subfolder(p Provider, configured string, preferred bool) string {
+	if len(configured) > 0 {
+		return configured
+	}
+	if s, ok := p.(Subfolderer); ok && preferred {
+		return s.Subfolder()
+	}
+	return ""
+}
+
+// MediaRoot gives the directory receiving the media of a destination: root joined with subfolder when not empty
+func MediaRoot(root, subfolder string) string {
+	if len(subfolder) == 0 {
+		return root
+	}
+	return filepath.Join(root, PathNameCleaner(subfolder))
+}
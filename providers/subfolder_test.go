@@ -0,0 +1,40 @@
+package providers
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+type subfolderProvider struct {
+	fakeProvider
+}
+
+func (subfolderProvider) Subfolder() string { return "Source" }
+
+func TestSubfolder(t *testing.T) {
+	tests := []struct {
+		name       string
+		p          Provider
+		configured string
+		preferred  bool
+		want       string
+	}{
+		{"none", subfolderProvider{}, "", false, ""},
+		{"preferred", subfolderProvider{}, "", true, "Source"},
+		{"configured", subfolderProvider{}, "Ici", true, "Ici"},
+		{"no preference", fakeProvider{}, "", true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Subfolder(tt.p, tt.configured, tt.preferred); got != tt.want {
+				t.Errorf("Subfolder() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+	if got, want := MediaRoot("/videos", "Source"), filepath.Join("/videos", "Source"); got != want {
+		t.Errorf("MediaRoot() = %q, want %q", got, want)
+	}
+	if got := MediaRoot("/videos", ""); got != "/videos" {
+		t.Errorf("MediaRoot() = %q, want %q", got, "/videos")
+	}
+}
@@ -0,0 +1,65 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/simulot/aspiratv/subtitles"
+)
+
+// SubtitleTrack describes a subtitle file available for a media
+type SubtitleTrack struct {
+	Language string `json:"language"` // ISO 639-1 code of the subtitles language
+	URL      string `json:"url"`      // Where to get the subtitles
+	Format   string `json:"format"`   // vtt or srt
+}
+
+// DownloadSubtitles fetches media's subtitles and writes them next to the video file,
+// with the same base name followed by language code: show.fr.srt.
+// WebVTT tracks are converted into SRT, and SRT tracks are renumbered, see the subtitles package.
+func DownloadSubtitles(ctx context.Context, g Getter, m *Media, videoPath string) error {
+	base := strings.TrimSuffix(videoPath, filepath.Ext(videoPath))
+	for _, s := range m.Subtitles {
+		lang := s.Language
+		if len(lang) == 0 {
+			lang = "und"
+		}
+		err := downloadSubtitle(ctx, g, s, base+"."+lang+".srt")
+		if err != nil {
+			return fmt.Errorf("Can't download %s subtitles: %w", lang, err)
+		}
+	}
+	return nil
+}
+
+func downloadSubtitle(ctx context.Context, g Getter, s SubtitleTrack, dest string) error {
+	r, err := g.Get(ctx, s.URL)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	f, err := os.Create(dest)
+	if err != nil {
+		return fmt.Errorf("Can't create %s: %w", dest, err)
+	}
+	switch strings.ToLower(s.Format) {
+	case "vtt":
+		err = subtitles.VTTtoSRT(r, f)
+	case "srt":
+		err = subtitles.RenumberSRT(r, f)
+	default:
+		_, err = io.Copy(f, r)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(dest)
+	}
+	return err
+}
@@ -0,0 +1,66 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/simulot/aspiratv/net/myhttp"
+)
+
+const vttSample = `WEBVTT
+
+NOTE produced by france.tv
+
+1
+00:00:01.000 --> 00:00:03.500 align:middle line:90%
+Bonjour à tous
+
+00:01.000 --> 01:02.250
+Deuxième ligne
+sur deux lignes
+`
+
+const srtSample = `1
+00:00:01,000 --> 00:00:03,500
+Bonjour à tous
+
+2
+00:00:01,000 --> 00:01:02,250
+Deuxième ligne
+sur deux lignes
+`
+
+func TestDownloadSubtitles(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, vttSample)
+	}))
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "aspiratv-subtitles-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	m := &Media{
+		Subtitles: []SubtitleTrack{{Language: "fr", URL: ts.URL + "/show.vtt", Format: "vtt"}},
+	}
+	err = DownloadSubtitles(context.TODO(), myhttp.DefaultClient, m, filepath.Join(dir, "show.mp4"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(filepath.Join(dir, "show.fr.srt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(string(b)) != strings.TrimSpace(srtSample) {
+		t.Errorf("Unexpected subtitles file content:\n%s", string(b))
+	}
+}
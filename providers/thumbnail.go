@@ -0,0 +1,122 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/simulot/aspiratv/metadata/nfo"
+)
+
+// Getter is the interface used to get files from internet
+type Getter interface {
+	Get(ctx context.Context, uri string) (io.ReadCloser, error)
+}
+
+// DownloadThumbnail writes the media's thumbnail next to the video file, named <basename>-thumb.jpg.
+// For series, the poster is written into the season folder as poster.jpg when not yet present.
+// The file extension is given by the actual image format. Media without thumbnail are skipped.
+func DownloadThumbnail(ctx context.Context, g Getter, m *Media, videoPath string) error {
+	thumbs := m.Metadata.GetMediaInfo().Thumb
+	if len(thumbs) == 0 {
+		return nil
+	}
+	thumb := findThumb(thumbs, "thumb")
+	if thumb == nil {
+		thumb = &thumbs[0]
+	}
+	base := strings.TrimSuffix(videoPath, filepath.Ext(videoPath)) + "-thumb"
+	if !imageExists(base) {
+		_, err := DownloadImage(ctx, g, thumb.URL, base)
+		if err != nil {
+			return fmt.Errorf("Can't get thumbnail: %w", err)
+		}
+	}
+	if m.ShowType == Series {
+		poster := findThumb(thumbs, "poster")
+		base = filepath.Join(filepath.Dir(videoPath), "poster")
+		if poster != nil && !imageExists(base) {
+			_, err := DownloadImage(ctx, g, poster.URL, base)
+			if err != nil {
+				return fmt.Errorf("Can't get poster: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// imageExtensions gives the file extension of known image types
+var imageExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// DownloadImage get the image at url and writes it into base file name, with an extension
+// corresponding to the image format sniffed from its content, .jpg when unknown.
+// Redirections are followed by the getter. It returns the name of the file.
+func DownloadImage(ctx context.Context, g Getter, url string, base string) (string, error) {
+	r, err := g.Get(ctx, url)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(r, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return "", fmt.Errorf("Can't read image: %w", err)
+	}
+	head = head[:n]
+	contentType := http.DetectContentType(head)
+	ext, ok := imageExtensions[contentType]
+	if !ok {
+		log.Printf("Unknown image type %q for %q, saved as .jpg", contentType, url)
+		ext = ".jpg"
+	}
+
+	err = os.MkdirAll(filepath.Dir(base), 0777)
+	if err != nil {
+		return "", err
+	}
+	tmp := base + ".tmp"
+	w, err := os.Create(tmp)
+	if err != nil {
+		return "", err
+	}
+	_, err = io.Copy(w, io.MultiReader(bytes.NewReader(head), r))
+	if cerr := w.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+	name := base + ext
+	return name, os.Rename(tmp, name)
+}
+
+func findThumb(thumbs []nfo.Thumb, aspect string) *nfo.Thumb {
+	for i := range thumbs {
+		if thumbs[i].Aspect == aspect {
+			return &thumbs[i]
+		}
+	}
+	return nil
+}
+
+func imageExists(base string) bool {
+	for _, ext := range []string{".jpg", ".png", ".gif", ".webp"} {
+		if _, err := os.Stat(base + ext); err == nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,104 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/simulot/aspiratv/metadata/nfo"
+	"github.com/simulot/aspiratv/net/myhttp"
+)
+
+func TestDownloadThumbnail(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 16, 9))
+	pngImg, jpgImg := bytes.NewBuffer(nil), bytes.NewBuffer(nil)
+	png.Encode(pngImg, img)
+	jpeg.Encode(jpgImg, img, nil)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/thumb", func(w http.ResponseWriter, r *http.Request) { w.Write(jpgImg.Bytes()) })
+	mux.HandleFunc("/poster", func(w http.ResponseWriter, r *http.Request) { w.Write(pngImg.Bytes()) })
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "aspiratv-thumb-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	m := &Media{
+		ShowType: Series,
+		Metadata: &nfo.EpisodeDetails{
+			MediaInfo: nfo.MediaInfo{
+				Thumb: []nfo.Thumb{
+					{Aspect: "poster", URL: ts.URL + "/poster"},
+					{Aspect: "thumb", URL: ts.URL + "/thumb"},
+				},
+			},
+		},
+	}
+	video := filepath.Join(dir, "Season 01", "show - s01e01.mp4")
+	err = DownloadThumbnail(context.TODO(), myhttp.DefaultClient, m, video)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []string{"show - s01e01-thumb.jpg", "poster.png"} {
+		if _, err := os.Stat(filepath.Join(dir, "Season 01", f)); err != nil {
+			t.Errorf("Expecting file %q: %s", f, err)
+		}
+	}
+
+	m.Metadata.GetMediaInfo().Thumb = nil
+	err = DownloadThumbnail(context.TODO(), myhttp.DefaultClient, m, filepath.Join(dir, "other.mp4"))
+	if err != nil {
+		t.Errorf("Expecting media without thumbnail to be skipped, got %s", err)
+	}
+}
+
+func TestDownloadImage(t *testing.T) {
+	webp := append([]byte("RIFF\x00\x00\x00\x00WEBPVP8 "), make([]byte, 32)...)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/webp", func(w http.ResponseWriter, r *http.Request) { w.Write(webp) })
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) { http.Redirect(w, r, "/webp", http.StatusFound) })
+	mux.HandleFunc("/unknown", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte{0, 1, 2, 3}) })
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "aspiratv-image-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tcs := []struct {
+		path string
+		want string
+	}{
+		{"/webp", "webp.webp"},
+		{"/redirect", "redirect.webp"},
+		{"/unknown", "unknown.jpg"},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.path, func(t *testing.T) {
+			name, err := DownloadImage(context.TODO(), myhttp.DefaultClient, ts.URL+tc.path, filepath.Join(dir, tc.path[1:]))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if filepath.Base(name) != tc.want {
+				t.Errorf("Expecting %q, got %q", tc.want, filepath.Base(name))
+			}
+			if _, err := os.Stat(name); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
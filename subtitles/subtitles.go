@@ -0,0 +1,107 @@
+// Package subtitles converts subtitle files into the SubRip (SRT) format understood by media servers.
+package subtitles
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// cueTiming matches WebVTT and SRT cue timings. Hours are optional, milliseconds are separated by a dot or a comma.
+// Cue settings, like position or align, follow the timing.
+var cueTiming = regexp.MustCompile(`^\s*((?:\d+:)?\d{2}:\d{2})[.,](\d{3})\s+-->\s+((?:\d+:)?\d{2}:\d{2})[.,](\d{3})`)
+
+// vttTag matches WebVTT tags: classes, voices, languages, ruby text and karaoke timestamps
+var vttTag = regexp.MustCompile(`</?[a-z]+(?:\.[^\s>]*)?(?:\s[^>]*)?>|<\d[\d:.]*>`)
+
+// srtTags are the tags kept in SRT output
+var srtTags = map[string]bool{"i": true, "b": true, "u": true}
+
+type cue struct {
+	start, end string
+	lines      []string
+}
+
+// VTTtoSRT converts a WebVTT file into a SRT file. Cues are renumbered from 1, their settings,
+// like positioning, are dropped. Headers, comments, styles and regions are dropped.
+// Styling tags are removed, except italic, bold and underline. Entities are decoded.
+// Cues left without text are dropped.
+func VTTtoSRT(r io.Reader, w io.Writer) error {
+	return convert(r, w, cleanVTT)
+}
+
+// RenumberSRT copies a SRT file with cues numbered sequentially from 1, and timestamps in the SRT format.
+// Cues without text are dropped.
+func RenumberSRT(r io.Reader, w io.Writer) error {
+	return convert(r, w, func(l string) string { return l })
+}
+
+// convert reads the cues of r, and writes them in SRT format. Lines out of cues are ignored, as well as
+// the line before the timing, which is the cue identifier.
+func convert(r io.Reader, w io.Writer, clean func(string) string) error {
+	s := bufio.NewScanner(r)
+	bw := bufio.NewWriter(w)
+	n := 0
+	var c *cue
+	flush := func() {
+		if c == nil || len(c.lines) == 0 {
+			c = nil
+			return
+		}
+		n++
+		fmt.Fprintf(bw, "%d\n%s --> %s\n%s\n\n", n, c.start, c.end, strings.Join(c.lines, "\n"))
+		c = nil
+	}
+	for s.Scan() {
+		l := strings.TrimRight(s.Text(), "\r")
+		if m := cueTiming.FindStringSubmatch(l); m != nil {
+			flush()
+			c = &cue{
+				start: timestamp(m[1], m[2]),
+				end:   timestamp(m[3], m[4]),
+			}
+			continue
+		}
+		if len(strings.TrimSpace(l)) == 0 {
+			flush()
+			continue
+		}
+		if c == nil {
+			continue
+		}
+		if l = strings.TrimSpace(clean(l)); len(l) > 0 {
+			c.lines = append(c.lines, l)
+		}
+	}
+	flush()
+	if err := s.Err(); err != nil {
+		return fmt.Errorf("Can't read subtitles: %w", err)
+	}
+	return bw.Flush()
+}
+
+// cleanVTT removes WebVTT tags, except those known by SRT, and decodes entities
+func cleanVTT(l string) string {
+	l = vttTag.ReplaceAllStringFunc(l, func(tag string) string {
+		name := strings.TrimLeft(strings.TrimRight(tag, ">"), "</")
+		if srtTags[name] {
+			return tag
+		}
+		return ""
+	})
+	return html.UnescapeString(l)
+}
+
+// timestamp gives the SRT timestamp, with hours on 2 digits: 00:01:02,250
+func timestamp(t, ms string) string {
+	h := 0
+	if parts := strings.Split(t, ":"); len(parts) == 3 {
+		h, _ = strconv.Atoi(parts[0])
+		t = parts[1] + ":" + parts[2]
+	}
+	return fmt.Sprintf("%02d:%s,%s", h, t, ms)
+}
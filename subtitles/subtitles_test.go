@@ -0,0 +1,72 @@
+package subtitles
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVTTtoSRT(t *testing.T) {
+	tests := []struct {
+		name string
+		vtt  string
+		want string
+	}{
+		{
+			"simple",
+			"WEBVTT\n\nNOTE produced by france.tv\n\n1\n00:00:01.000 --> 00:00:03.500 align:middle line:90%\nBonjour à tous\n\n00:01.000 --> 01:02.250\nDeuxième ligne\nsur deux lignes\n",
+			"1\n00:00:01,000 --> 00:00:03,500\nBonjour à tous\n\n2\n00:00:01,000 --> 00:01:02,250\nDeuxième ligne\nsur deux lignes\n\n",
+		},
+		{
+			"comma and CRLF",
+			"WEBVTT\r\n\r\n00:00:01,000 --> 00:00:02,000\r\n<b>Gras</b>\r\n",
+			"1\n00:00:01,000 --> 00:00:02,000\n<b>Gras</b>\n\n",
+		},
+		{"empty", "WEBVTT\n", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := bytes.NewBuffer(nil)
+			if err := VTTtoSRT(strings.NewReader(tt.vtt), b); err != nil {
+				t.Fatal(err)
+			}
+			if got := b.String(); got != tt.want {
+				t.Errorf("VTTtoSRT() =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVTTtoSRTFranceTV(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "francetv.vtt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	want, err := ioutil.ReadFile(filepath.Join("testdata", "francetv.srt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := bytes.NewBuffer(nil)
+	if err := VTTtoSRT(f, b); err != nil {
+		t.Fatal(err)
+	}
+	if got := b.String(); got != string(want) {
+		t.Errorf("VTTtoSRT() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestRenumberSRT(t *testing.T) {
+	srt := "12\n00:00:01,000 --> 00:00:02,000\nUn\n\n\n\n13\n00:00:03,000 --> 00:00:04,000\n\n20\n00:00:05.000 --> 00:00:06.000\nDeux\n"
+	want := "1\n00:00:01,000 --> 00:00:02,000\nUn\n\n2\n00:00:05,000 --> 00:00:06,000\nDeux\n\n"
+	b := bytes.NewBuffer(nil)
+	if err := RenumberSRT(strings.NewReader(srt), b); err != nil {
+		t.Fatal(err)
+	}
+	if got := b.String(); got != want {
+		t.Errorf("RenumberSRT() =\n%q\nwant\n%q", got, want)
+	}
+}